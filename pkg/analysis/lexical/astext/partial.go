@@ -10,4 +10,7 @@ type PartialIndex struct {
 	ast.NodeBase
 
 	Target ast.Node
+	// Super is true if super is being indexed. Target is the self of
+	// the object containing super.
+	Super bool
 }
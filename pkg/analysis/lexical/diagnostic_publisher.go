@@ -0,0 +1,68 @@
+package lexical
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+)
+
+const (
+	// parseDiagnostics are diagnostics found while parsing.
+	parseDiagnostics = "parse"
+	// evalDiagnostics are diagnostics found while evaluating.
+	evalDiagnostics = "evaluation"
+)
+
+// DiagnosticPublisher publishes diagnostics for text documents.
+// Diagnostics are grouped by the source which created them, so sources
+// can publish independently without replacing each other's diagnostics.
+type DiagnosticPublisher struct {
+	published map[string]map[string][]lsp.Diagnostic
+
+	mu sync.Mutex
+}
+
+// NewDiagnosticPublisher creates an instance of DiagnosticPublisher.
+func NewDiagnosticPublisher() *DiagnosticPublisher {
+	return &DiagnosticPublisher{
+		published: make(map[string]map[string][]lsp.Diagnostic),
+	}
+}
+
+// Publish replaces the diagnostics from source for a text document and
+// sends the diagnostics from all sources to the client.
+func (dp *DiagnosticPublisher) Publish(ctx context.Context, conn RPCConn, uri, source string, diagnostics []lsp.Diagnostic) error {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	sources, ok := dp.published[uri]
+	if !ok {
+		sources = make(map[string][]lsp.Diagnostic)
+		dp.published[uri] = sources
+	}
+	sources[source] = diagnostics
+
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	all := make([]lsp.Diagnostic, 0)
+	for _, name := range names {
+		all = append(all, sources[name]...)
+	}
+
+	if conn == nil {
+		return nil
+	}
+
+	response := &lsp.PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: all,
+	}
+
+	return conn.Notify(ctx, "textDocument/publishDiagnostics", response)
+}
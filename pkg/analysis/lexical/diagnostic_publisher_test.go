@@ -0,0 +1,50 @@
+package lexical
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingRPCConn struct {
+	notifications []interface{}
+}
+
+func (c *recordingRPCConn) Notify(ctx context.Context, method string, params interface{}, opts ...jsonrpc2.CallOption) error {
+	c.notifications = append(c.notifications, params)
+	return nil
+}
+
+func TestDiagnosticPublisher_Publish(t *testing.T) {
+	dp := NewDiagnosticPublisher()
+	conn := &recordingRPCConn{}
+	ctx := context.Background()
+
+	parse := []lsp.Diagnostic{{Message: "parse"}}
+	eval := []lsp.Diagnostic{{Message: "eval"}}
+
+	require.NoError(t, dp.Publish(ctx, conn, "file:///a.jsonnet", evalDiagnostics, eval))
+	require.NoError(t, dp.Publish(ctx, conn, "file:///a.jsonnet", parseDiagnostics, parse))
+	require.NoError(t, dp.Publish(ctx, conn, "file:///a.jsonnet", evalDiagnostics, nil))
+
+	expected := []interface{}{
+		&lsp.PublishDiagnosticsParams{
+			URI:         "file:///a.jsonnet",
+			Diagnostics: []lsp.Diagnostic{{Message: "eval"}},
+		},
+		&lsp.PublishDiagnosticsParams{
+			URI:         "file:///a.jsonnet",
+			Diagnostics: []lsp.Diagnostic{{Message: "eval"}, {Message: "parse"}},
+		},
+		&lsp.PublishDiagnosticsParams{
+			URI:         "file:///a.jsonnet",
+			Diagnostics: []lsp.Diagnostic{{Message: "parse"}},
+		},
+	}
+
+	assert.Equal(t, expected, conn.notifications)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
 	"github.com/bryanl/jsonnet-language-server/pkg/config"
 	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
 	"github.com/bryanl/jsonnet-language-server/pkg/tracing"
@@ -19,15 +20,46 @@ type DocumentProcessor interface {
 	Process(ctx context.Context, td config.TextDocument, conn RPCConn) error
 }
 
+// DocumentProcessors runs multiple document processors. Every processor
+// runs even if an earlier processor fails.
+type DocumentProcessors []DocumentProcessor
+
+var _ DocumentProcessor = (DocumentProcessors)(nil)
+
+// Process processes the text document with each processor. It returns
+// the first error.
+func (dps DocumentProcessors) Process(ctx context.Context, td config.TextDocument, conn RPCConn) error {
+	var firstErr error
+	for _, dp := range dps {
+		if err := dp.Process(ctx, td, conn); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// DiagnosticsConfig is configuration for PerformDiagnostics.
+type DiagnosticsConfig interface {
+	DiagnosticSeverity(path string, code static.DiagnosticCode) (static.Severity, bool)
+	ForPath(path string) (*config.Config, error)
+}
+
 // PerformDiagnostics performs diagnostics on a text document and sends results
 // to the client.
-type PerformDiagnostics struct{}
+type PerformDiagnostics struct {
+	config    DiagnosticsConfig
+	publisher *DiagnosticPublisher
+}
 
 var _ DocumentProcessor = (*PerformDiagnostics)(nil)
 
 // NewPerformDiagnostics creates an instance of PerformDiagnostics.
-func NewPerformDiagnostics() *PerformDiagnostics {
-	return &PerformDiagnostics{}
+func NewPerformDiagnostics(c DiagnosticsConfig, publisher *DiagnosticPublisher) *PerformDiagnostics {
+	return &PerformDiagnostics{
+		config:    c,
+		publisher: publisher,
+	}
 }
 
 // Process runs the diagnositics.
@@ -44,6 +76,16 @@ func (p *PerformDiagnostics) Process(ctx context.Context, td config.TextDocument
 		return err
 	}
 
+	// the configuration for the document contains the lib paths and node
+	// cache of its project and workspace folder.
+	severityFor := p.config.DiagnosticSeverity
+	pc, err := p.config.ForPath(filename)
+	if err != nil {
+		span.LogFields(log.Error(err))
+	} else {
+		severityFor = pc.DiagnosticSeverity
+	}
+
 	done := make(chan bool, 1)
 	diagCh := make(chan token.ParseDiagnostic, 1)
 
@@ -57,14 +99,16 @@ func (p *PerformDiagnostics) Process(ctx context.Context, td config.TextDocument
 
 		for d := range diagCh {
 			if conn != nil {
-				r := position.FromJsonnetRange(d.Loc)
-
-				diagnostic := lsp.Diagnostic{
-					Range:    r.ToLSP(),
-					Message:  d.Message,
-					Severity: lsp.Error,
+				severity := lsp.Error
+				if d.Code != "" {
+					s, ok := severityFor(filename, d.Code)
+					if !ok {
+						continue
+					}
+					severity = lsp.DiagnosticSeverity(s)
 				}
 
+				diagnostic := newDiagnostic(d.Loc, d.Message, d.Code, severity)
 				diagnostics = append(diagnostics, diagnostic)
 			}
 		}
@@ -72,46 +116,59 @@ func (p *PerformDiagnostics) Process(ctx context.Context, td config.TextDocument
 		close(done)
 	}()
 
-	_, err = convertToNode(filename, td.String(), diagCh)
+	node, err := token.Parse(filename, td.String(), diagCh)
 	if err != nil {
-		return errors.Wrap(err, "converting source to node")
+		return errors.Wrap(err, "converting source to node: parsing source")
 	}
 
 	<-done
 
+	codeDiagnostics := static.Diagnose(node)
+
+	if err := token.DesugarFile(&node); err != nil {
+		return errors.Wrap(err, "converting source to node")
+	}
+
+	// imports can't be resolved without the configuration for the
+	// document.
+	if pc != nil {
+		typeDiagnostics := token.TypeDiagnostics(ctx, filename, node, pc.JsonnetLibPaths(), pc.NodeCache())
+		codeDiagnostics = append(codeDiagnostics, typeDiagnostics...)
+	}
+
+	for _, d := range codeDiagnostics {
+		s, ok := severityFor(filename, d.Code)
+		if !ok {
+			continue
+		}
+
+		diagnostic := newDiagnostic(d.Loc, d.Message, d.Code, lsp.DiagnosticSeverity(s))
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
 	if conn != nil {
 		span.LogFields(
 			log.String("event", "sending diagnostics"),
 		)
 
-		response := &lsp.PublishDiagnosticsParams{
-			URI:         td.URI(),
-			Diagnostics: diagnostics,
-		}
-
 		ctx := context.Background()
-		method := "textDocument/publishDiagnostics"
-		if err := conn.Notify(ctx, method, response); err != nil {
+		if err := p.publisher.Publish(ctx, conn, td.URI(), parseDiagnostics, diagnostics); err != nil {
 			span.LogFields(
 				log.Error(err),
 			)
-
 		}
-
 	}
 
 	return nil
 }
 
-func convertToNode(filename, snippet string, diagCh chan<- token.ParseDiagnostic) (ast.Node, error) {
-	node, err := token.Parse(filename, snippet, diagCh)
-	if err != nil {
-		return nil, errors.Wrap(err, "parsing source")
-	}
+func newDiagnostic(loc ast.LocationRange, message string, code static.DiagnosticCode, severity lsp.DiagnosticSeverity) lsp.Diagnostic {
+	r := position.FromJsonnetRange(loc)
 
-	if err := token.DesugarFile(&node); err != nil {
-		return nil, err
+	return lsp.Diagnostic{
+		Range:    r.ToLSP(),
+		Message:  message,
+		Severity: severity,
+		Code:     string(code),
 	}
-
-	return node, nil
 }
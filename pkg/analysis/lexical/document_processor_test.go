@@ -1 +1,80 @@
 package lexical
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerformDiagnostics_Process_folderLibPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diagnostics")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	folder := filepath.Join(dir, "app")
+	require.NoError(t, os.MkdirAll(filepath.Join(folder, "lib"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(folder, "lib", "lib.libsonnet"), []byte("{a: 1}"), 0644))
+
+	c := config.New()
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+	c.AddWorkspaceFolder(ctx, folder, "app")
+	require.NoError(t, c.UpdateWorkspaceFolderSettings(ctx, folder, map[string]interface{}{
+		"libPaths": []interface{}{"lib"},
+	}))
+
+	cases := []struct {
+		name     string
+		path     string
+		expected []lsp.Diagnostic
+	}{
+		{
+			name: "in folder",
+			path: filepath.Join(folder, "main.jsonnet"),
+			expected: []lsp.Diagnostic{
+				{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: 0, Character: 0},
+						End:   lsp.Position{Line: 0, Character: 26},
+					},
+					Message:  "field b does not exist",
+					Severity: lsp.Warning,
+					Code:     string(static.TypeMismatch),
+				},
+			},
+		},
+		{
+			// the folder lib path isn't searched for files outside the
+			// folder, so the import can't be typed.
+			name:     "outside folder",
+			path:     filepath.Join(dir, "main.jsonnet"),
+			expected: []lsp.Diagnostic{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPerformDiagnostics(c, NewDiagnosticPublisher())
+			conn := &recordingRPCConn{}
+
+			td := config.NewTextDocument(uri.FromPath(tc.path), "(import 'lib.libsonnet').b")
+			require.NoError(t, p.Process(ctx, td, conn))
+
+			require.Len(t, conn.notifications, 1)
+			params, ok := conn.notifications[0].(*lsp.PublishDiagnosticsParams)
+			require.True(t, ok)
+
+			assert.Equal(t, tc.expected, params.Diagnostics)
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package lexical
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	jsonnet "github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/parser"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultEvalDebounce is how long evaluation waits for further edits.
+	defaultEvalDebounce = 500 * time.Millisecond
+)
+
+// EvaluationConfig is configuration for PerformEvaluation.
+type EvaluationConfig interface {
+	IdentifyConfig(path string) (token.IdentifyConfig, error)
+	EvalDiagnostics() bool
+	EvalTimeout() time.Duration
+}
+
+// PerformEvaluation evaluates Jsonnet entry files and sends runtime
+// errors to the client as diagnostics. Evaluation is debounced, and an
+// evaluation is cancelled when the document changes again. The go-jsonnet
+// VM can't be interrupted, so a cancelled evaluation keeps running in the
+// background, and the document isn't evaluated again until it finishes.
+type PerformEvaluation struct {
+	config    EvaluationConfig
+	publisher *DiagnosticPublisher
+	debounce  time.Duration
+	evaluate  evaluateFn
+
+	pending map[string]*pendingEvaluation
+	// running are closed when the evaluation of a document finishes.
+	running map[string]chan struct{}
+	mu      sync.Mutex
+}
+
+var _ DocumentProcessor = (*PerformEvaluation)(nil)
+
+type evaluateFn func(ic token.IdentifyConfig, filename, source string) ([]lsp.Diagnostic, error)
+
+type pendingEvaluation struct {
+	cancel context.CancelFunc
+}
+
+// NewPerformEvaluation creates an instance of PerformEvaluation.
+func NewPerformEvaluation(c EvaluationConfig, publisher *DiagnosticPublisher) *PerformEvaluation {
+	return &PerformEvaluation{
+		config:    c,
+		publisher: publisher,
+		debounce:  defaultEvalDebounce,
+		evaluate:  EvaluationDiagnostics,
+		pending:   make(map[string]*pendingEvaluation),
+		running:   make(map[string]chan struct{}),
+	}
+}
+
+// Process schedules the evaluation of a text document. Documents which
+// aren't `.jsonnet` files are not evaluated.
+func (p *PerformEvaluation) Process(ctx context.Context, td config.TextDocument, conn RPCConn) error {
+	filename, err := uri.ToPath(td.URI())
+	if err != nil {
+		return err
+	}
+
+	if filepath.Ext(filename) != ".jsonnet" {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pe, ok := p.pending[td.URI()]; ok {
+		pe.cancel()
+		delete(p.pending, td.URI())
+	}
+
+	if !p.config.EvalDiagnostics() {
+		return p.publisher.Publish(context.Background(), conn, td.URI(), evalDiagnostics, nil)
+	}
+
+	evalCtx, cancel := context.WithCancel(context.Background())
+	evalCtx = opentracing.ContextWithSpan(evalCtx, opentracing.SpanFromContext(ctx))
+
+	pe := &pendingEvaluation{cancel: cancel}
+	p.pending[td.URI()] = pe
+
+	go p.run(evalCtx, pe, td, filename, conn)
+
+	return nil
+}
+
+func (p *PerformEvaluation) run(ctx context.Context, pe *pendingEvaluation, td config.TextDocument, filename string, conn RPCConn) {
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		pe.cancel()
+		if p.pending[td.URI()] == pe {
+			delete(p.pending, td.URI())
+		}
+	}()
+
+	select {
+	case <-time.After(p.debounce):
+	case <-ctx.Done():
+		return
+	}
+
+	var diagnostics []lsp.Diagnostic
+
+	timeout := p.config.EvalTimeout()
+
+	ic, err := p.config.IdentifyConfig(filename)
+	if err == nil {
+		diagnostics, err = p.start(ctx, timeout, ic, td.URI(), filename, td.String())
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// the document changed while it was being evaluated.
+		return
+	case err == context.DeadlineExceeded:
+		diagnostics = []lsp.Diagnostic{
+			{
+				Severity: lsp.Information,
+				Source:   evalDiagnostics,
+				Message:  fmt.Sprintf("evaluation timed out after %v", timeout),
+			},
+		}
+	case err != nil:
+		// the evaluation failed for a reason other than the source, so
+		// there is nothing to show in the editor.
+		if span := opentracing.SpanFromContext(ctx); span != nil {
+			span.LogFields(log.Error(err))
+		}
+		diagnostics = nil
+	}
+
+	if err := p.publisher.Publish(context.Background(), conn, td.URI(), evalDiagnostics, diagnostics); err != nil {
+		if span := opentracing.SpanFromContext(ctx); span != nil {
+			span.LogFields(log.Error(err))
+		}
+	}
+}
+
+// start evaluates a document in the background and waits up to timeout
+// for it to finish. An abandoned evaluation of the document which is
+// still running is waited for first. If ctx is done or the timeout
+// expires, the evaluation is abandoned and the context's error is
+// returned. The evaluation stays running for the document until it
+// finishes.
+func (p *PerformEvaluation) start(ctx context.Context, timeout time.Duration, ic token.IdentifyConfig, uriStr, filename, source string) ([]lsp.Diagnostic, error) {
+	done := make(chan struct{})
+
+	for {
+		p.mu.Lock()
+		running, ok := p.running[uriStr]
+		if !ok {
+			if err := ctx.Err(); err != nil {
+				p.mu.Unlock()
+				return nil, err
+			}
+
+			p.running[uriStr] = done
+			p.mu.Unlock()
+			break
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-running:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	evalCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var diagnostics []lsp.Diagnostic
+	var err error
+
+	go func() {
+		diagnostics, err = p.evaluate(ic, filename, source)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if p.running[uriStr] == done {
+			delete(p.running, uriStr)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return diagnostics, err
+	case <-evalCtx.Done():
+		return nil, evalCtx.Err()
+	}
+}
+
+// EvaluationDiagnostics evaluates source with a VM created from ic and
+// converts runtime and static errors to diagnostics for filename.
+// Locations in the stack trace outside of the error location are
+// attached as related information.
+// Other errors, e.g. crashes in the VM, are returned. The go-jsonnet VM
+// can't be interrupted, so this blocks until the evaluation finishes.
+func EvaluationDiagnostics(ic token.IdentifyConfig, filename, source string) ([]lsp.Diagnostic, error) {
+	vm := ic.VM()
+	recorder := &errorRecorder{}
+	vm.ErrorFormatter = recorder
+
+	_, _ = vm.EvaluateSnippet(filename, source)
+
+	switch recorder.err.(type) {
+	case nil:
+		return []lsp.Diagnostic{}, nil
+	case jsonnet.RuntimeError, parser.StaticError:
+		return []lsp.Diagnostic{evaluationDiagnostic(filename, recorder.err)}, nil
+	default:
+		return nil, errors.Wrap(recorder.err, "evaluating")
+	}
+}
+
+// evaluationDiagnostic converts an evaluation error to a diagnostic.
+func evaluationDiagnostic(filename string, err error) lsp.Diagnostic {
+	diagnostic := lsp.Diagnostic{
+		Severity: lsp.Error,
+		Source:   evalDiagnostics,
+	}
+
+	var frames []jsonnet.TraceFrame
+	switch err := err.(type) {
+	case jsonnet.RuntimeError:
+		diagnostic.Message = err.Msg
+		// frames are ordered from the outermost call to the error.
+		for i := len(err.StackTrace) - 1; i >= 0; i-- {
+			frames = append(frames, err.StackTrace[i])
+		}
+	case parser.StaticError:
+		diagnostic.Message = err.Msg
+		frames = append(frames, jsonnet.TraceFrame{Loc: err.Loc})
+	}
+
+	primary := -1
+	for i, frame := range frames {
+		if frame.Loc.FileName == filename {
+			primary = i
+			r := position.FromJsonnetRange(frame.Loc)
+			diagnostic.Range = r.ToLSP()
+			break
+		}
+	}
+
+	for i, frame := range frames {
+		if i == primary || !frame.Loc.IsSet() || frame.Loc.FileName == "" {
+			continue
+		}
+
+		message := frame.Name
+		if message == "" {
+			message = "during evaluation"
+		}
+		if i == 0 {
+			message = diagnostic.Message
+		}
+
+		l := position.LocationFromJsonnet(frame.Loc)
+		diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, lsp.DiagnosticRelatedInformation{
+			Location: l.ToLSP(),
+			Message:  message,
+		})
+	}
+
+	return diagnostic
+}
+
+// errorRecorder is a jsonnet.ErrorFormatter which records the error
+// it formats.
+type errorRecorder struct {
+	err error
+}
+
+var _ jsonnet.ErrorFormatter = (*errorRecorder)(nil)
+
+func (er *errorRecorder) Format(err error) string {
+	er.err = err
+	return err.Error()
+}
+
+func (er *errorRecorder) SetMaxStackTraceSize(size int) {}
+
+func (er *errorRecorder) SetColorFormatter(color jsonnet.ColorFormatter) {}
@@ -0,0 +1,214 @@
+package lexical
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	jsonnet "github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/google/go-jsonnet/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluationDiagnostic(t *testing.T) {
+	mainLoc := createRange(2, 1, 2, 11)
+	mainLoc.FileName = "/main.jsonnet"
+	libLoc := createRange(2, 13, 2, 32)
+	libLoc.FileName = "/lib.libsonnet"
+
+	cases := []struct {
+		name     string
+		err      error
+		expected lsp.Diagnostic
+	}{
+		{
+			name: "runtime error in file",
+			err: jsonnet.RuntimeError{
+				Msg: "failure",
+				StackTrace: []jsonnet.TraceFrame{
+					{Loc: mainLoc, Name: "field <a>"},
+				},
+			},
+			expected: lsp.Diagnostic{
+				Range:    lspRange(1, 0, 1, 10),
+				Severity: lsp.Error,
+				Source:   evalDiagnostics,
+				Message:  "failure",
+			},
+		},
+		{
+			name: "runtime error in import",
+			err: jsonnet.RuntimeError{
+				Msg: "lib failure",
+				StackTrace: []jsonnet.TraceFrame{
+					{Loc: mainLoc, Name: "function <fail>"},
+					{Loc: ast.LocationRange{}, Name: "builtin"},
+					{Loc: libLoc, Name: "error"},
+				},
+			},
+			expected: lsp.Diagnostic{
+				Range:    lspRange(1, 0, 1, 10),
+				Severity: lsp.Error,
+				Source:   evalDiagnostics,
+				Message:  "lib failure",
+				RelatedInformation: []lsp.DiagnosticRelatedInformation{
+					{
+						Location: lsp.Location{
+							URI:   "file:///lib.libsonnet",
+							Range: lspRange(1, 12, 1, 31),
+						},
+						Message: "lib failure",
+					},
+				},
+			},
+		},
+		{
+			name: "static error in import",
+			err: parser.StaticError{
+				Msg: "unexpected end of file",
+				Loc: libLoc,
+			},
+			expected: lsp.Diagnostic{
+				Severity: lsp.Error,
+				Source:   evalDiagnostics,
+				Message:  "unexpected end of file",
+				RelatedInformation: []lsp.DiagnosticRelatedInformation{
+					{
+						Location: lsp.Location{
+							URI:   "file:///lib.libsonnet",
+							Range: lspRange(1, 12, 1, 31),
+						},
+						Message: "unexpected end of file",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := evaluationDiagnostic("/main.jsonnet", tc.err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+type fakeEvaluationConfig struct {
+	enabled bool
+}
+
+func (c *fakeEvaluationConfig) IdentifyConfig(path string) (token.IdentifyConfig, error) {
+	return token.NewIdentifyConfig(path)
+}
+
+func (c *fakeEvaluationConfig) EvalDiagnostics() bool      { return c.enabled }
+func (c *fakeEvaluationConfig) EvalTimeout() time.Duration { return 50 * time.Millisecond }
+
+func TestPerformEvaluation_Process(t *testing.T) {
+	c := &fakeEvaluationConfig{enabled: true}
+	p := NewPerformEvaluation(c, NewDiagnosticPublisher())
+	p.debounce = 20 * time.Millisecond
+
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	var evaluated []string
+	p.evaluate = func(ic token.IdentifyConfig, filename, source string) ([]lsp.Diagnostic, error) {
+		mu.Lock()
+		evaluated = append(evaluated, source)
+		mu.Unlock()
+
+		if source == "slow" {
+			<-release
+		}
+
+		return []lsp.Diagnostic{{Message: source}}, nil
+	}
+
+	evaluatedSources := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, evaluated...)
+	}
+
+	conn := &recordingRPCConn{}
+	ctx := context.Background()
+
+	// the second change arrives before the first is evaluated.
+	require.NoError(t, p.Process(ctx, config.NewTextDocument("file:///main.jsonnet", "first"), conn))
+	require.NoError(t, p.Process(ctx, config.NewTextDocument("file:///main.jsonnet", "second"), conn))
+
+	// libraries aren't evaluated.
+	require.NoError(t, p.Process(ctx, config.NewTextDocument("file:///lib.libsonnet", "lib"), conn))
+
+	waitForEvaluations(t, p)
+
+	assert.Equal(t, []string{"second"}, evaluatedSources())
+	assert.Equal(t, []string{"second"}, publishedMessages(t, conn, 0))
+
+	require.NoError(t, p.Process(ctx, config.NewTextDocument("file:///main.jsonnet", "slow"), conn))
+	waitForEvaluations(t, p)
+
+	assert.Equal(t, []string{"evaluation timed out after 50ms"}, publishedMessages(t, conn, 1))
+
+	// the abandoned evaluation is still running, so the document isn't
+	// evaluated again until it finishes.
+	require.NoError(t, p.Process(ctx, config.NewTextDocument("file:///main.jsonnet", "after"), conn))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []string{"second", "slow"}, evaluatedSources())
+
+	close(release)
+	waitForEvaluations(t, p)
+
+	assert.Equal(t, []string{"second", "slow", "after"}, evaluatedSources())
+	assert.Equal(t, []string{"after"}, publishedMessages(t, conn, 2))
+
+	c.enabled = false
+	require.NoError(t, p.Process(ctx, config.NewTextDocument("file:///main.jsonnet", "third"), conn))
+
+	assert.Empty(t, publishedMessages(t, conn, 3))
+	assert.Len(t, conn.notifications, 4)
+}
+
+func publishedMessages(t *testing.T, conn *recordingRPCConn, i int) []string {
+	require.True(t, len(conn.notifications) > i)
+
+	params, ok := conn.notifications[i].(*lsp.PublishDiagnosticsParams)
+	require.True(t, ok)
+
+	var messages []string
+	for _, d := range params.Diagnostics {
+		messages = append(messages, d.Message)
+	}
+
+	return messages
+}
+
+func waitForEvaluations(t *testing.T, p *PerformEvaluation) {
+	for i := 0; i < 100; i++ {
+		p.mu.Lock()
+		n := len(p.pending)
+		p.mu.Unlock()
+
+		if n == 0 {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("evaluations did not finish")
+}
+
+func lspRange(sl, sc, el, ec int) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: sl, Character: sc},
+		End:   lsp.Position{Line: el, Character: ec},
+	}
+}
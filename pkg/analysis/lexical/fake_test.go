@@ -13,7 +13,7 @@ type fakeDocumentProcessor struct {
 
 var _ DocumentProcessor = (*fakeDocumentProcessor)(nil)
 
-func (dp *fakeDocumentProcessor) Process(td config.TextDocument, conn RPCConn) error {
+func (dp *fakeDocumentProcessor) Process(ctx context.Context, td config.TextDocument, conn RPCConn) error {
 	return dp.processErr
 }
 
@@ -1,6 +1,7 @@
 package lexical
 
 import (
+	"context"
 	"testing"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/config"
@@ -21,5 +22,5 @@ func TestTextDocumentWatcher_watch(t *testing.T) {
 		URI:  "file:///file.jsonnet",
 	})
 
-	c.watchFn(td)
+	c.watchFn(context.Background(), td)
 }
@@ -0,0 +1,148 @@
+package token
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+)
+
+// Edit replaces the text in a range of a document.
+type Edit struct {
+	Range   jpos.Range
+	NewText string
+}
+
+// CodeActionDiagnostic is a diagnostic a code action can fix.
+type CodeActionDiagnostic struct {
+	Code    static.DiagnosticCode
+	Message string
+	Range   jpos.Range
+}
+
+// CodeAction is a change to a document.
+type CodeAction struct {
+	Title string
+	Kind  lsp.CodeActionKind
+	// Diagnostics are the diagnostics fixed by the action.
+	Diagnostics []CodeActionDiagnostic
+	Edits       []Edit
+}
+
+// CodeActionRequest is a request for the code actions available in a
+// range of a document.
+type CodeActionRequest struct {
+	Filename string
+	Source   string
+	Range    jpos.Range
+	// Diagnostics are the diagnostics in the range.
+	Diagnostics []CodeActionDiagnostic
+	LibPaths    []string
+}
+
+// QuickFix creates code actions which fix a diagnostic. A quick fix
+// which doesn't apply to the diagnostic returns no actions.
+type QuickFix func(req CodeActionRequest, tokens Tokens, diagnostic CodeActionDiagnostic) ([]CodeAction, error)
+
+// Refactoring creates code actions for the range in a request. A
+// refactoring which doesn't apply to the range returns no actions.
+type Refactoring func(req CodeActionRequest, tokens Tokens) ([]CodeAction, error)
+
+// CodeActionRegistry is a registry of quick fixes, keyed by the
+// diagnostic code they fix, and refactorings.
+type CodeActionRegistry struct {
+	quickFixes   map[static.DiagnosticCode][]QuickFix
+	refactorings []Refactoring
+
+	mu sync.RWMutex
+}
+
+// NewCodeActionRegistry creates an empty instance of CodeActionRegistry.
+func NewCodeActionRegistry() *CodeActionRegistry {
+	return &CodeActionRegistry{
+		quickFixes: make(map[static.DiagnosticCode][]QuickFix),
+	}
+}
+
+// NewDefaultCodeActionRegistry creates an instance of CodeActionRegistry
+// containing the built in quick fixes and refactorings.
+func NewDefaultCodeActionRegistry() *CodeActionRegistry {
+	r := NewCodeActionRegistry()
+
+	r.RegisterQuickFix(static.UnusedLocal, removeUnusedLocal)
+	r.RegisterQuickFix(static.UndefinedVariable, addMissingImport)
+
+	r.RegisterRefactoring(hideField)
+	r.RegisterRefactoring(mixinField)
+	r.RegisterRefactoring(formatConcatenation)
+
+	return r
+}
+
+// RegisterQuickFix registers a quick fix for diagnostics with a code.
+func (r *CodeActionRegistry) RegisterQuickFix(code static.DiagnosticCode, fix QuickFix) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.quickFixes[code] = append(r.quickFixes[code], fix)
+}
+
+// RegisterRefactoring registers a refactoring.
+func (r *CodeActionRegistry) RegisterRefactoring(refactoring Refactoring) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refactorings = append(r.refactorings, refactoring)
+}
+
+// CodeActions returns the quick fixes for the diagnostics in a request
+// followed by the refactorings for its range.
+func (r *CodeActionRegistry) CodeActions(ctx context.Context, req CodeActionRequest) ([]CodeAction, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "codeActions")
+	defer span.Finish()
+
+	tokens, err := Lex(req.Filename, req.Source)
+	if err != nil {
+		return nil, errors.Wrap(err, "lexing source")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var actions []CodeAction
+
+	for _, diagnostic := range req.Diagnostics {
+		for _, fix := range r.quickFixes[diagnostic.Code] {
+			fixActions, err := fix(req, tokens, diagnostic)
+			if err != nil {
+				return nil, errors.Wrapf(err, "fixing %s", diagnostic.Code)
+			}
+
+			for i := range fixActions {
+				fixActions[i].Kind = lsp.QuickFix
+				fixActions[i].Diagnostics = []CodeActionDiagnostic{diagnostic}
+			}
+
+			actions = append(actions, fixActions...)
+		}
+	}
+
+	for _, refactoring := range r.refactorings {
+		refactorActions, err := refactoring(req, tokens)
+		if err != nil {
+			return nil, errors.Wrap(err, "refactoring")
+		}
+
+		for i := range refactorActions {
+			refactorActions[i].Kind = lsp.RefactorRewrite
+		}
+
+		actions = append(actions, refactorActions...)
+	}
+
+	return actions, nil
+}
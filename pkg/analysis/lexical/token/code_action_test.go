@@ -0,0 +1,274 @@
+package token
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeActionRegistry_CodeActions_quickFixes(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		expected []string
+	}{
+		{
+			name:     "only local",
+			source:   "local a = 1;\n{}",
+			expected: []string{"{}"},
+		},
+		{
+			name:     "first bind",
+			source:   "local a = 1, b = 2;\nb",
+			expected: []string{"local b = 2;\nb"},
+		},
+		{
+			name:     "last bind",
+			source:   "local b = 2, a = 1;\nb",
+			expected: []string{"local b = 2;\nb"},
+		},
+		{
+			name:     "function bind",
+			source:   "local f(x) = x;\n{}",
+			expected: []string{"{}"},
+		},
+		{
+			name:     "bind containing a local",
+			source:   "local a = local b = 1; b;\n{}",
+			expected: []string{"{}"},
+		},
+		{
+			name:     "object local",
+			source:   "{\n  local a = 1,\n  b: 2,\n}",
+			expected: []string{"{\n  b: 2,\n}"},
+		},
+		{
+			name:     "last object local",
+			source:   "{ b: 2, local a = [1, 2] }",
+			expected: []string{"{ b: 2 }"},
+		},
+		{
+			name:     "comments are kept",
+			source:   "local a = 1; // a\n{}",
+			expected: []string{" // a\n{}"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := codeActionRequest(t, tc.source, jpos.New(1, 1))
+
+			r := NewDefaultCodeActionRegistry()
+			actions, err := r.CodeActions(context.Background(), req)
+			require.NoError(t, err)
+
+			var got []string
+			for _, action := range actions {
+				if action.Kind != lsp.QuickFix {
+					continue
+				}
+
+				require.Len(t, action.Diagnostics, 1)
+				assert.Equal(t, static.UnusedLocal, action.Diagnostics[0].Code)
+				got = append(got, applyEdits(tc.source, action.Edits))
+			}
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestCodeActionRegistry_CodeActions_addMissingImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "code-action")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"k.libsonnet", "nested/k.libsonnet", "nested/other.libsonnet", ".hidden/k.libsonnet"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte("{}"), 0644))
+	}
+
+	source := "// header\n{\n  a: k.b,\n}"
+	req := codeActionRequest(t, source, jpos.New(3, 6))
+	req.LibPaths = []string{dir}
+
+	r := NewDefaultCodeActionRegistry()
+	actions, err := r.CodeActions(context.Background(), req)
+	require.NoError(t, err)
+
+	var titles, got []string
+	for _, action := range actions {
+		if action.Kind == lsp.QuickFix {
+			titles = append(titles, action.Title)
+			got = append(got, applyEdits(source, action.Edits))
+		}
+	}
+
+	expectedTitles := []string{
+		"Import k from 'k.libsonnet'",
+		"Import k from 'nested/k.libsonnet'",
+	}
+	assert.Equal(t, expectedTitles, titles)
+
+	expected := []string{
+		"// header\nlocal k = import 'k.libsonnet';\n{\n  a: k.b,\n}",
+		"// header\nlocal k = import 'nested/k.libsonnet';\n{\n  a: k.b,\n}",
+	}
+	assert.Equal(t, expected, got)
+}
+
+func TestCodeActionRegistry_CodeActions_refactorings(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		pos      jpos.Position
+		expected map[string]string
+	}{
+		{
+			name:   "visible field",
+			source: "{ a: 1 }",
+			pos:    jpos.New(1, 3),
+			expected: map[string]string{
+				"Hide field a": "{ a:: 1 }",
+				"Merge field a with inherited field using +:": "{ a+: 1 }",
+			},
+		},
+		{
+			name:   "cursor on operator",
+			source: "{ a: 1, 'b c'+: {} }",
+			pos:    jpos.New(1, 14),
+			expected: map[string]string{
+				"Hide field b c": "{ a: 1, 'b c'+:: {} }",
+			},
+		},
+		{
+			name:     "hidden method",
+			source:   "{ f(x):: x }",
+			pos:      jpos.New(1, 3),
+			expected: map[string]string{},
+		},
+		{
+			name:     "slice",
+			source:   "local a = [1, 2]; { b: a[x:2] }",
+			pos:      jpos.New(1, 26),
+			expected: map[string]string{},
+		},
+		{
+			name:   "concatenation",
+			source: "local name = 'x'; 'Hello ' + name + ', 100%' + std.length(name)",
+			pos:    jpos.New(1, 22),
+			expected: map[string]string{
+				"Convert concatenation to format string": "local name = 'x'; 'Hello %s, 100%%%s' % [name, std.length(name)]",
+			},
+		},
+		{
+			name:   "nested concatenation",
+			source: "local name = 'x'; 'a' + std.join(',', ['b' + name])",
+			pos:    jpos.New(1, 42),
+			expected: map[string]string{
+				"Convert concatenation to format string": "local name = 'x'; 'a' + std.join(',', ['b%s' % [name]])",
+			},
+		},
+		{
+			name:     "numbers are added first",
+			source:   "local n = 1; n + 2 + 'a'",
+			pos:      jpos.New(1, 15),
+			expected: map[string]string{},
+		},
+		{
+			name:     "only literals",
+			source:   "'a' + 'b'",
+			pos:      jpos.New(1, 2),
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := CodeActionRequest{
+				Filename: "file.jsonnet",
+				Source:   tc.source,
+				Range:    jpos.NewRange(tc.pos, tc.pos),
+			}
+
+			r := NewDefaultCodeActionRegistry()
+			actions, err := r.CodeActions(context.Background(), req)
+			require.NoError(t, err)
+
+			got := make(map[string]string)
+			for _, action := range actions {
+				assert.Equal(t, lsp.RefactorRewrite, action.Kind)
+				got[action.Title] = applyEdits(tc.source, action.Edits)
+			}
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestCodeActionRegistry_RegisterQuickFix(t *testing.T) {
+	r := NewCodeActionRegistry()
+	r.RegisterQuickFix(static.ShadowedStd, func(req CodeActionRequest, tokens Tokens, diagnostic CodeActionDiagnostic) ([]CodeAction, error) {
+		return []CodeAction{{Title: "rename std"}}, nil
+	})
+
+	req := codeActionRequest(t, "local std = {}; std", jpos.New(1, 7))
+
+	actions, err := r.CodeActions(context.Background(), req)
+	require.NoError(t, err)
+
+	require.Len(t, actions, 1)
+	assert.Equal(t, "rename std", actions[0].Title)
+	assert.Equal(t, lsp.QuickFix, actions[0].Kind)
+	require.Len(t, actions[0].Diagnostics, 1)
+	assert.Equal(t, static.ShadowedStd, actions[0].Diagnostics[0].Code)
+}
+
+// codeActionRequest creates a request containing the diagnostics for
+// source.
+func codeActionRequest(t *testing.T, source string, pos jpos.Position) CodeActionRequest {
+	node, err := Parse("file.jsonnet", source, nil)
+	require.NoError(t, err)
+
+	var diagnostics []CodeActionDiagnostic
+	for _, d := range static.Diagnose(node) {
+		diagnostics = append(diagnostics, CodeActionDiagnostic{
+			Code:    d.Code,
+			Message: d.Message,
+			Range:   jpos.FromJsonnetRange(d.Loc),
+		})
+	}
+
+	return CodeActionRequest{
+		Filename:    "file.jsonnet",
+		Source:      source,
+		Range:       jpos.NewRange(pos, pos),
+		Diagnostics: diagnostics,
+	}
+}
+
+// applyEdits applies non overlapping edits to source.
+func applyEdits(source string, edits []Edit) string {
+	sorted := append([]Edit(nil), edits...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sourceOffset(source, sorted[i].Range.Start.ToJsonnet()) > sourceOffset(source, sorted[j].Range.Start.ToJsonnet())
+	})
+
+	for _, edit := range sorted {
+		start := sourceOffset(source, edit.Range.Start.ToJsonnet())
+		end := sourceOffset(source, edit.Range.End.ToJsonnet())
+		source = source[:start] + edit.NewText + source[end:]
+	}
+
+	return source
+}
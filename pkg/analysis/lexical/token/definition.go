@@ -0,0 +1,117 @@
+package token
+
+import (
+	"context"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/google/go-jsonnet/ast"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+var (
+	errNoDefinition = errors.New("no definition found")
+)
+
+// Definition returns the locations where the item at a position is
+// declared. Locals, function parameters, object fields reached through
+// indexes, `self`, `super`, `$` and imports are followed. Imports
+// are resolved relative to filename and then through libPaths.
+func Definition(ctx context.Context, filename, source string, pos jpos.Position, libPaths []string) ([]jpos.Location, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "definition")
+	defer span.Finish()
+
+	r := newResolver(libPaths)
+	node, err := r.addSource(filename, source)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := locateNode(node, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := r.definition(found, pos)
+	if err != nil {
+		span.LogFields(
+			log.Error(err),
+		)
+		return []jpos.Location{}, nil
+	}
+
+	return []jpos.Location{jpos.LocationFromJsonnet(loc)}, nil
+}
+
+// definition finds where the item at pos in node n is declared.
+// nolint: gocyclo
+func (r *resolver) definition(n ast.Node, pos jpos.Position) (ast.LocationRange, error) {
+	switch n := n.(type) {
+	case *ast.Var:
+		b, err := r.lookup(n)
+		if err != nil {
+			return ast.LocationRange{}, err
+		}
+		if !b.loc.IsSet() {
+			// bindings created while desugaring, e.g. `$`, don't exist
+			// in the source.
+			return ast.LocationRange{}, errNoDefinition
+		}
+		return b.loc, nil
+	case *ast.Index:
+		name, ok := literalIndex(n.Index)
+		if !ok {
+			return ast.LocationRange{}, errNoDefinition
+		}
+		f, err := r.field(n.Target, name)
+		if err != nil {
+			return ast.LocationRange{}, err
+		}
+		return f.loc, nil
+	case *ast.SuperIndex:
+		name, ok := literalIndex(n.Index)
+		if !ok {
+			return ast.LocationRange{}, errNoDefinition
+		}
+		f, err := r.superField(n, name)
+		if err != nil {
+			return ast.LocationRange{}, err
+		}
+		return f.loc, nil
+	case *ast.Import:
+		return r.resolveImportLoc(n.Loc().FileName, n.File.Value)
+	case *ast.ImportStr:
+		return r.resolveImportLoc(n.Loc().FileName, n.File.Value)
+	case *ast.Local:
+		for _, bind := range n.Binds {
+			if pos.IsInJsonnetRange(bind.VarLoc) {
+				return bind.VarLoc, nil
+			}
+		}
+	case *ast.Function:
+		for _, id := range n.Parameters.Required {
+			if loc := n.Parameters.RequiredLocs[id]; pos.IsInJsonnetRange(loc) {
+				return loc, nil
+			}
+		}
+		for _, param := range n.Parameters.Optional {
+			if pos.IsInJsonnetRange(param.Loc) {
+				return param.Loc, nil
+			}
+		}
+
+		// functions declared with `local f(x) = ...` enclose the bind name.
+		if local, ok := r.parents[n].(*ast.Local); ok {
+			return r.definition(local, pos)
+		}
+	case *ast.DesugaredObject:
+		for _, loc := range n.FieldLocs {
+			if pos.IsInJsonnetRange(loc) {
+				return loc, nil
+			}
+		}
+	}
+
+	return ast.LocationRange{}, errNoDefinition
+}
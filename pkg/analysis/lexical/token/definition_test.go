@@ -0,0 +1,140 @@
+package token
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinition(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "definition"))
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "file.jsonnet")
+	lib := filepath.Join(dir, "lib", "foo.libsonnet")
+	libPaths := []string{filepath.Join(dir, "lib")}
+
+	cases := []struct {
+		name     string
+		source   string
+		pos      jpos.Position
+		expected []jpos.Location
+	}{
+		{
+			name:   "local",
+			source: "local x=1; x",
+			pos:    jpos.New(1, 12),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 7, 1, 8)),
+			},
+		},
+		{
+			name:   "function parameter",
+			source: "local id(x, y=1)=x+y; id(1)",
+			pos:    jpos.New(1, 20),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 13, 1, 16)),
+			},
+		},
+		{
+			name:   "function bind",
+			source: "local id(x)=x; id(1)",
+			pos:    jpos.New(1, 16),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 7, 1, 9)),
+			},
+		},
+		{
+			name:   "nested index",
+			source: "local o={a:{b:1}}; o.a.b",
+			pos:    jpos.New(1, 24),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 13, 1, 14)),
+			},
+		},
+		{
+			name:   "self",
+			source: "{a: 1, b: self.a}",
+			pos:    jpos.New(1, 16),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 2, 1, 3)),
+			},
+		},
+		{
+			name:   "dollar",
+			source: "{a: 1, b: {c: $.a}}",
+			pos:    jpos.New(1, 17),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 2, 1, 3)),
+			},
+		},
+		{
+			name:   "super",
+			source: "{a: 1} + {b: super.a}",
+			pos:    jpos.New(1, 20),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 2, 1, 3)),
+			},
+		},
+		{
+			name:   "self in mixin",
+			source: "{a: 1} + {b: self.a}",
+			pos:    jpos.New(1, 19),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 2, 1, 3)),
+			},
+		},
+		{
+			name:   "import",
+			source: `local x = import "foo.libsonnet"; x`,
+			pos:    jpos.New(1, 20),
+			expected: []jpos.Location{
+				jpos.NewLocation(lib, jpos.NewRangeFromCoords(1, 1, 1, 1)),
+			},
+		},
+		{
+			name:   "field in import",
+			source: `local x = import "foo.libsonnet"; x.deployment.spec`,
+			pos:    jpos.New(1, 49),
+			expected: []jpos.Location{
+				jpos.NewLocation(lib, jpos.NewRangeFromCoords(1, 30, 1, 34)),
+			},
+		},
+		{
+			name:   "field in import mixin",
+			source: `local x = import "foo.libsonnet"; x.service.port`,
+			pos:    jpos.New(1, 46),
+			expected: []jpos.Location{
+				jpos.NewLocation(lib, jpos.NewRangeFromCoords(3, 14, 3, 18)),
+			},
+		},
+		{
+			name:   "import relative to file",
+			source: `(import "local.libsonnet").name`,
+			pos:    jpos.New(1, 29),
+			expected: []jpos.Location{
+				jpos.NewLocation(filepath.Join(dir, "local.libsonnet"), jpos.NewRangeFromCoords(2, 3, 2, 7)),
+			},
+		},
+		{
+			name:     "std",
+			source:   "std.length([])",
+			pos:      jpos.New(1, 2),
+			expected: []jpos.Location{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			got, err := Definition(ctx, file, tc.source, tc.pos, libPaths)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
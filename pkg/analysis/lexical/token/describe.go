@@ -0,0 +1,294 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/google/go-jsonnet/ast"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+// Description describes the item at a position.
+type Description struct {
+	// Name is the name of the item, e.g. the name of a local or field.
+	Name string
+	// Type is the inferred type of the item's value, e.g. object or
+	// array of string. It is empty if the type is unknown.
+	Type string
+	// Signature is the signature of a function including the default
+	// arguments of its parameters.
+	Signature string
+	// Value is the JSON value of the item. It is only set if the value
+	// can be computed without evaluating the source.
+	Value string
+	// Documentation is the doc comment of the declaration rendered as
+	// markdown.
+	Documentation string
+	// Deprecated is true if the doc comment marks the item as deprecated.
+	Deprecated bool
+	// Declaration is where the item is declared.
+	Declaration ast.LocationRange
+}
+
+// Describe describes the item at a position. Like Definition, locals,
+// object fields, `self`, `super`, `$` and imports are followed
+// statically.
+func Describe(ctx context.Context, filename, source string, pos jpos.Position, libPaths []string, nodeCache *NodeCache) (*Description, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "describe")
+	defer span.Finish()
+
+	r := newResolver(libPaths)
+	r.nodeCache = nodeCache
+
+	node, err := r.addSource(filename, source)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := locateNode(node, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	if d, ok := describeStd(found); ok {
+		return d, nil
+	}
+
+	name, target, ok := r.describedNode(found, pos)
+	if !ok {
+		return nil, errors.Errorf("unable to describe %T", found)
+	}
+
+	d := &Description{Name: name}
+
+	if target != nil {
+		if t := r.typeInferrer().infer(target); t.IsKnown() {
+			d.Type = t.String()
+		}
+
+		value, err := r.value(target)
+		if err != nil {
+			span.LogFields(log.Error(err))
+		} else {
+			r.describeValue(d, value)
+		}
+	}
+
+	decl, err := r.definition(found, pos)
+	if err != nil {
+		span.LogFields(log.Error(err))
+		return d, nil
+	}
+	d.Declaration = decl
+
+	if declSource, ok := r.sources[decl.FileName]; ok {
+		comment, err := DocComment(decl.FileName, declSource, decl.Begin)
+		if err != nil {
+			span.LogFields(log.Error(err))
+		}
+
+		doc := ParseDoc(comment)
+		d.Documentation = doc.Markdown()
+		d.Deprecated = doc.Deprecated
+	}
+
+	return d, nil
+}
+
+// describeStd describes a standard library function.
+func describeStd(n ast.Node) (*Description, bool) {
+	idx, ok := n.(*ast.Index)
+	if !ok {
+		return nil, false
+	}
+
+	v, ok := idx.Target.(*ast.Var)
+	if !ok || v.Id != "std" {
+		return nil, false
+	}
+
+	name, ok := literalIndex(idx.Index)
+	if !ok {
+		return nil, false
+	}
+
+	stdlib, err := LoadStdlib()
+	if err != nil {
+		return nil, false
+	}
+
+	f, ok := stdlib.Function(name)
+	if !ok {
+		return nil, false
+	}
+
+	return &Description{
+		Name:          name,
+		Type:          "function",
+		Signature:     f.Signature(),
+		Documentation: f.Documentation,
+	}, true
+}
+
+// describedNode returns the name of the item at pos in n and the node
+// its value is resolved from. The node is nil if the value is unknown,
+// e.g. for function parameters.
+// nolint: gocyclo
+func (r *resolver) describedNode(n ast.Node, pos jpos.Position) (string, ast.Node, bool) {
+	switch n := n.(type) {
+	case *ast.Var:
+		return string(n.Id), n, true
+	case *ast.Index:
+		name, ok := literalIndex(n.Index)
+		return name, n, ok
+	case *ast.SuperIndex:
+		name, ok := literalIndex(n.Index)
+		return name, n, ok
+	case *ast.Import:
+		return n.File.Value, n, true
+	case *ast.Local:
+		for _, bind := range n.Binds {
+			if pos.IsInJsonnetRange(bind.VarLoc) {
+				return string(bind.Variable), bind.Body, true
+			}
+		}
+	case *ast.Function:
+		for _, id := range n.Parameters.Required {
+			if loc := n.Parameters.RequiredLocs[id]; pos.IsInJsonnetRange(loc) {
+				return string(id), nil, true
+			}
+		}
+		for _, param := range n.Parameters.Optional {
+			if pos.IsInJsonnetRange(param.Loc) {
+				return string(param.Name), param.DefaultArg, true
+			}
+		}
+
+		// functions declared with `local f(x) = ...` enclose the bind name.
+		if local, ok := r.parents[n].(*ast.Local); ok {
+			return r.describedNode(local, pos)
+		}
+	case *ast.DesugaredObject:
+		for _, field := range n.Fields {
+			name, err := fieldName(field)
+			if err != nil {
+				continue
+			}
+
+			if loc, ok := n.FieldLocs[name]; ok && pos.IsInJsonnetRange(loc) {
+				return name, field.Body, true
+			}
+		}
+	}
+
+	return "", nil, false
+}
+
+// describeValue adds the signature and constant value of value to d.
+func (r *resolver) describeValue(d *Description, value ast.Node) {
+	if fn, ok := value.(*ast.Function); ok {
+		d.Signature = r.signature(d.Name, fn)
+	}
+
+	if v, ok := constantValue(value); ok {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err == nil {
+			d.Value = strings.TrimSuffix(buf.String(), "\n")
+		}
+	}
+}
+
+// signature creates the signature of a function named name. Default
+// arguments are copied from the source.
+func (r *resolver) signature(name string, fn *ast.Function) string {
+	var params []string
+	for _, id := range fn.Parameters.Required {
+		params = append(params, string(id))
+	}
+
+	for _, param := range fn.Parameters.Optional {
+		def := "..."
+		if param.DefaultArg != nil {
+			loc := param.DefaultArg.Loc()
+			if source, ok := r.sources[loc.FileName]; ok && loc.IsSet() {
+				def = sourceText(source, *loc)
+			}
+		}
+
+		params = append(params, fmt.Sprintf("%s=%s", param.Name, def))
+	}
+
+	if name == "" {
+		name = "function"
+	}
+
+	return fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+}
+
+// constantValue converts a value which only contains literals to its
+// JSON representation. Hidden fields are not included.
+// nolint: gocyclo
+func constantValue(n ast.Node) (interface{}, bool) {
+	switch n := n.(type) {
+	case *ast.LiteralBoolean:
+		return n.Value, true
+	case *ast.LiteralNull:
+		return nil, true
+	case *ast.LiteralNumber:
+		return n.Value, true
+	case *ast.LiteralString:
+		return n.Value, true
+	case *ast.Unary:
+		if number, ok := n.Expr.(*ast.LiteralNumber); ok && n.Op == ast.UopMinus {
+			return -number.Value, true
+		}
+	case *ast.Array:
+		elements := make([]interface{}, 0, len(n.Elements))
+		for _, elem := range n.Elements {
+			v, ok := constantValue(elem)
+			if !ok {
+				return nil, false
+			}
+			elements = append(elements, v)
+		}
+		return elements, true
+	case *ast.DesugaredObject:
+		if len(n.Asserts) > 0 {
+			return nil, false
+		}
+
+		fields := make(map[string]interface{})
+		for _, field := range n.Fields {
+			name, err := fieldName(field)
+			if err != nil || field.PlusSuper {
+				return nil, false
+			}
+
+			body := field.Body
+			if len(objectLocalBinds(body)) > 0 {
+				body = body.(*ast.Local).Body
+			}
+
+			v, ok := constantValue(body)
+			if !ok {
+				return nil, false
+			}
+
+			if field.Hide != ast.ObjectFieldHidden {
+				fields[name] = v
+			}
+		}
+		return fields, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,155 @@
+package token
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "definition"))
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "file.jsonnet")
+	libPaths := []string{filepath.Join(dir, "lib")}
+
+	cases := []struct {
+		name     string
+		source   string
+		pos      jpos.Position
+		expected Description
+		isErr    bool
+	}{
+		{
+			name:   "local name",
+			source: "// The answer.\nlocal a = 42; a",
+			pos:    jpos.New(2, 7),
+			expected: Description{
+				Name:          "a",
+				Type:          "number",
+				Value:         "42",
+				Documentation: "The answer.",
+				Declaration:   createRange(file, 2, 7, 2, 8),
+			},
+		},
+		{
+			name:   "variable",
+			source: "local o = {a: 'x', b:: [1, -2], c: null};\no",
+			pos:    jpos.New(2, 1),
+			expected: Description{
+				Name:        "o",
+				Type:        "object",
+				Value:       "{\n  \"a\": \"x\",\n  \"c\": null\n}",
+				Declaration: createRange(file, 1, 7, 1, 8),
+			},
+		},
+		{
+			name:   "object with references",
+			source: "local x = 1;\nlocal o = {a: x};\no",
+			pos:    jpos.New(3, 1),
+			expected: Description{
+				Name:        "o",
+				Type:        "object",
+				Declaration: createRange(file, 2, 7, 2, 8),
+			},
+		},
+		{
+			name:   "inferred type",
+			source: "local f(x) = [x + ''];\nlocal xs = f(1);\nxs",
+			pos:    jpos.New(3, 1),
+			expected: Description{
+				Name:        "xs",
+				Type:        "array of string",
+				Declaration: createRange(file, 2, 7, 2, 9),
+			},
+		},
+		{
+			name:   "function",
+			source: "// Adds numbers.\nlocal add(x, y=1) = x + y;\nadd(1)",
+			pos:    jpos.New(3, 1),
+			expected: Description{
+				Name:          "add",
+				Type:          "function",
+				Signature:     "add(x, y=1)",
+				Documentation: "Adds numbers.",
+				Declaration:   createRange(file, 2, 7, 2, 10),
+			},
+		},
+		{
+			name:   "field",
+			source: "local o = {\n  // The name.\n  name: 'app' + '-' + 'x',\n};\no.name",
+			pos:    jpos.New(5, 4),
+			expected: Description{
+				Name:          "name",
+				Type:          "string",
+				Documentation: "The name.",
+				Declaration:   createRange(file, 3, 3, 3, 7),
+			},
+		},
+		{
+			name:   "parameter",
+			source: "local f(x) = x; f(1)",
+			pos:    jpos.New(1, 9),
+			expected: Description{
+				Name:        "x",
+				Declaration: createRange(file, 1, 9, 1, 10),
+			},
+		},
+		{
+			name:   "imported field",
+			source: "local foo = import 'foo.libsonnet'; foo.deployment",
+			pos:    jpos.New(1, 42),
+			expected: Description{
+				Name:        "deployment",
+				Type:        "object",
+				Value:       "{\n  \"spec\": {\n    \"replicas\": 1\n  }\n}",
+				Declaration: createRange(filepath.Join(dir, "lib", "foo.libsonnet"), 1, 16, 1, 26),
+			},
+		},
+		{
+			name:   "std function",
+			source: "std.length([])",
+			pos:    jpos.New(1, 6),
+			expected: Description{
+				Name:      "length",
+				Type:      "function",
+				Signature: "std.length(x)",
+			},
+		},
+		{
+			name:   "literal",
+			source: "1",
+			pos:    jpos.New(1, 1),
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Describe(context.Background(), file, tc.source, tc.pos, libPaths, NewNodeCache())
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.expected.Signature == "std.length(x)" {
+				assert.NotEmpty(t, got.Documentation)
+				got.Documentation = ""
+			}
+
+			assert.Equal(t, tc.expected.Name, got.Name)
+			assert.Equal(t, tc.expected.Type, got.Type)
+			assert.Equal(t, tc.expected.Signature, got.Signature)
+			assert.Equal(t, tc.expected.Value, got.Value)
+			assert.Equal(t, tc.expected.Documentation, got.Documentation)
+			assert.Equal(t, tc.expected.Declaration.FileName, got.Declaration.FileName)
+			assert.Equal(t, tc.expected.Declaration.Begin, got.Declaration.Begin)
+			assert.Equal(t, tc.expected.Declaration.End, got.Declaration.End)
+		})
+	}
+}
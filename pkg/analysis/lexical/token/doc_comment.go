@@ -0,0 +1,419 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-jsonnet/ast"
+	"github.com/pkg/errors"
+)
+
+// Doc is the documentation of a local or object field. It is parsed from
+// the doc comment on the lines directly before the declaration. A doc
+// comment is a block of `//` or `#` comments, or a `/** ... */` comment:
+//
+//	/**
+//	 * Creates a deployment.
+//	 *
+//	 * The deployment has a single container.
+//	 *
+//	 * @param name the name of the deployment
+//	 * @param replicas the number of replicas
+//	 * @return a deployment object
+//	 * @deprecated use deployment.new instead
+//	 * @example
+//	 * deployment('web', replicas=2)
+//	 */
+//
+// The first paragraph is the summary and the following paragraphs are the
+// description. Text following a tag continues on the next lines until a
+// blank line or another tag. Examples continue until the next tag.
+type Doc struct {
+	Summary     string
+	Description string
+	Params      []DocParam
+	Returns     string
+	// Deprecated is true if the comment has a @deprecated tag.
+	Deprecated        bool
+	DeprecationReason string
+	Examples          []string
+}
+
+// DocParam is the documentation of a function parameter.
+type DocParam struct {
+	Name        string
+	Description string
+}
+
+// ParseDoc parses a doc comment without its comment delimiters.
+// nolint: gocyclo
+func ParseDoc(comment string) Doc {
+	var d Doc
+	var text, example []string
+	tag := ""
+
+	appendTo := func(s *string, line string) {
+		if *s == "" {
+			*s = line
+			return
+		}
+		*s += " " + line
+	}
+
+	endExample := func() {
+		if tag == "example" {
+			if e := strings.Trim(strings.Join(example, "\n"), "\n"); e != "" {
+				d.Examples = append(d.Examples, e)
+			}
+			example = nil
+		}
+	}
+
+	for _, line := range strings.Split(comment, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "@") {
+			name, rest := splitWord(trimmed[1:])
+
+			switch name {
+			case "param":
+				endExample()
+				paramName, desc := splitWord(rest)
+				d.Params = append(d.Params, DocParam{Name: paramName, Description: desc})
+				tag = name
+				continue
+			case "return", "returns":
+				endExample()
+				d.Returns = rest
+				tag = "return"
+				continue
+			case "deprecated":
+				endExample()
+				d.Deprecated = true
+				d.DeprecationReason = rest
+				tag = name
+				continue
+			case "example":
+				endExample()
+				tag = name
+				if rest != "" {
+					example = append(example, rest)
+				}
+				continue
+			}
+		}
+
+		switch {
+		case tag == "example":
+			example = append(example, line)
+		case trimmed == "":
+			tag = ""
+			text = append(text, "")
+		case tag == "param":
+			appendTo(&d.Params[len(d.Params)-1].Description, trimmed)
+		case tag == "return":
+			appendTo(&d.Returns, trimmed)
+		case tag == "deprecated":
+			appendTo(&d.DeprecationReason, trimmed)
+		default:
+			text = append(text, trimmed)
+		}
+	}
+	endExample()
+
+	paragraphs := docParagraphs(text)
+	if len(paragraphs) > 0 {
+		d.Summary = paragraphs[0]
+		d.Description = strings.Join(paragraphs[1:], "\n\n")
+	}
+
+	return d
+}
+
+// splitWord splits the first word from s.
+func splitWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, " \t")
+	if i == -1 {
+		return s, ""
+	}
+
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+func docParagraphs(lines []string) []string {
+	var paragraphs, cur []string
+	for _, line := range append(lines, "") {
+		if line != "" {
+			cur = append(cur, line)
+			continue
+		}
+
+		if len(cur) > 0 {
+			paragraphs = append(paragraphs, strings.Join(cur, "\n"))
+			cur = nil
+		}
+	}
+
+	return paragraphs
+}
+
+// IsEmpty returns true if there is no documentation.
+func (d Doc) IsEmpty() bool {
+	return d.Summary == "" && d.Description == "" && len(d.Params) == 0 &&
+		d.Returns == "" && !d.Deprecated && len(d.Examples) == 0
+}
+
+// Param returns the description of the parameter named name.
+func (d Doc) Param(name string) string {
+	for _, p := range d.Params {
+		if p.Name == name {
+			return p.Description
+		}
+	}
+
+	return ""
+}
+
+// Markdown renders the documentation as markdown.
+func (d Doc) Markdown() string {
+	var sections []string
+
+	if d.Deprecated {
+		sections = append(sections, joinNonEmpty(": ", "**Deprecated**", d.DeprecationReason))
+	}
+
+	sections = append(sections, d.Summary, d.Description)
+
+	if len(d.Params) > 0 {
+		lines := []string{"**Parameters**", ""}
+		for _, p := range d.Params {
+			lines = append(lines, joinNonEmpty(": ", fmt.Sprintf("- `%s`", p.Name), p.Description))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if d.Returns != "" {
+		sections = append(sections, "**Returns** "+d.Returns)
+	}
+
+	for _, example := range d.Examples {
+		sections = append(sections, fmt.Sprintf("**Example**\n\n```jsonnet\n%s\n```", example))
+	}
+
+	return joinNonEmpty("\n\n", sections...)
+}
+
+// Text renders the documentation as plain text.
+func (d Doc) Text() string {
+	var sections []string
+
+	if d.Deprecated {
+		sections = append(sections, joinNonEmpty(": ", "Deprecated", d.DeprecationReason))
+	}
+
+	sections = append(sections, d.Summary, d.Description)
+
+	if len(d.Params) > 0 {
+		lines := []string{"Parameters:"}
+		for _, p := range d.Params {
+			lines = append(lines, joinNonEmpty(": ", "  "+p.Name, p.Description))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if d.Returns != "" {
+		sections = append(sections, "Returns: "+d.Returns)
+	}
+
+	for _, example := range d.Examples {
+		sections = append(sections, "Example:\n  "+strings.Replace(example, "\n", "\n  ", -1))
+	}
+
+	return joinNonEmpty("\n\n", sections...)
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, part := range parts {
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+
+	return strings.Join(out, sep)
+}
+
+// DocComment returns the doc comment for the token which begins at loc
+// without its comment delimiters. The doc comment is the block of `//`
+// or `#` comments, or the `/** ... */` comment, on the lines directly
+// before the token. A blank line ends the block and comments trailing the
+// previous token are ignored. The doc comment of a local is before its
+// `local` keyword.
+func DocComment(filename, source string, loc ast.Location) (string, error) {
+	tokens, err := Lex(filename, source)
+	if err != nil {
+		return "", err
+	}
+
+	comment, ok := newDocComments(tokens).at(loc)
+	if !ok {
+		return "", errors.Errorf("no token begins at %d:%d", loc.Line, loc.Column)
+	}
+
+	return comment, nil
+}
+
+// docComments finds doc comments in tokens.
+type docComments struct {
+	tokens Tokens
+	index  map[ast.Location]int
+}
+
+func newDocComments(tokens Tokens) *docComments {
+	index := make(map[ast.Location]int)
+	for i := range tokens {
+		index[tokens[i].Loc.Begin] = i
+	}
+
+	return &docComments{
+		tokens: tokens,
+		index:  index,
+	}
+}
+
+// at returns the doc comment for the token which begins at loc. It
+// returns false if no token begins at loc.
+func (dc *docComments) at(loc ast.Location) (string, bool) {
+	i, ok := dc.index[loc]
+	if !ok {
+		return "", false
+	}
+
+	if i > 0 && dc.tokens[i-1].Kind == TokenLocal {
+		i--
+	}
+
+	return docCommentFromFodder(dc.tokens[i].Fodder(), i == 0), true
+}
+
+// doc returns the parsed doc comment for the token which begins at loc.
+func (dc *docComments) doc(loc ast.Location) Doc {
+	comment, _ := dc.at(loc)
+	return ParseDoc(comment)
+}
+
+func docCommentFromFodder(fodder Fodder, atStart bool) string {
+	var lines []string
+
+	ownLine := atStart
+	for _, f := range fodder {
+		switch f.kind {
+		case fodderWhitespace:
+			newlines := strings.Count(f.data, "\n")
+			if newlines > 0 {
+				ownLine = true
+			}
+			if newlines > 1 {
+				lines = nil
+			}
+		case fodderCommentCpp, fodderCommentHash:
+			if !ownLine {
+				// the comment trails the previous token.
+				continue
+			}
+			lines = append(lines, strings.TrimPrefix(strings.TrimRight(f.data, " \t\r"), " "))
+		case fodderCommentC:
+			lines = nil
+			if ownLine && strings.HasPrefix(f.data, "*") {
+				lines = blockCommentLines(f.data[1:])
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// blockCommentLines removes the leading `*` of the lines in a block
+// comment.
+func blockCommentLines(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimLeft(line, " \t")
+		line = strings.TrimPrefix(line, "*")
+		line = strings.TrimPrefix(line, " ")
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// docIndex maps the values of locals and object fields to the docs of
+// their declarations.
+type docIndex map[ast.Node]Doc
+
+func newDocIndex(node ast.Node, tokens Tokens) docIndex {
+	dc := newDocComments(tokens)
+	docs := make(docIndex)
+
+	add := func(n ast.Node, loc ast.Location) {
+		if doc := dc.doc(loc); !doc.IsEmpty() {
+			docs[n] = doc
+		}
+	}
+
+	var visit func(n ast.Node)
+	visit = func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.Local:
+			for _, bind := range n.Binds {
+				if bind.VarLoc.IsSet() {
+					add(bind.Body, bind.VarLoc.Begin)
+				}
+			}
+		case *ast.DesugaredObject:
+			for _, field := range n.Fields {
+				name, err := fieldName(field)
+				if err != nil {
+					continue
+				}
+
+				loc, ok := n.FieldLocs[name]
+				if !ok {
+					continue
+				}
+
+				add(field.Body, loc.Begin)
+				if len(objectLocalBinds(field.Body)) > 0 {
+					add(field.Body.(*ast.Local).Body, loc.Begin)
+				}
+			}
+		}
+
+		for _, child := range children(n) {
+			visit(child)
+		}
+	}
+
+	visit(node)
+
+	return docs
+}
+
+// sourceDocIndex creates a doc index for a node parsed from source.
+// Sources which can't be lexed don't have docs.
+func sourceDocIndex(filename, source string, node ast.Node) docIndex {
+	tokens, err := Lex(filename, source)
+	if err != nil {
+		return docIndex{}
+	}
+
+	return newDocIndex(node, tokens)
+}
@@ -0,0 +1,228 @@
+package token
+
+import (
+	"testing"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocComment(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		line     int
+		column   int
+		expected string
+		isErr    bool
+	}{
+		{
+			name:     "line comments",
+			source:   "{\n  // first\n  // second\n  a: 1,\n}",
+			line:     4,
+			column:   3,
+			expected: "first\nsecond",
+		},
+		{
+			name:     "hash comment",
+			source:   "{\n  # hash\n  a: 1,\n}",
+			line:     3,
+			column:   3,
+			expected: "hash",
+		},
+		{
+			name:     "blank line ends the block",
+			source:   "{\n  // detached\n\n  // attached\n  a: 1,\n}",
+			line:     5,
+			column:   3,
+			expected: "attached",
+		},
+		{
+			name:     "trailing comment of previous field",
+			source:   "{\n  a: 1, // about a\n  b: 2,\n}",
+			line:     3,
+			column:   3,
+			expected: "",
+		},
+		{
+			name:     "start of file",
+			source:   "// doc\nlocal a = 1; a",
+			line:     2,
+			column:   1,
+			expected: "doc",
+		},
+		{
+			name:     "local",
+			source:   "// one\nlocal a = 1,\n  // two\n  b = 2;\na + b",
+			line:     2,
+			column:   7,
+			expected: "one",
+		},
+		{
+			name:     "second bind of local",
+			source:   "// one\nlocal a = 1,\n  // two\n  b = 2;\na + b",
+			line:     4,
+			column:   3,
+			expected: "two",
+		},
+		{
+			name:     "block doc comment",
+			source:   "{\n  /**\n   * Summary.\n   *\n   * @param x the x\n   */\n  a(x): x,\n}",
+			line:     7,
+			column:   3,
+			expected: "Summary.\n\n@param x the x",
+		},
+		{
+			name:     "single line block doc comment",
+			source:   "/** The answer. */\nlocal a = 42; a",
+			line:     2,
+			column:   7,
+			expected: "The answer.",
+		},
+		{
+			name:     "block comment is not a doc comment",
+			source:   "{\n  /* not a doc */\n  a: 1,\n}",
+			line:     3,
+			column:   3,
+			expected: "",
+		},
+		{
+			name:     "no comment",
+			source:   "{a: 1}",
+			line:     1,
+			column:   2,
+			expected: "",
+		},
+		{
+			name:   "no token at location",
+			source: "{a: 1}",
+			line:   1,
+			column: 4,
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DocComment("file.jsonnet", tc.source, createLoc(tc.line, tc.column))
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestParseDoc(t *testing.T) {
+	cases := []struct {
+		name     string
+		comment  string
+		expected Doc
+	}{
+		{
+			name:     "empty",
+			expected: Doc{},
+		},
+		{
+			name:     "summary",
+			comment:  "Creates a\ndeployment.",
+			expected: Doc{Summary: "Creates a\ndeployment."},
+		},
+		{
+			name:    "description",
+			comment: "Creates a deployment.\n\nFirst.\n\nSecond.",
+			expected: Doc{
+				Summary:     "Creates a deployment.",
+				Description: "First.\n\nSecond.",
+			},
+		},
+		{
+			name:    "tags",
+			comment: "Creates a deployment.\n@param name the name\n  of the deployment\n@param replicas\n@returns a deployment\n@deprecated use new",
+			expected: Doc{
+				Summary: "Creates a deployment.",
+				Params: []DocParam{
+					{Name: "name", Description: "the name of the deployment"},
+					{Name: "replicas"},
+				},
+				Returns:           "a deployment",
+				Deprecated:        true,
+				DeprecationReason: "use new",
+			},
+		},
+		{
+			name:    "examples",
+			comment: "@example deployment('a')\n@example\nlocal d = deployment('b');\n\n  d\n@return a deployment",
+			expected: Doc{
+				Returns:  "a deployment",
+				Examples: []string{"deployment('a')", "local d = deployment('b');\n\n  d"},
+			},
+		},
+		{
+			name:    "text after a tag",
+			comment: "@deprecated\n\nSummary.",
+			expected: Doc{
+				Summary:    "Summary.",
+				Deprecated: true,
+			},
+		},
+		{
+			name:     "unknown tag",
+			comment:  "@see other",
+			expected: Doc{Summary: "@see other"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseDoc(tc.comment)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestDoc_render(t *testing.T) {
+	doc := Doc{
+		Summary:           "Creates a deployment.",
+		Description:       "It has one container.",
+		Params:            []DocParam{{Name: "name", Description: "the name"}, {Name: "replicas"}},
+		Returns:           "a deployment",
+		Deprecated:        true,
+		DeprecationReason: "use new",
+		Examples:          []string{"deployment('a')\n+ {}"},
+	}
+
+	expectedMarkdown := "**Deprecated**: use new\n\nCreates a deployment.\n\nIt has one container.\n\n" +
+		"**Parameters**\n\n- `name`: the name\n- `replicas`\n\n**Returns** a deployment\n\n" +
+		"**Example**\n\n```jsonnet\ndeployment('a')\n+ {}\n```"
+	assert.Equal(t, expectedMarkdown, doc.Markdown())
+
+	expectedText := "Deprecated: use new\n\nCreates a deployment.\n\nIt has one container.\n\n" +
+		"Parameters:\n  name: the name\n  replicas\n\nReturns: a deployment\n\n" +
+		"Example:\n  deployment('a')\n  + {}"
+	assert.Equal(t, expectedText, doc.Text())
+
+	assert.Equal(t, "Summary.", Doc{Summary: "Summary."}.Markdown())
+	assert.Equal(t, "the name", doc.Param("name"))
+	assert.Equal(t, "", doc.Param("missing"))
+}
+
+func TestLocationScope_documentation(t *testing.T) {
+	source := "/**\n * The labels.\n * @deprecated\n */\nlocal labels = {\n  // The app.\n  app: 'web',\n};\nlabels.app"
+
+	s, err := LocationScope("file.jsonnet", source, jpos.New(9, 1), NewNodeCache())
+	require.NoError(t, err)
+
+	e, err := s.Get("labels")
+	require.NoError(t, err)
+	assert.Equal(t, "Deprecated\n\nThe labels.", e.Documentation)
+	assert.True(t, e.Doc.Deprecated)
+
+	e, err = s.GetInPath([]string{"labels", "app"})
+	require.NoError(t, err)
+	assert.Equal(t, "The app.", e.Documentation)
+}
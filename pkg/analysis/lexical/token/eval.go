@@ -219,10 +219,14 @@ func (e *evaluator) eval(parent, n ast.Node, parentScope *evalScope) {
 	case *ast.Import:
 	case *ast.ImportStr:
 	case *ast.Index:
-		path := resolveIndex(n)
-		if err := parentScope.refersTo(ast.Identifier(path[0]), n, path[1:]...); err != nil {
-			e.err = err
-			return
+		// indexes of other expressions, e.g. imports, don't refer to
+		// variables.
+		if resolvableIndex(n) {
+			path := resolveIndex(n)
+			if err := parentScope.refersTo(ast.Identifier(path[0]), n, path[1:]...); err != nil {
+				e.err = err
+				return
+			}
 		}
 
 		e.eval(n, n.Target, parentScope)
@@ -157,6 +157,12 @@ func (i *identifier) index(idx *ast.Index) (Identity, error) {
 
 	_, ok := vSe.Node.(*ast.Index)
 
+	if path[0] == "std" && len(path) == 2 {
+		if item, ok := identifyStd(path[1]); ok {
+			return item, nil
+		}
+	}
+
 	if path[0] == "std" || ok {
 		stub, err := buildEvalStub(idx, i.scope)
 		if err != nil {
@@ -316,3 +322,42 @@ func (ei *emptyItem) String() string {
 func (ei *emptyItem) Signature() *Signature {
 	return nil
 }
+
+// stdItem is a standard library function.
+type stdItem struct {
+	function StdFunction
+}
+
+var _ Identity = (*stdItem)(nil)
+
+// identifyStd identifies a standard library function.
+func identifyStd(name string) (Identity, bool) {
+	stdlib, err := LoadStdlib()
+	if err != nil {
+		return nil, false
+	}
+
+	f, ok := stdlib.Function(name)
+	if !ok {
+		return nil, false
+	}
+
+	return &stdItem{function: f}, true
+}
+
+func (si *stdItem) String() string {
+	return si.function.Signature()
+}
+
+func (si *stdItem) Signature() *Signature {
+	var params []string
+	for _, p := range si.function.Parameters {
+		params = append(params, p.Name)
+	}
+
+	return &Signature{
+		label:         si.function.Signature(),
+		documentation: si.function.Documentation,
+		parameters:    params,
+	}
+}
@@ -1,13 +1,7 @@
 package token
 
 import (
-	"io/ioutil"
-	"os"
-	"path/filepath"
-	"strings"
-
 	jsonnet "github.com/google/go-jsonnet"
-	"github.com/pkg/errors"
 )
 
 // IdentifyConfig is configuration for Identify.
@@ -20,7 +14,9 @@ type IdentifyConfig struct {
 	tlaVar          map[string]string
 }
 
-// NewIdentifyConfig creates an instance of IdentifyConfig.
+// NewIdentifyConfig creates an instance of IdentifyConfig. jPath are the
+// lib paths in the order they are searched. If path is in a detected
+// project, the project's lib paths are searched first.
 func NewIdentifyConfig(path string, jPath ...string) (IdentifyConfig, error) {
 	ic := IdentifyConfig{
 		path:            path,
@@ -31,24 +27,21 @@ func NewIdentifyConfig(path string, jPath ...string) (IdentifyConfig, error) {
 		tlaVar:          make(map[string]string),
 	}
 
-	dir, file := filepath.Split(ic.path)
-
-	// check to see if this is a ksonnet app
-	root, err := findKsonnetRoot(dir)
-	if err == nil {
-		componentDir := filepath.Join(root, "components")
-		if strings.HasPrefix(dir, componentDir) && file != "params.libsonnet" {
-			// this file is a component
+	// projects such as ksonnet applications and Tanka environments
+	// have their own lib paths and variables.
+	info, ok, err := DetectProject(ic.path)
+	if err != nil {
+		return IdentifyConfig{}, err
+	}
 
-			// create __ksonnet/params ext code
-			paramsFile := filepath.Join(dir, "params.libsonnet")
-			data, err := ioutil.ReadFile(paramsFile)
-			if err != nil {
-				return IdentifyConfig{}, err
-			}
+	if ok {
+		ic.jsonnetLibPaths = mergeLibPaths(info.LibPaths, jPath)
 
-			// load parameters for current component
-			ic.ExtCode("__ksonnet/params", string(data))
+		for k, v := range info.ExtVar {
+			ic.ExtVar(k, v)
+		}
+		for k, v := range info.ExtCode {
+			ic.ExtCode(k, v)
 		}
 	}
 
@@ -84,8 +77,14 @@ func (ic *IdentifyConfig) TLAVar(k, v string) {
 func (ic *IdentifyConfig) VM() *jsonnet.VM {
 	vm := jsonnet.MakeVM()
 
+	// the file importer searches the last lib path first.
+	var jPaths []string
+	for i := len(ic.jsonnetLibPaths) - 1; i >= 0; i-- {
+		jPaths = append(jPaths, ic.jsonnetLibPaths[i])
+	}
+
 	importer := &jsonnet.FileImporter{
-		JPaths: ic.jsonnetLibPaths,
+		JPaths: jPaths,
 	}
 
 	vm.Importer(importer)
@@ -106,29 +105,21 @@ func (ic *IdentifyConfig) VM() *jsonnet.VM {
 	return vm
 }
 
-func findKsonnetRoot(cwd string) (string, error) {
-	prev := cwd
+// mergeLibPaths appends lib paths which haven't been seen.
+func mergeLibPaths(lists ...[]string) []string {
+	seen := make(map[string]bool)
 
-	for {
-		path := filepath.Join(cwd, "app.yaml")
-		_, err := os.Stat(path)
-		if err == nil {
-			return cwd, nil
-		}
-
-		if !os.IsNotExist(err) {
-			return "", err
-		}
-
-		cwd, err = filepath.Abs(filepath.Join(cwd, ".."))
-		if err != nil {
-			return "", err
-		}
+	var out []string
+	for _, list := range lists {
+		for _, path := range list {
+			if seen[path] {
+				continue
+			}
 
-		if cwd == prev {
-			return "", errors.Errorf("unable to find ksonnet project")
+			seen[path] = true
+			out = append(out, path)
 		}
-
-		prev = cwd
 	}
+
+	return out
 }
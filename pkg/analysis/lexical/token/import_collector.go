@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -90,3 +91,74 @@ func ImportPath(filename string, libPaths []string) (string, error) {
 
 	return "", errors.Errorf("import %q not found in lib path", filename)
 }
+
+// ResolveImport finds the absolute path to an import made from the file
+// `from`. Like the Jsonnet VM, the directory containing `from` is
+// searched before the lib paths.
+func ResolveImport(from, filename string, libPaths []string) (string, error) {
+	if filepath.IsAbs(filename) {
+		if _, err := os.Stat(filename); err != nil {
+			return "", errors.Errorf("import %q not found", filename)
+		}
+
+		return filename, nil
+	}
+
+	searchPaths := libPaths
+	if from != "" {
+		searchPaths = append([]string{filepath.Dir(from)}, libPaths...)
+	}
+
+	return ImportPath(filename, searchPaths)
+}
+
+// ImportNames returns the names path can be imported as through the
+// lib paths.
+func ImportNames(path string, libPaths []string) []string {
+	var names []string
+	for _, libPath := range libPaths {
+		rel, err := filepath.Rel(libPath, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		names = append(names, filepath.ToSlash(rel))
+	}
+
+	return names
+}
+
+// SourceImports returns the paths of the files imported by source.
+// Imports are resolved relative to filename and then through the lib
+// paths. Imports that can't be resolved are skipped.
+func SourceImports(filename, source string, libPaths []string) ([]string, error) {
+	tokens, err := Lex(filename, source)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make(map[string]bool)
+	for i := 0; i+1 < len(tokens); i++ {
+		switch tokens[i].Kind {
+		case TokenImport, TokenImportStr:
+		default:
+			continue
+		}
+
+		path, err := ResolveImport(filename, tokens[i+1].Data, libPaths)
+		if err != nil {
+			continue
+		}
+
+		matches[path] = true
+	}
+
+	paths := []string{}
+	for path := range matches {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
@@ -66,3 +66,57 @@ func TestImportCollector_Collect(t *testing.T) {
 	}
 
 }
+
+func TestImportNames(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		libPaths []string
+		expected []string
+	}{
+		{
+			name:     "in lib path",
+			path:     "/lib/a/b.libsonnet",
+			libPaths: []string{"/lib", "/other"},
+			expected: []string{"a/b.libsonnet"},
+		},
+		{
+			name:     "in multiple lib paths",
+			path:     "/lib/a/b.libsonnet",
+			libPaths: []string{"/lib", "/lib/a"},
+			expected: []string{"a/b.libsonnet", "b.libsonnet"},
+		},
+		{
+			name:     "outside of lib paths",
+			path:     "/src/a.jsonnet",
+			libPaths: []string{"/lib"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ImportNames(tc.path, tc.libPaths)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestSourceImports(t *testing.T) {
+	abs, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	source := `local a = import "importcollector1.jsonnet";
+local b = importstr "importcollector2.jsonnet";
+local c = import "missing.jsonnet";
+local d = import "importcollector1.jsonnet";
+{}`
+
+	got, err := SourceImports(filepath.Join(abs, "file.jsonnet"), source, nil)
+	require.NoError(t, err)
+
+	expected := []string{
+		filepath.Join(abs, "importcollector1.jsonnet"),
+		filepath.Join(abs, "importcollector2.jsonnet"),
+	}
+	assert.Equal(t, expected, got)
+}
@@ -0,0 +1,348 @@
+package token
+
+import (
+	"github.com/google/go-jsonnet/ast"
+)
+
+// stdReturnTypes are the types returned by standard library functions.
+// Functions which aren't listed return values of any type.
+var stdReturnTypes = map[string]*Type{
+	"abs":                 newType(TypeNumber),
+	"acos":                newType(TypeNumber),
+	"asciiLower":          newType(TypeString),
+	"asciiUpper":          newType(TypeString),
+	"asin":                newType(TypeNumber),
+	"atan":                newType(TypeNumber),
+	"base64":              newType(TypeString),
+	"base64Decode":        newType(TypeString),
+	"base64DecodeBytes":   arrayOf(newType(TypeNumber)),
+	"ceil":                newType(TypeNumber),
+	"char":                newType(TypeString),
+	"codepoint":           newType(TypeNumber),
+	"cos":                 newType(TypeNumber),
+	"count":               newType(TypeNumber),
+	"endsWith":            newType(TypeBoolean),
+	"equals":              newType(TypeBoolean),
+	"escapeStringBash":    newType(TypeString),
+	"escapeStringDollars": newType(TypeString),
+	"escapeStringJson":    newType(TypeString),
+	"escapeStringPython":  newType(TypeString),
+	"exp":                 newType(TypeNumber),
+	"exponent":            newType(TypeNumber),
+	"filter":              newType(TypeArray),
+	"filterMap":           newType(TypeArray),
+	"flatMap":             newType(TypeArray),
+	"floor":               newType(TypeNumber),
+	"format":              newType(TypeString),
+	"isArray":             newType(TypeBoolean),
+	"isBoolean":           newType(TypeBoolean),
+	"isFunction":          newType(TypeBoolean),
+	"isNumber":            newType(TypeBoolean),
+	"isObject":            newType(TypeBoolean),
+	"isString":            newType(TypeBoolean),
+	"length":              newType(TypeNumber),
+	"lines":               newType(TypeString),
+	"log":                 newType(TypeNumber),
+	"makeArray":           newType(TypeArray),
+	"manifestIni":         newType(TypeString),
+	"manifestJson":        newType(TypeString),
+	"manifestJsonEx":      newType(TypeString),
+	"manifestPython":      newType(TypeString),
+	"manifestPythonVars":  newType(TypeString),
+	"manifestXmlJsonml":   newType(TypeString),
+	"manifestYamlDoc":     newType(TypeString),
+	"manifestYamlStream":  newType(TypeString),
+	"mantissa":            newType(TypeNumber),
+	"map":                 newType(TypeArray),
+	"mapWithIndex":        newType(TypeArray),
+	"mapWithKey":          {Kind: TypeObject, Open: true},
+	"max":                 newType(TypeNumber),
+	"md5":                 newType(TypeString),
+	"min":                 newType(TypeNumber),
+	"modulo":              newType(TypeNumber),
+	"objectFields":        arrayOf(newType(TypeString)),
+	"objectFieldsAll":     arrayOf(newType(TypeString)),
+	"objectFieldsEx":      arrayOf(newType(TypeString)),
+	"objectHas":           newType(TypeBoolean),
+	"objectHasAll":        newType(TypeBoolean),
+	"objectHasEx":         newType(TypeBoolean),
+	"parseHex":            newType(TypeNumber),
+	"parseInt":            newType(TypeNumber),
+	"parseOctal":          newType(TypeNumber),
+	"pow":                 newType(TypeNumber),
+	"primitiveEquals":     newType(TypeBoolean),
+	"range":               arrayOf(newType(TypeNumber)),
+	"set":                 newType(TypeArray),
+	"setDiff":             newType(TypeArray),
+	"setInter":            newType(TypeArray),
+	"setMember":           newType(TypeBoolean),
+	"setUnion":            newType(TypeArray),
+	"sin":                 newType(TypeNumber),
+	"sort":                newType(TypeArray),
+	"split":               arrayOf(newType(TypeString)),
+	"splitLimit":          arrayOf(newType(TypeString)),
+	"sqrt":                newType(TypeNumber),
+	"startsWith":          newType(TypeBoolean),
+	"stringChars":         arrayOf(newType(TypeString)),
+	"strReplace":          newType(TypeString),
+	"substr":              newType(TypeString),
+	"tan":                 newType(TypeNumber),
+	"toString":            newType(TypeString),
+	"type":                newType(TypeString),
+	"uniq":                newType(TypeArray),
+}
+
+// typeInferrer infers the types of expressions in desugared nodes without
+// evaluating them. Identifiers, `self`, `super` and imports are followed
+// with a resolver.
+type typeInferrer struct {
+	r          *resolver
+	types      map[ast.Node]*Type
+	inProgress map[ast.Node]bool
+	std        *Type
+}
+
+func newTypeInferrer(r *resolver) *typeInferrer {
+	return &typeInferrer{
+		r:          r,
+		types:      make(map[ast.Node]*Type),
+		inProgress: make(map[ast.Node]bool),
+	}
+}
+
+// typeInferrer returns the type inferrer for the nodes visited by the
+// resolver.
+func (r *resolver) typeInferrer() *typeInferrer {
+	if r.inferrer == nil {
+		r.inferrer = newTypeInferrer(r)
+	}
+
+	return r.inferrer
+}
+
+// infer infers the type of n. Nodes whose type depends on themselves
+// have an unknown type.
+func (ti *typeInferrer) infer(n ast.Node) *Type {
+	if n == nil {
+		return newType(TypeAny)
+	}
+
+	if t, ok := ti.types[n]; ok {
+		return t
+	}
+
+	if ti.inProgress[n] {
+		return newType(TypeAny)
+	}
+
+	ti.inProgress[n] = true
+	t := ti.inferNode(n)
+	delete(ti.inProgress, n)
+
+	ti.types[n] = t
+	return t
+}
+
+// nolint: gocyclo
+func (ti *typeInferrer) inferNode(n ast.Node) *Type {
+	switch n := n.(type) {
+	case *ast.LiteralNull:
+		return newType(TypeNull)
+	case *ast.LiteralBoolean:
+		return newType(TypeBoolean)
+	case *ast.LiteralNumber:
+		return newType(TypeNumber)
+	case *ast.LiteralString, *ast.ImportStr:
+		return newType(TypeString)
+	case *ast.InSuper:
+		return newType(TypeBoolean)
+	case *ast.Array:
+		var elem *Type
+		for _, e := range n.Elements {
+			elem = joinElems(elem, ti.infer(e))
+		}
+		return arrayOf(elem)
+	case *ast.DesugaredObject:
+		return ti.objectType(n)
+	case *ast.Function:
+		t := &Type{Kind: TypeFunction}
+		for _, param := range n.Parameters.Required {
+			t.Params = append(t.Params, TypeParam{Name: string(param)})
+		}
+		for _, param := range n.Parameters.Optional {
+			t.Params = append(t.Params, TypeParam{Name: string(param.Name), Optional: true})
+		}
+		t.Returns = ti.infer(n.Body)
+		return t
+	case *ast.Local:
+		return ti.infer(n.Body)
+	case *ast.Var:
+		b, err := ti.r.lookup(n)
+		if err != nil {
+			if n.Id == "std" {
+				return ti.stdType()
+			}
+			return newType(TypeAny)
+		}
+		if b.param || b.body == nil {
+			return newType(TypeAny)
+		}
+		return ti.infer(b.body)
+	case *ast.Self:
+		env := ti.r.envs[n]
+		if env == nil || env.self == nil {
+			return newType(TypeAny)
+		}
+		// objects composed with this object can add fields to self and
+		// to the objects in its fields.
+		return ti.infer(env.self).openDeep()
+	case *ast.SuperIndex:
+		name, ok := literalIndex(n.Index)
+		if !ok {
+			return newType(TypeAny)
+		}
+		f, err := ti.r.superField(n, name)
+		if err != nil {
+			return newType(TypeAny)
+		}
+		return ti.infer(f.field.Body).openDeep()
+	case *ast.Index:
+		return ti.indexType(ti.infer(n.Target), n.Index)
+	case *ast.Import:
+		node, _, err := ti.r.importFile(n.Loc().FileName, n.File.Value)
+		if err != nil {
+			return newType(TypeAny)
+		}
+		return ti.infer(node)
+	case *ast.Apply:
+		target := ti.infer(n.Target)
+		if target.Kind != TypeFunction || target.Returns == nil {
+			return newType(TypeAny)
+		}
+		return target.Returns
+	case *ast.Binary:
+		return ti.binaryType(n)
+	case *ast.Unary:
+		if n.Op == ast.UopNot {
+			return newType(TypeBoolean)
+		}
+		return newType(TypeNumber)
+	case *ast.Conditional:
+		// a branch which raises an error doesn't produce a value.
+		if _, ok := n.BranchFalse.(*ast.Error); ok {
+			return ti.infer(n.BranchTrue)
+		}
+		if _, ok := n.BranchTrue.(*ast.Error); ok {
+			return ti.infer(n.BranchFalse)
+		}
+		return joinTypes(ti.infer(n.BranchTrue), ti.infer(n.BranchFalse))
+	}
+
+	return newType(TypeAny)
+}
+
+// objectType creates the type of an object. Objects with computed field
+// names are open.
+func (ti *typeInferrer) objectType(o *ast.DesugaredObject) *Type {
+	t := &Type{Kind: TypeObject}
+	for _, field := range o.Fields {
+		name, err := fieldName(field)
+		if err != nil {
+			t.Open = true
+			continue
+		}
+
+		body := field.Body
+		t.fields = append(t.fields, &FieldType{
+			Name:      name,
+			Hidden:    field.Hide == ast.ObjectFieldHidden,
+			Node:      body,
+			hide:      field.Hide,
+			plusSuper: field.PlusSuper,
+			resolve:   func() *Type { return ti.infer(body) },
+		})
+	}
+
+	return t
+}
+
+// indexType returns the type of indexing a value of type target.
+func (ti *typeInferrer) indexType(target *Type, index ast.Node) *Type {
+	switch target.Kind {
+	case TypeObject:
+		name, ok := literalIndex(index)
+		if !ok {
+			return newType(TypeAny)
+		}
+		if f, ok := target.Field(name); ok {
+			return f.Type()
+		}
+	case TypeArray:
+		if target.Elem != nil {
+			return target.Elem
+		}
+	case TypeString:
+		return newType(TypeString)
+	}
+
+	return newType(TypeAny)
+}
+
+// nolint: gocyclo
+func (ti *typeInferrer) binaryType(n *ast.Binary) *Type {
+	switch n.Op {
+	case ast.BopPlus:
+		return plusTypes(ti.infer(n.Left), ti.infer(n.Right))
+	case ast.BopMult, ast.BopDiv, ast.BopMinus, ast.BopShiftL, ast.BopShiftR,
+		ast.BopBitwiseAnd, ast.BopBitwiseXor, ast.BopBitwiseOr:
+		return newType(TypeNumber)
+	case ast.BopGreater, ast.BopGreaterEq, ast.BopLess, ast.BopLessEq,
+		ast.BopManifestEqual, ast.BopManifestUnequal, ast.BopIn,
+		ast.BopAnd, ast.BopOr:
+		return newType(TypeBoolean)
+	}
+
+	return newType(TypeAny)
+}
+
+// stdType is the type of the standard library. It is open because some
+// fields of std aren't in the catalogue.
+func (ti *typeInferrer) stdType() *Type {
+	if ti.std != nil {
+		return ti.std
+	}
+
+	ti.std = &Type{Kind: TypeObject, Open: true}
+
+	stdlib, err := LoadStdlib()
+	if err != nil {
+		return ti.std
+	}
+
+	for _, f := range stdlib.Functions() {
+		ti.std.fields = append(ti.std.fields, &FieldType{
+			Name:   f.Name,
+			Hidden: true,
+			typ:    stdFunctionType(f),
+		})
+	}
+
+	return ti.std
+}
+
+func stdFunctionType(f StdFunction) *Type {
+	t := &Type{
+		Kind:    TypeFunction,
+		Returns: newType(TypeAny),
+	}
+
+	if returns, ok := stdReturnTypes[f.Name]; ok {
+		t.Returns = returns
+	}
+
+	for _, p := range f.Parameters {
+		t.Params = append(t.Params, TypeParam{Name: p.Name, Optional: p.Default != ""})
+	}
+
+	return t
+}
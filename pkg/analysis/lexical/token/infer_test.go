@@ -0,0 +1,111 @@
+package token
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeInferrer(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "definition"))
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "file.jsonnet")
+	libPaths := []string{filepath.Join(dir, "lib")}
+
+	cases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{name: "null", source: "null", expected: "null"},
+		{name: "boolean", source: "true", expected: "boolean"},
+		{name: "number", source: "1", expected: "number"},
+		{name: "string", source: "'a'", expected: "string"},
+		{name: "importstr", source: "importstr 'foo.libsonnet'", expected: "string"},
+		{name: "array", source: "[1, 2]", expected: "array of number"},
+		{name: "empty array", source: "[]", expected: "array"},
+		{name: "mixed array", source: "[1, 'a']", expected: "array"},
+		{name: "nested array", source: "[[1], []]", expected: "array of array of number"},
+		{name: "object", source: "{a: 1}", expected: "object"},
+		{name: "function", source: "function(x) x", expected: "function"},
+		{name: "local", source: "local a = 'x'; a", expected: "string"},
+		{name: "parameter", source: "local f(x) = x; f(1)", expected: "any"},
+		{name: "function result", source: "local f(x) = [x]; f(1)", expected: "array"},
+		{name: "field", source: "local o = {a: {b: 'x'}}; o.a.b", expected: "string"},
+		{name: "missing field", source: "local o = {a: 1}; o.b", expected: "any"},
+		{name: "self field", source: "{a: 1, b: self.a}.b", expected: "number"},
+		{name: "super field", source: "({a: 1} + {b: super.a}).b", expected: "number"},
+		{name: "mixin field", source: "({a: 1} + {b: 'x'}).b", expected: "string"},
+		{name: "plus super field", source: "({a: [1]} + {a+: [2]}).a", expected: "array of number"},
+		{name: "array element", source: "local xs = ['a']; xs[0]", expected: "string"},
+		{name: "string index", source: "'abc'[0]", expected: "string"},
+		{name: "string concatenation", source: "1 + 'a'", expected: "string"},
+		{name: "arithmetic", source: "local a = 1; a * 2 - 1", expected: "number"},
+		{name: "comparison", source: "1 < 2", expected: "boolean"},
+		{name: "equality", source: "1 == 2", expected: "boolean"},
+		{name: "not", source: "!true", expected: "boolean"},
+		{name: "negation", source: "-1", expected: "number"},
+		{name: "conditional", source: "if true then 1 else 2", expected: "number"},
+		{name: "conditional with different types", source: "if true then 1 else 'a'", expected: "any"},
+		{name: "conditional with error", source: "if true then 'a' else error 'b'", expected: "string"},
+		{name: "std function", source: "std.length", expected: "function"},
+		{name: "std call", source: "std.split('a,b', ',')", expected: "array of string"},
+		{name: "unknown std call", source: "std.extVar('a')", expected: "any"},
+		{name: "array comprehension", source: "[x for x in [1]]", expected: "array"},
+		{name: "import", source: "(import 'foo.libsonnet').service.port", expected: "number"},
+		{name: "recursive local", source: "local a = a; a", expected: "any"},
+		{name: "recursive field", source: "{a: self.a}.a", expected: "any"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newResolver(libPaths)
+			node, err := r.addSource(file, tc.source)
+			require.NoError(t, err)
+
+			got := r.typeInferrer().infer(node)
+			assert.Equal(t, tc.expected, got.String())
+		})
+	}
+}
+
+func TestTypeInferrer_shapes(t *testing.T) {
+	source := "local f(x, y=1) = {a: x, b:: 'y'}; {f: f, o: f(1) + {c: [1]}, s: std.substr}"
+
+	r := newResolver(nil)
+	node, err := r.addSource("file.jsonnet", source)
+	require.NoError(t, err)
+
+	typ := r.typeInferrer().infer(node)
+	require.Equal(t, TypeObject, typ.Kind)
+	assert.False(t, typ.Open)
+
+	f, ok := typ.Field("f")
+	require.True(t, ok)
+	fn := f.Type()
+	assert.Equal(t, TypeFunction, fn.Kind)
+	assert.Equal(t, []TypeParam{{Name: "x"}, {Name: "y", Optional: true}}, fn.Params)
+	assert.Equal(t, TypeObject, fn.Returns.Kind)
+
+	o, ok := typ.Field("o")
+	require.True(t, ok)
+
+	var names []string
+	for _, field := range o.Type().Fields() {
+		names = append(names, field.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	b, ok := o.Type().Field("b")
+	require.True(t, ok)
+	assert.True(t, b.Hidden)
+	assert.Equal(t, "string", b.Type().String())
+
+	s, ok := typ.Field("s")
+	require.True(t, ok)
+	assert.Equal(t, []TypeParam{{Name: "str"}, {Name: "from"}, {Name: "len"}}, s.Type().Params)
+	assert.Equal(t, "string", s.Type().Returns.String())
+}
@@ -0,0 +1,315 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/google/go-jsonnet/ast"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// maxInlayValueLength is the maximum length of a value hint. Longer
+// values are truncated.
+const maxInlayValueLength = 40
+
+// InlayHintKind is the kind of an inlay hint.
+type InlayHintKind int
+
+const (
+	// ParameterHint is the name of the parameter an argument is passed to.
+	ParameterHint InlayHintKind = iota + 1
+	// ValueHint is the constant value of an expression.
+	ValueHint
+)
+
+// InlayHintOptions selects the kinds of inlay hints.
+type InlayHintOptions struct {
+	// ParameterNames shows parameter names before positional arguments.
+	ParameterNames bool
+	// Values shows the constant values of locals, fields and external
+	// variables.
+	Values bool
+}
+
+// DefaultInlayHintOptions returns the default inlay hint options. Only
+// parameter names are shown.
+func DefaultInlayHintOptions() InlayHintOptions {
+	return InlayHintOptions{
+		ParameterNames: true,
+	}
+}
+
+// InlayHint is a label shown inline in a source.
+type InlayHint struct {
+	// Position is where the label is shown.
+	Position jpos.Position
+	// Label is the text of the hint, e.g. `name:` or `= "dev"`.
+	Label string
+	// Kind is the kind of hint.
+	Kind InlayHintKind
+}
+
+// InlayHints creates the inlay hints in a range of a source. Parameter
+// hints name the parameters of positional arguments in calls to
+// functions whose parameters can be inferred statically. Value hints show
+// the values of locals and fields which resolve to constants, and of
+// `std.extVar` calls whose variables are set in ic. Hints are sorted by
+// position.
+func InlayHints(ctx context.Context, filename, source string, r jpos.Range, ic IdentifyConfig, nodeCache *NodeCache, options InlayHintOptions) ([]InlayHint, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "inlayHints")
+	defer span.Finish()
+
+	if !options.ParameterNames && !options.Values {
+		return nil, nil
+	}
+
+	res := newResolver(ic.jsonnetLibPaths)
+	res.nodeCache = nodeCache
+
+	node, err := res.addSource(filename, source)
+	if err != nil {
+		return nil, err
+	}
+
+	hv := &inlayHintVisitor{
+		filename: filename,
+		r:        r,
+		ic:       ic,
+		options:  options,
+		res:      res,
+		ti:       res.typeInferrer(),
+		visited:  make(map[ast.Node]bool),
+		seen:     make(map[InlayHint]bool),
+		span:     span,
+	}
+	hv.visit(node)
+
+	sort.SliceStable(hv.hints, func(i, j int) bool {
+		return isBefore(hv.hints[i].Position.ToJsonnet(), hv.hints[j].Position.ToJsonnet())
+	})
+
+	return hv.hints, nil
+}
+
+type inlayHintVisitor struct {
+	filename string
+	r        jpos.Range
+	ic       IdentifyConfig
+	options  InlayHintOptions
+	res      *resolver
+	ti       *typeInferrer
+	visited  map[ast.Node]bool
+	seen     map[InlayHint]bool
+	hints    []InlayHint
+	span     opentracing.Span
+}
+
+// add adds a hint at loc if loc is in the source and in the requested
+// range.
+func (hv *inlayHintVisitor) add(loc ast.Location, label string, kind InlayHintKind) {
+	pos := jpos.FromJsonnetLocation(loc)
+	if isBefore(loc, hv.r.Start.ToJsonnet()) || isBefore(hv.r.End.ToJsonnet(), loc) {
+		return
+	}
+
+	hint := InlayHint{
+		Position: pos,
+		Label:    label,
+		Kind:     kind,
+	}
+
+	// desugaring copies object locals into every field of their object.
+	if hv.seen[hint] {
+		return
+	}
+	hv.seen[hint] = true
+
+	hv.hints = append(hv.hints, hint)
+}
+
+// inSource returns true if n was parsed from the source.
+func (hv *inlayHintVisitor) inSource(n ast.Node) bool {
+	if n == nil {
+		return false
+	}
+
+	loc := n.Loc()
+	return loc != nil && loc.IsSet() && loc.FileName == hv.filename
+}
+
+func (hv *inlayHintVisitor) visit(n ast.Node) {
+	if n == nil || hv.visited[n] {
+		return
+	}
+	hv.visited[n] = true
+
+	switch n := n.(type) {
+	case *ast.Apply:
+		if hv.options.ParameterNames {
+			hv.parameterHints(n)
+		}
+		if hv.options.Values {
+			hv.extVarHint(n)
+		}
+	case *ast.Local:
+		if hv.options.Values {
+			for _, bind := range n.Binds {
+				hv.valueHint(bind.Body)
+			}
+		}
+	case *ast.DesugaredObject:
+		if hv.options.Values {
+			for _, field := range n.Fields {
+				body := field.Body
+				if len(objectLocalBinds(body)) > 0 {
+					body = body.(*ast.Local).Body
+				}
+				hv.valueHint(body)
+			}
+		}
+	}
+
+	for _, child := range children(n) {
+		hv.visit(child)
+	}
+}
+
+// parameterHints names the parameters of the positional arguments of a
+// call. Arguments which are variables named after their parameter, and
+// parameters with single letter names, e.g. `x` in `std.length(x)`, are
+// skipped.
+func (hv *inlayHintVisitor) parameterHints(n *ast.Apply) {
+	if !hv.inSource(n) {
+		// calls created while desugaring, e.g. for `%`, have no arguments
+		// in the source.
+		return
+	}
+
+	t := hv.ti.infer(n.Target)
+	if t.Kind != TypeFunction {
+		return
+	}
+
+	for i, arg := range n.Arguments.Positional {
+		if i >= len(t.Params) || !hv.inSource(arg) {
+			return
+		}
+
+		name := t.Params[i].Name
+		if len(name) < 2 {
+			continue
+		}
+		if v, ok := arg.(*ast.Var); ok && string(v.Id) == name {
+			continue
+		}
+
+		hv.add(arg.Loc().Begin, name+":", ParameterHint)
+	}
+}
+
+// extVarHint shows the value of a `std.extVar` call if the variable is
+// set. The values of external code variables are only shown if they are
+// constants.
+func (hv *inlayHintVisitor) extVarHint(n *ast.Apply) {
+	if !hv.inSource(n) || !isStdCall(n, "extVar") || len(n.Arguments.Positional) != 1 {
+		return
+	}
+
+	str, ok := n.Arguments.Positional[0].(*ast.LiteralString)
+	if !ok {
+		return
+	}
+
+	var value interface{}
+	if v, ok := hv.ic.extVar[str.Value]; ok {
+		value = v
+	} else if code, ok := hv.ic.extCode[str.Value]; ok {
+		// external code is only shown if it is a constant.
+		node, err := ReadSource("<extcode:"+str.Value+">", code, nil)
+		if err != nil {
+			hv.span.LogFields(log.Error(err))
+			return
+		}
+
+		if value, ok = constantValue(node); !ok {
+			return
+		}
+	} else {
+		return
+	}
+
+	if label, ok := inlayValueLabel(value); ok {
+		hv.add(n.Loc().End, label, ValueHint)
+	}
+}
+
+// valueHint shows the value of an expression if it resolves to a
+// constant. Expressions which are constants themselves are skipped.
+func (hv *inlayHintVisitor) valueHint(n ast.Node) {
+	if !hv.inSource(n) {
+		return
+	}
+
+	if _, ok := constantValue(n); ok {
+		return
+	}
+
+	switch n.(type) {
+	case *ast.Function, *ast.Import, *ast.ImportStr:
+		return
+	}
+
+	value, err := hv.res.value(n)
+	if err != nil {
+		hv.span.LogFields(log.Error(err))
+		return
+	}
+
+	v, ok := constantValue(value)
+	if !ok {
+		return
+	}
+
+	if label, ok := inlayValueLabel(v); ok {
+		hv.add(n.Loc().End, label, ValueHint)
+	}
+}
+
+// isStdCall returns true if n calls the standard library function name.
+func isStdCall(n *ast.Apply, name string) bool {
+	idx, ok := n.Target.(*ast.Index)
+	if !ok {
+		return false
+	}
+
+	v, ok := idx.Target.(*ast.Var)
+	if !ok || v.Id != "std" {
+		return false
+	}
+
+	index, ok := literalIndex(idx.Index)
+	return ok && index == name
+}
+
+// inlayValueLabel renders a value as compact JSON. Long values are
+// truncated.
+func inlayValueLabel(v interface{}) (string, bool) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", false
+	}
+
+	s := strings.TrimSuffix(buf.String(), "\n")
+	if runes := []rune(s); len(runes) > maxInlayValueLength {
+		s = string(runes[:maxInlayValueLength-1]) + "…"
+	}
+
+	return "= " + s, true
+}
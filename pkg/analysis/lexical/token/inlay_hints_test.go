@@ -0,0 +1,153 @@
+package token
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlayHints(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "definition"))
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "file.jsonnet")
+	libPaths := []string{filepath.Join(dir, "lib")}
+
+	all := InlayHintOptions{ParameterNames: true, Values: true}
+
+	type hint struct {
+		pos   [2]int
+		label string
+		kind  InlayHintKind
+	}
+
+	cases := []struct {
+		name     string
+		source   string
+		r        jpos.Range
+		options  InlayHintOptions
+		expected []hint
+	}{
+		{
+			name:    "parameter names",
+			source:  "local container(name, image, port=80) = name; container('app', 'img:1', 8080)",
+			options: DefaultInlayHintOptions(),
+			expected: []hint{
+				{[2]int{1, 57}, "name:", ParameterHint},
+				{[2]int{1, 64}, "image:", ParameterHint},
+				{[2]int{1, 73}, "port:", ParameterHint},
+			},
+		},
+		{
+			name:    "named arguments and matching variables",
+			source:  "local f(name, port) = name; local name = 'a'; f(name, port=1)",
+			options: DefaultInlayHintOptions(),
+		},
+		{
+			name:     "method",
+			source:   "local o = {f(port):: port}; o.f(1)",
+			options:  DefaultInlayHintOptions(),
+			expected: []hint{{[2]int{1, 33}, "port:", ParameterHint}},
+		},
+		{
+			name:    "std function",
+			source:  "std.substr('abc', 0, 1)",
+			options: DefaultInlayHintOptions(),
+			expected: []hint{
+				{[2]int{1, 12}, "str:", ParameterHint},
+				{[2]int{1, 19}, "from:", ParameterHint},
+				{[2]int{1, 22}, "len:", ParameterHint},
+			},
+		},
+		{
+			name:    "single letter parameters",
+			source:  "local f(x, y) = x; [f(1, 2), std.length([])]",
+			options: DefaultInlayHintOptions(),
+		},
+		{
+			name:    "unknown function",
+			source:  "function(f) f(1)",
+			options: all,
+		},
+		{
+			name:    "operators",
+			source:  "local a = 'x'; [a % 1, 'a' in {}]",
+			options: DefaultInlayHintOptions(),
+		},
+		{
+			name:    "values",
+			source:  "local o = {a: 'x', b: self.a}; local a = o.a; local n = 1; a",
+			options: InlayHintOptions{Values: true},
+			expected: []hint{
+				{[2]int{1, 29}, `= "x"`, ValueHint},
+				{[2]int{1, 45}, `= "x"`, ValueHint},
+			},
+		},
+		{
+			name:     "imported value",
+			source:   "local lib = import 'foo.libsonnet'; local port = lib.service.port; port",
+			options:  InlayHintOptions{Values: true},
+			expected: []hint{{[2]int{1, 66}, "= 80", ValueHint}},
+		},
+		{
+			name:     "long value",
+			source:   "local o = {a: 'abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyz'}; local a = o.a; a",
+			options:  InlayHintOptions{Values: true},
+			expected: []hint{{[2]int{1, 85}, `= "abcdefghijklmnopqrstuvwxyzabcdefghijkl…`, ValueHint}},
+		},
+		{
+			name:    "ext vars",
+			source:  "[std.extVar('env'), std.extVar('replicas'), std.extVar('computed'), std.extVar('unset')]",
+			options: InlayHintOptions{Values: true},
+			expected: []hint{
+				{[2]int{1, 19}, `= "dev"`, ValueHint},
+				{[2]int{1, 43}, "= 3", ValueHint},
+			},
+		},
+		{
+			name:    "disabled",
+			source:  "local f(name) = name; local a = f(1); std.extVar('env')",
+			options: InlayHintOptions{},
+		},
+		{
+			name:     "range",
+			source:   "local f(name) = name;\n[f(1),\nf(2)]",
+			r:        jpos.NewRangeFromCoords(3, 1, 3, 5),
+			options:  DefaultInlayHintOptions(),
+			expected: []hint{{[2]int{3, 3}, "name:", ParameterHint}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ic, err := NewIdentifyConfig(file, libPaths...)
+			require.NoError(t, err)
+			ic.ExtVar("env", "dev")
+			ic.ExtCode("replicas", "3")
+			ic.ExtCode("computed", "1 + 2")
+
+			r := tc.r
+			if r == (jpos.Range{}) {
+				r = jpos.NewRangeFromCoords(1, 1, 100, 1)
+			}
+
+			got, err := InlayHints(context.Background(), file, tc.source, r, ic, NewNodeCache(), tc.options)
+			require.NoError(t, err)
+
+			var hints []hint
+			for _, h := range got {
+				hints = append(hints, hint{
+					pos:   [2]int{h.Position.Line(), h.Position.Column()},
+					label: h.Label,
+					kind:  h.Kind,
+				})
+			}
+
+			assert.Equal(t, tc.expected, hints)
+		})
+	}
+}
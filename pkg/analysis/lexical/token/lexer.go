@@ -46,6 +46,32 @@ type FodderElement struct {
 	data string
 }
 
+// IsWhitespace returns true if the element is whitespace.
+func (f FodderElement) IsWhitespace() bool {
+	return f.kind == fodderWhitespace
+}
+
+// Comment returns the comment with its delimiters. It returns an empty
+// string if the element is whitespace.
+func (f FodderElement) Comment() string {
+	switch f.kind {
+	case fodderCommentC:
+		return "/*" + f.data + "*/"
+	case fodderCommentCpp:
+		return "//" + f.data
+	case fodderCommentHash:
+		return "#" + f.data
+	default:
+		return ""
+	}
+}
+
+// Data returns the content of the element. Comment delimiters are not
+// included.
+func (f FodderElement) Data() string {
+	return f.data
+}
+
 type Fodder []FodderElement
 
 // ---------------------------------------------------------------------------
@@ -166,6 +192,11 @@ type Token struct {
 	Loc ast.LocationRange
 }
 
+// Fodder returns the fodder that occurs before the Token.
+func (t *Token) Fodder() Fodder {
+	return t.fodder
+}
+
 // Tokens is a slice of Token structs.
 type Tokens []Token
 
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -56,6 +57,10 @@ type NodeCache struct {
 	store       map[string]NodeEntry
 	nodeBuilder NodeBuilder
 
+	// dependents maps a dependency to the keys of the entries that
+	// depend on it.
+	dependents map[string]map[string]bool
+
 	mu sync.Mutex
 }
 
@@ -64,6 +69,7 @@ func NewNodeCache() *NodeCache {
 	c := &NodeCache{
 		store:       make(map[string]NodeEntry),
 		nodeBuilder: &nodeBuilder{},
+		dependents:  make(map[string]map[string]bool),
 	}
 
 	return c
@@ -159,12 +165,163 @@ func (c *NodeCache) set(ctx context.Context, key string, e *NodeEntry) error {
 	}
 
 	e.Node = node
-	c.store[key] = *e
+	c.put(key, e)
+
 	return nil
 }
 
+// put stores an entry which has been built.
+func (c *NodeCache) put(key string, e *NodeEntry) {
+	c.remove(key)
+	c.store[key] = *e
+
+	for _, dep := range e.Dependencies {
+		if _, ok := c.dependents[dep.Name]; !ok {
+			c.dependents[dep.Name] = make(map[string]bool)
+		}
+		c.dependents[dep.Name][key] = true
+	}
+}
+
 // Remove removes an item from the node cache.
 func (c *NodeCache) Remove(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+	return nil
+}
+
+func (c *NodeCache) remove(key string) {
+	e, ok := c.store[key]
+	if !ok {
+		return
+	}
+
+	for _, dep := range e.Dependencies {
+		delete(c.dependents[dep.Name], key)
+		if len(c.dependents[dep.Name]) == 0 {
+			delete(c.dependents, dep.Name)
+		}
+	}
+
+	delete(c.store, key)
+}
+
+// Dependents returns the keys of the entries that depend on name
+// directly or through other entries.
+func (c *NodeCache) Dependents(name string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.dependentsOf(name)
+}
+
+func (c *NodeCache) dependentsOf(name string) []string {
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+
+	var keys []string
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for key := range c.dependents[cur] {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keys = append(keys, key)
+			queue = append(queue, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Invalidate evicts the entry for name and the entries that depend on
+// it. Evicted entries are rebuilt from disk in the background, so
+// evaluating them doesn't block the cache. Entries that can't be rebuilt,
+// e.g. because a file was deleted, stay evicted. It returns the keys of
+// the evicted entries.
+func (c *NodeCache) Invalidate(ctx context.Context, name string) ([]string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "invalidateNodeCache")
+	defer span.Finish()
+
+	c.mu.Lock()
+
+	keys := c.dependentsOf(name)
+	if _, ok := c.store[name]; ok {
+		keys = append([]string{name}, keys...)
+	}
+
+	span.LogFields(
+		log.String("name", name),
+		log.String("keys", strings.Join(keys, ",")),
+	)
+
+	entries := make(map[string]NodeEntry)
+	for _, key := range keys {
+		entries[key] = c.store[key]
+		c.remove(key)
+	}
+
+	c.mu.Unlock()
+
+	go func() {
+		span, ctx := opentracing.StartSpanFromContext(ctx, "rebuildNodeCache")
+		defer span.Finish()
+
+		for _, key := range keys {
+			e := entries[key]
+			if err := c.rebuild(ctx, key, e.libPaths); err != nil {
+				span.LogFields(
+					log.String("key", key),
+					log.Error(err),
+				)
+			}
+		}
+	}()
+
+	return keys, nil
+}
+
+// rebuild builds the entry for key from disk without holding the lock.
+// Entries set while the entry was being built aren't replaced.
+func (c *NodeCache) rebuild(ctx context.Context, key string, libPaths []string) error {
+	path, err := ImportPath(key, libPaths)
+	if err != nil {
+		return err
+	}
+
+	importedFiles, err := NewImportCollector(libPaths).Collect(path, false)
+	if err != nil {
+		return err
+	}
+
+	ncds, err := collectNodeDependencies(path, importedFiles, libPaths)
+	if err != nil {
+		return err
+	}
+
+	e := NewNodeEntry(ncds, libPaths, key)
+
+	node, err := c.nodeBuilder.Build(libPaths, key)
+	if err != nil {
+		return err
+	}
+	e.Node = node
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.store[key]; ok {
+		return nil
+	}
+
+	c.put(key, e)
+
 	return nil
 }
 
@@ -0,0 +1,146 @@
+package token
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-jsonnet/ast"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNodeBuilder struct {
+	built []string
+
+	mu sync.Mutex
+}
+
+func (nb *fakeNodeBuilder) Build(libPaths []string, name string) (ast.Node, error) {
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	nb.built = append(nb.built, name)
+	return &ast.Object{}, nil
+}
+
+func (nb *fakeNodeBuilder) builtNames() []string {
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	return append([]string(nil), nb.built...)
+}
+
+func (nb *fakeNodeBuilder) reset() {
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	nb.built = nil
+}
+
+// waitForKeys waits until c contains n entries, i.e. invalidated
+// entries have been rebuilt in the background.
+func waitForKeys(t *testing.T, c *NodeCache, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.Keys()) != n {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "node cache entries weren't rebuilt", "got %v", c.Keys())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNodeCache_Invalidate(t *testing.T) {
+	abs, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	libPaths := []string{abs}
+
+	cases := []struct {
+		name       string
+		invalidate string
+		expected   []string
+	}{
+		{
+			name:       "leaf dependency",
+			invalidate: "importcollector1.jsonnet",
+			expected: []string{
+				"importcollector1.jsonnet",
+				"importcollector2.jsonnet",
+				"importcollector3.jsonnet",
+			},
+		},
+		{
+			name:       "intermediate dependency",
+			invalidate: "importcollector2.jsonnet",
+			expected: []string{
+				"importcollector2.jsonnet",
+				"importcollector3.jsonnet",
+			},
+		},
+		{
+			name:       "no dependents",
+			invalidate: "importcollector3.jsonnet",
+			expected: []string{
+				"importcollector3.jsonnet",
+			},
+		},
+		{
+			name:       "not cached",
+			invalidate: "missing.jsonnet",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+			nb := &fakeNodeBuilder{}
+			c := NewNodeCache()
+			c.nodeBuilder = nb
+
+			for _, name := range []string{"importcollector1.jsonnet", "importcollector2.jsonnet", "importcollector3.jsonnet"} {
+				require.NoError(t, c.rebuild(ctx, name, libPaths))
+			}
+			nb.reset()
+
+			keys, err := c.Invalidate(ctx, tc.invalidate)
+			require.NoError(t, err)
+			waitForKeys(t, c, 3)
+
+			assert.Equal(t, tc.expected, keys)
+			assert.Equal(t, tc.expected, nb.builtNames())
+		})
+	}
+}
+
+func TestNodeCache_Dependents(t *testing.T) {
+	abs, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	libPaths := []string{abs}
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+	c := NewNodeCache()
+	c.nodeBuilder = &fakeNodeBuilder{}
+
+	for _, name := range []string{"importcollector2.jsonnet", "importcollector3.jsonnet"} {
+		require.NoError(t, c.rebuild(ctx, name, libPaths))
+	}
+
+	assert.Equal(t,
+		[]string{"importcollector2.jsonnet", "importcollector3.jsonnet"},
+		c.Dependents("importcollector1.jsonnet"))
+
+	require.NoError(t, c.Remove("importcollector2.jsonnet"))
+
+	assert.Equal(t,
+		[]string{"importcollector3.jsonnet"},
+		c.Dependents("importcollector1.jsonnet"))
+	assert.Equal(t,
+		[]string{"importcollector3.jsonnet"},
+		c.Dependents("importcollector2.jsonnet"))
+}
@@ -0,0 +1,302 @@
+package token
+
+import (
+	"context"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/astext"
+	"github.com/google/go-jsonnet/ast"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+)
+
+// ObjectField is a field of an object.
+type ObjectField struct {
+	Name string
+	// Hidden is true if the field is hidden, i.e. it was declared with
+	// `::` or inherits a hidden field.
+	Hidden bool
+	// Method is true if the field is a function.
+	Method bool
+	// Parameters are the parameters of a method.
+	Parameters []string
+	// Node is the value of the field.
+	Node ast.Node
+	// Loc is the location of the field name.
+	Loc ast.LocationRange
+	// Type is the inferred type of the value. Inferring types is
+	// expensive, so it is only set by DeclaredField and for fields found
+	// through the inferred type of the object.
+	Type *Type
+
+	hide ast.ObjectFieldHide
+}
+
+// IndexFields returns the fields of the object being indexed at the end
+// of source, e.g. the fields of `self` for `{a: 1, b: self.`. The
+// object is found statically by following locals, `self`, `super`,
+// `$`, object composition with `+` and imports. If the object can't be
+// found, the fields of the inferred type of the target are returned,
+// e.g. for the elements of an array of objects. Fields are returned in
+// inheritance order: fields of the base object come first, and an
+// overridden field keeps the position of the field it overrides.
+func IndexFields(ctx context.Context, filename, source string, libPaths []string, nodeCache *NodeCache) ([]ObjectField, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "indexFields")
+	defer span.Finish()
+
+	r := newResolver(libPaths)
+	r.nodeCache = nodeCache
+
+	if _, err := r.addSource(filename, source); err != nil {
+		return nil, err
+	}
+
+	partial := r.lastPartialIndex()
+	if partial == nil {
+		return nil, errors.New("source does not end with an index")
+	}
+
+	if partial.Super {
+		env := r.envs[partial]
+		if env == nil || env.object == nil {
+			return nil, errors.New("super used outside of an object")
+		}
+
+		super, ok := r.supers[env.object]
+		if !ok {
+			return nil, errors.New("object does not have a super object")
+		}
+
+		return r.fields(super)
+	}
+
+	fields, err := r.fields(partial.Target)
+	if err == nil {
+		return fields, nil
+	}
+
+	if t := r.typeInferrer().infer(partial.Target); t.Kind == TypeObject {
+		return typeFields(t), nil
+	}
+
+	return nil, err
+}
+
+// DeclaredField returns the field whose name is declared at loc in a
+// source. The value of the field is resolved and its type is inferred.
+func DeclaredField(ctx context.Context, filename, source string, loc ast.Location, libPaths []string, nodeCache *NodeCache) (*ObjectField, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "declaredField")
+	defer span.Finish()
+
+	r := newResolver(libPaths)
+	r.nodeCache = nodeCache
+
+	if _, err := r.addSource(filename, source); err != nil {
+		return nil, err
+	}
+
+	for n := range r.envs {
+		obj, ok := n.(*ast.DesugaredObject)
+		if !ok {
+			continue
+		}
+
+		for _, field := range obj.Fields {
+			name, err := fieldName(field)
+			if err != nil {
+				continue
+			}
+
+			fieldLoc, ok := obj.FieldLocs[name]
+			if !ok || fieldLoc.FileName != filename || fieldLoc.Begin != loc {
+				continue
+			}
+
+			of := r.objectField(name, field, 0)
+			of.Loc = fieldLoc
+			of.Type = r.typeInferrer().infer(field.Body)
+
+			return &of, nil
+		}
+	}
+
+	return nil, errors.Errorf("no field is declared at %d:%d in %s", loc.Line, loc.Column, filename)
+}
+
+// typeFields returns the fields of an object type.
+func typeFields(t *Type) []ObjectField {
+	var fields []ObjectField
+	for _, f := range t.Fields() {
+		of := ObjectField{
+			Name:   f.Name,
+			Hidden: f.Hidden,
+			Node:   f.Node,
+			Type:   f.Type(),
+			hide:   f.hide,
+		}
+
+		if of.Type.Kind == TypeFunction {
+			of.Method = true
+			for _, param := range of.Type.Params {
+				of.Parameters = append(of.Parameters, param.Name)
+			}
+		}
+
+		fields = append(fields, of)
+	}
+
+	return fields
+}
+
+// lastPartialIndex finds the partial index which starts last in the
+// nodes visited by the resolver.
+func (r *resolver) lastPartialIndex() *astext.PartialIndex {
+	var found *astext.PartialIndex
+	for n := range r.envs {
+		pi, ok := n.(*astext.PartialIndex)
+		if !ok {
+			continue
+		}
+
+		if found == nil || isBefore(found.Loc().Begin, pi.Loc().Begin) {
+			found = pi
+		}
+	}
+
+	return found
+}
+
+func isBefore(a, b ast.Location) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// fields returns the fields of the object n resolves to.
+func (r *resolver) fields(n ast.Node) ([]ObjectField, error) {
+	return r.fieldsDepth(n, 0)
+}
+
+func (r *resolver) fieldsDepth(n ast.Node, depth int) ([]ObjectField, error) {
+	if depth > maxResolveDepth {
+		return nil, errors.New("maximum resolve depth exceeded")
+	}
+	depth++
+
+	v, err := r.valueDepth(n, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case *ast.DesugaredObject:
+		var fields []ObjectField
+		for _, field := range v.Fields {
+			name, err := fieldName(field)
+			if err != nil {
+				// fields with computed names can't be completed.
+				continue
+			}
+
+			if isPartialField(field) {
+				// the field being typed isn't a completion for itself.
+				continue
+			}
+
+			of := r.objectField(name, field, depth)
+			of.Loc = v.FieldLocs[name]
+			fields = append(fields, of)
+		}
+
+		return fields, nil
+	case *ast.Binary:
+		if v.Op != ast.BopPlus {
+			break
+		}
+
+		left, err := r.fieldsDepth(v.Left, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := r.fieldsDepth(v.Right, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		return mergeFields(left, right), nil
+	}
+
+	return nil, errors.Errorf("unable to find fields in %T", v)
+}
+
+// isPartialField returns true if the body of a field is the partial
+// expression at the end of a source, e.g. `b` in `{a: 1, b: self.`.
+func isPartialField(field ast.DesugaredObjectField) bool {
+	body := field.Body
+	if len(objectLocalBinds(body)) > 0 {
+		body = body.(*ast.Local).Body
+	}
+
+	switch body.(type) {
+	case *astext.Partial, *astext.PartialIndex:
+		return true
+	}
+
+	return false
+}
+
+func (r *resolver) objectField(name string, field ast.DesugaredObjectField, depth int) ObjectField {
+	of := ObjectField{
+		Name:   name,
+		Hidden: field.Hide == ast.ObjectFieldHidden,
+		Node:   field.Body,
+		hide:   field.Hide,
+	}
+
+	value, err := r.valueDepth(field.Body, depth)
+	if err != nil {
+		return of
+	}
+	of.Node = value
+
+	if fn, ok := value.(*ast.Function); ok {
+		of.Method = true
+		for _, param := range fn.Parameters.Required {
+			of.Parameters = append(of.Parameters, string(param))
+		}
+		for _, param := range fn.Parameters.Optional {
+			of.Parameters = append(of.Parameters, string(param.Name))
+		}
+	}
+
+	return of
+}
+
+// mergeFields overrides the fields of a base object with the fields of
+// an object composed with it. Fields declared with `:` keep the
+// visibility of the field they override.
+func mergeFields(base, fields []ObjectField) []ObjectField {
+	merged := append([]ObjectField{}, base...)
+
+	index := make(map[string]int)
+	for i, field := range merged {
+		index[field.Name] = i
+	}
+
+	for _, field := range fields {
+		i, ok := index[field.Name]
+		if !ok {
+			index[field.Name] = len(merged)
+			merged = append(merged, field)
+			continue
+		}
+
+		hidden := field.Hidden
+		if field.hide == ast.ObjectFieldInherit {
+			hidden = merged[i].Hidden
+		}
+
+		merged[i] = field
+		merged[i].Hidden = hidden
+	}
+
+	return merged
+}
@@ -0,0 +1,207 @@
+package token
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-jsonnet/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexFields(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "definition"))
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "file.jsonnet")
+	libPaths := []string{filepath.Join(dir, "lib")}
+
+	type field struct {
+		name       string
+		hidden     bool
+		method     bool
+		parameters []string
+	}
+
+	cases := []struct {
+		name     string
+		source   string
+		expected []field
+		isErr    bool
+	}{
+		{
+			name:     "local",
+			source:   "local o={a:1, b:2}; o.",
+			expected: []field{{name: "a"}, {name: "b"}},
+		},
+		{
+			name:     "nested",
+			source:   "local o={a:{b:1}}; o.a.",
+			expected: []field{{name: "b"}},
+		},
+		{
+			name:     "self",
+			source:   "{a: 1, b: self.",
+			expected: []field{{name: "a"}},
+		},
+		{
+			name:     "self in mixin",
+			source:   "{a: 1} + {b: self.",
+			expected: []field{{name: "a"}},
+		},
+		{
+			name:     "super",
+			source:   "{a: 1, c: 2} + {b: super.",
+			expected: []field{{name: "a"}, {name: "c"}},
+		},
+		{
+			name:     "dollar",
+			source:   "{a: 1, b: {c: $.",
+			expected: []field{{name: "a"}, {name: "b"}},
+		},
+		{
+			name:   "hidden",
+			source: "local o={a:: 1, b: 2}; o.",
+			expected: []field{
+				{name: "a", hidden: true},
+				{name: "b"},
+			},
+		},
+		{
+			name:   "inherited visibility",
+			source: "local o={a:: 1, b:: 2, c: 3} + {a: 4, b::: 5, c:: 6}; o.",
+			expected: []field{
+				{name: "a", hidden: true},
+				{name: "b"},
+				{name: "c", hidden: true},
+			},
+		},
+		{
+			name:   "inheritance order",
+			source: "local o={a: 1, b: 2} + {c: 3, a: 4}; o.",
+			expected: []field{
+				{name: "a"},
+				{name: "b"},
+				{name: "c"},
+			},
+		},
+		{
+			name:   "method",
+			source: "local o={f(x, y=1):: x, g: function(z) z}; o.",
+			expected: []field{
+				{name: "f", hidden: true, method: true, parameters: []string{"x", "y"}},
+				{name: "g", method: true, parameters: []string{"z"}},
+			},
+		},
+		{
+			name:     "import",
+			source:   "(import 'foo.libsonnet').",
+			expected: []field{{name: "deployment"}, {name: "service"}},
+		},
+		{
+			name:     "imported local",
+			source:   "local foo = import 'foo.libsonnet'; foo.deployment.",
+			expected: []field{{name: "spec"}},
+		},
+		{
+			name:     "import from node cache",
+			source:   "(import 'cached.libsonnet').",
+			expected: []field{{name: "cached"}},
+		},
+		{
+			name:     "array element",
+			source:   "local xs=[{a: 1}, {a: 2, b: 3}]; xs[0].",
+			expected: []field{{name: "a"}},
+		},
+		{
+			name:     "function result",
+			source:   "local f(x)={a: x, g(y):: y}; local o=f(1); o.",
+			expected: []field{{name: "a"}, {name: "g", hidden: true, method: true, parameters: []string{"y"}}},
+		},
+		{
+			name:   "not an object",
+			source: "local o=1; o.",
+			isErr:  true,
+		},
+		{
+			name:   "not an index",
+			source: "local o={a:1}; o",
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cached, err := ReadSource("cached.libsonnet", "{cached: true}", nil)
+			require.NoError(t, err)
+
+			nodeCache := NewNodeCache()
+			nodeCache.store["cached.libsonnet"] = NodeEntry{Node: cached}
+
+			got, err := IndexFields(context.Background(), file, tc.source, libPaths, nodeCache)
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var fields []field
+			for _, f := range got {
+				fields = append(fields, field{
+					name:       f.Name,
+					hidden:     f.Hidden,
+					method:     f.Method,
+					parameters: f.Parameters,
+				})
+			}
+
+			assert.Equal(t, tc.expected, fields)
+		})
+	}
+}
+
+func TestIndexFields_location(t *testing.T) {
+	got, err := IndexFields(context.Background(), "file.jsonnet", "local o={\n  a: 1,\n  'b': 2,\n}; o.", nil, NewNodeCache())
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+
+	assert.Equal(t, "file.jsonnet", got[0].Loc.FileName)
+	assert.Equal(t, createLoc(2, 3), got[0].Loc.Begin)
+	assert.Equal(t, createLoc(3, 3), got[1].Loc.Begin)
+	assert.Equal(t, createLoc(3, 6), got[1].Loc.End)
+}
+
+func TestDeclaredField(t *testing.T) {
+	source := "local o = {\n  a: [1],\n  f(x):: x,\n};\n{b: o.a}"
+
+	cases := []struct {
+		name       string
+		loc        ast.Location
+		expected   string
+		method     bool
+		parameters []string
+		isErr      bool
+	}{
+		{name: "field", loc: ast.Location{Line: 2, Column: 3}, expected: "array of number"},
+		{name: "method", loc: ast.Location{Line: 3, Column: 3}, expected: "function", method: true, parameters: []string{"x"}},
+		{name: "resolved value", loc: ast.Location{Line: 5, Column: 2}, expected: "array of number"},
+		{name: "no field", loc: ast.Location{Line: 1, Column: 1}, isErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DeclaredField(context.Background(), "file.jsonnet", source, tc.loc, nil, NewNodeCache())
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got.Type.String())
+			assert.Equal(t, tc.method, got.Method)
+			assert.Equal(t, tc.parameters, got.Parameters)
+			assert.Equal(t, tc.loc, got.Loc.Begin)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/astext"
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
 	"github.com/google/go-jsonnet/ast"
 	"github.com/google/go-jsonnet/parser"
 	"github.com/pkg/errors"
@@ -84,6 +85,8 @@ func locFromPartial(begin *Token) ast.LocationRange {
 type ParseDiagnostic struct {
 	Message string
 	Loc     ast.LocationRange
+	// Code is set for problems which don't prevent parsing.
+	Code static.DiagnosticCode
 }
 
 type mParser struct {
@@ -416,6 +419,11 @@ func (p *mParser) parse(prec precedence) (ast.Node, error) {
 			case TokenDot:
 				fieldID, err := p.popExpect(TokenIdentifier)
 				if err != nil {
+					// leave the unexpected token for the enclosing
+					// expression.
+					if p.peekPrev().Kind != TokenEndOfFile {
+						p.cur--
+					}
 					cur := p.peek()
 					loc := locFromTokens(cur, cur)
 					p.publishDiag("expected field id", cur.Loc)
@@ -804,6 +812,18 @@ func (p *mParser) parseObjectRemainder(tok *Token) (ast.Node, *Token, error) {
 			}, next, nil
 		}
 
+		if next.Kind == TokenEndOfFile {
+			// return the fields parsed so far because this is a partial
+			// parser.
+			p.publishDiag("object is missing a closing brace", next.Loc)
+			return &ast.Object{
+				NodeBase:      ast.NewNodeBaseLoc(locFromTokens(tok, next)),
+				Fields:        fields,
+				TrailingComma: gotComma,
+				FieldLocs:     fieldLocs,
+			}, next, nil
+		}
+
 		if next.Kind == TokenFor {
 			// It's a comprehension
 			numFields := 0
@@ -912,8 +932,8 @@ func (p *mParser) parseObjectRemainder(tok *Token) (ast.Node, *Token, error) {
 
 			if kind != ast.ObjectFieldExpr {
 				if !literalFields.Add(LiteralField(next.Data)) {
-					return nil, nil, locError(
-						errors.Errorf("Duplicate field: %v", next.Data), next.Loc)
+					p.publishCodeDiag(static.DuplicateField,
+						fmt.Sprintf("duplicate field: %v", next.Data), next.Loc)
 				}
 			}
 
@@ -1156,20 +1176,31 @@ func (p *mParser) parseTerminal() (ast.Node, error) {
 		next := p.pop()
 		var index ast.Node
 		var id *ast.Identifier
+		var end *Token
 		switch next.Kind {
 		case TokenDot:
 			fieldID, err := p.popExpect(TokenIdentifier)
 			if err != nil {
-				return nil, err
+				if p.peekPrev().Kind != TokenEndOfFile {
+					p.cur--
+				}
+				cur := p.peek()
+				p.publishDiag("expected field id", cur.Loc)
+				return &astext.PartialIndex{
+					NodeBase: ast.NewNodeBaseLoc(locFromTokens(cur, cur)),
+					Target:   &ast.Self{NodeBase: ast.NewNodeBaseLoc(tok.Loc)},
+					Super:    true,
+				}, nil
 			}
 			id = (*ast.Identifier)(&fieldID.Data)
+			end = fieldID
 		case TokenBracketL:
 			var err error
 			index, err = p.parse(maxPrecedence)
 			if err != nil {
 				return nil, err
 			}
-			_, err = p.popExpect(TokenBracketR)
+			end, err = p.popExpect(TokenBracketR)
 			if err != nil {
 				return nil, err
 			}
@@ -1177,7 +1208,7 @@ func (p *mParser) parseTerminal() (ast.Node, error) {
 			return nil, locError(errors.New("expected . or [ after super"), tok.Loc)
 		}
 		return &ast.SuperIndex{
-			NodeBase: ast.NewNodeBaseLoc(tok.Loc),
+			NodeBase: ast.NewNodeBaseLoc(locFromTokens(tok, end)),
 			Index:    index,
 			Id:       id,
 		}, nil
@@ -1243,6 +1274,16 @@ func (p *mParser) publishDiag(msg string, loc ast.LocationRange) {
 	}
 }
 
+func (p *mParser) publishCodeDiag(code static.DiagnosticCode, msg string, loc ast.LocationRange) {
+	if p.diagCh != nil {
+		p.diagCh <- ParseDiagnostic{
+			Message: msg,
+			Loc:     loc,
+			Code:    code,
+		}
+	}
+}
+
 func (p *mParser) unexpectedError(t *Token, while string) error {
 	return errors.Errorf("unexpected: %v while %v at %s", t, while, t.Loc.String())
 }
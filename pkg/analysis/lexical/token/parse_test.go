@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/astext"
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
 	pos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
 	"github.com/google/go-jsonnet/ast"
 	"github.com/stretchr/testify/assert"
@@ -236,6 +237,29 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParse_duplicateField(t *testing.T) {
+	ch := make(chan ParseDiagnostic, 1)
+	done := make(chan bool, 1)
+
+	var diagnostics []ParseDiagnostic
+	go func() {
+		for d := range ch {
+			diagnostics = append(diagnostics, d)
+		}
+
+		done <- true
+	}()
+
+	_, err := Parse("file.jsonnet", "{a: 1, 'a': 2, [a]: 3}", ch)
+	require.NoError(t, err)
+
+	<-done
+
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, static.DuplicateField, diagnostics[0].Code)
+	assert.Equal(t, createLoc(1, 8), diagnostics[0].Loc.Begin)
+}
+
 func createFakeNodeBase(l1, c1, l2, c2 int) ast.NodeBase {
 	return ast.NewNodeBaseLoc(createRange("file.jsonnet", l1, c1, l2, c2))
 }
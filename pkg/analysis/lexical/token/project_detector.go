@@ -0,0 +1,260 @@
+package token
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProjectTypeKsonnet is the type of ksonnet applications.
+	ProjectTypeKsonnet = "ksonnet"
+
+	// ProjectTypeTanka is the type of Tanka projects.
+	ProjectTypeTanka = "tanka"
+
+	// TankaEnvironmentExtCode is the ext code Tanka sets to the
+	// environment of the document being evaluated.
+	TankaEnvironmentExtCode = "tanka.dev/environment"
+)
+
+// ProjectInfo describes the project a document is in.
+type ProjectInfo struct {
+	// Type is the project type.
+	Type string
+	// Root is the project root directory.
+	Root string
+	// LibPaths are the lib paths of the document in the order they are
+	// searched.
+	LibPaths []string
+	// ExtVar are external string variables for the document.
+	ExtVar map[string]string
+	// ExtCode are external code variables for the document.
+	ExtCode map[string]string
+}
+
+// ProjectDetector detects a type of project.
+type ProjectDetector interface {
+	// Detect detects the project containing the document at path. It
+	// returns false if the document is not in a project of this type.
+	Detect(path string) (ProjectInfo, bool, error)
+}
+
+var (
+	projectDetectors = []ProjectDetector{
+		&ksonnetDetector{},
+		&tankaDetector{},
+	}
+	projectDetectorsMu sync.RWMutex
+)
+
+// RegisterProjectDetector registers a project detector. Detectors are
+// tried in the order they are registered.
+func RegisterProjectDetector(detector ProjectDetector) {
+	projectDetectorsMu.Lock()
+	defer projectDetectorsMu.Unlock()
+
+	projectDetectors = append(projectDetectors, detector)
+}
+
+// DetectProject detects the project containing the document at path
+// using the first detector which recognizes it.
+func DetectProject(path string) (ProjectInfo, bool, error) {
+	projectDetectorsMu.RLock()
+	defer projectDetectorsMu.RUnlock()
+
+	for _, detector := range projectDetectors {
+		info, ok, err := detector.Detect(path)
+		if err != nil {
+			return ProjectInfo{}, false, err
+		}
+
+		if ok {
+			return info, true, nil
+		}
+	}
+
+	return ProjectInfo{}, false, nil
+}
+
+// ksonnetDetector detects ksonnet applications. Components are given
+// their parameters as the __ksonnet/params ext code.
+type ksonnetDetector struct{}
+
+var _ ProjectDetector = (*ksonnetDetector)(nil)
+
+func (d *ksonnetDetector) Detect(path string) (ProjectInfo, bool, error) {
+	dir, file := filepath.Split(path)
+
+	root, ok, err := findRoot(dir, "app.yaml")
+	if err != nil || !ok {
+		return ProjectInfo{}, false, err
+	}
+
+	info := ProjectInfo{
+		Type:    ProjectTypeKsonnet,
+		Root:    root,
+		ExtVar:  make(map[string]string),
+		ExtCode: make(map[string]string),
+	}
+
+	componentDir := filepath.Join(root, "components")
+	if strings.HasPrefix(dir, componentDir) && file != "params.libsonnet" {
+		// this file is a component
+
+		// create __ksonnet/params ext code
+		paramsFile := filepath.Join(dir, "params.libsonnet")
+		data, err := ioutil.ReadFile(paramsFile)
+		if err != nil {
+			return ProjectInfo{}, false, err
+		}
+
+		// load parameters for current component
+		info.ExtCode["__ksonnet/params"] = string(data)
+	}
+
+	return info, true, nil
+}
+
+// tankaDetector detects Tanka projects. The root of a project contains
+// a tkrc.yaml or jsonnetfile.json file, and environments are directories
+// containing main.jsonnet and spec.json. Lib paths are ordered the same
+// way as `tk eval`: the environment, lib and vendor directories.
+type tankaDetector struct{}
+
+var _ ProjectDetector = (*tankaDetector)(nil)
+
+func (d *tankaDetector) Detect(path string) (ProjectInfo, bool, error) {
+	dir := filepath.Dir(path)
+
+	root, ok, err := findRoot(dir, "tkrc.yaml", "jsonnetfile.json")
+	if err != nil || !ok {
+		return ProjectInfo{}, false, err
+	}
+
+	env, inEnv := tankaEnvironment(root, dir)
+
+	// jsonnet-bundler projects are only Tanka projects if they have
+	// environments.
+	if !inEnv && !exists(filepath.Join(root, "tkrc.yaml")) && !exists(filepath.Join(root, "environments")) {
+		return ProjectInfo{}, false, nil
+	}
+
+	info := ProjectInfo{
+		Type:    ProjectTypeTanka,
+		Root:    root,
+		ExtVar:  make(map[string]string),
+		ExtCode: make(map[string]string),
+	}
+
+	if inEnv {
+		info.LibPaths = append(info.LibPaths, env)
+
+		spec, err := tankaSpec(root, env)
+		if err != nil {
+			return ProjectInfo{}, false, err
+		}
+
+		info.ExtCode[TankaEnvironmentExtCode] = spec
+	}
+
+	info.LibPaths = append(info.LibPaths,
+		filepath.Join(root, "lib"),
+		filepath.Join(root, "vendor"))
+
+	return info, true, nil
+}
+
+// tankaEnvironment finds the environment between dir and root.
+func tankaEnvironment(root, dir string) (string, bool) {
+	for {
+		if exists(filepath.Join(dir, "main.jsonnet")) && exists(filepath.Join(dir, "spec.json")) {
+			return dir, true
+		}
+
+		if dir == root {
+			return "", false
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// tankaSpec reads the spec.json of an environment. Like Tanka, the name
+// of the environment is its path relative to the project root.
+func tankaSpec(root, env string) (string, error) {
+	specFile := filepath.Join(env, "spec.json")
+
+	data, err := ioutil.ReadFile(specFile)
+	if err != nil {
+		return "", err
+	}
+
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return "", errors.Wrapf(err, "reading %s", specFile)
+	}
+
+	metadata, ok := spec["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = make(map[string]interface{})
+		spec["metadata"] = metadata
+	}
+
+	if _, ok := metadata["name"]; !ok {
+		name, err := filepath.Rel(root, env)
+		if err != nil {
+			return "", err
+		}
+		metadata["name"] = filepath.ToSlash(name)
+	}
+
+	data, err = json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// findRoot finds the nearest directory containing dir which contains one
+// of the marker files.
+func findRoot(dir string, markers ...string) (string, bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false, err
+	}
+
+	for {
+		for _, marker := range markers {
+			_, err := os.Stat(filepath.Join(dir, marker))
+			if err == nil {
+				return dir, true, nil
+			}
+
+			if !os.IsNotExist(err) {
+				return "", false, err
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false, nil
+		}
+		dir = parent
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
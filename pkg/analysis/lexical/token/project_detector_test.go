@@ -0,0 +1,137 @@
+package token
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "detect-project")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"tk/jsonnetfile.json":                 "{}",
+		"tk/environments/dev/main.jsonnet":    "{}",
+		"tk/environments/dev/spec.json":       `{"apiVersion": "tanka.dev/v1alpha1", "kind": "Environment", "spec": {"namespace": "dev"}}`,
+		"tk/environments/dev/app/app.jsonnet": "{}",
+		"tk/lib/k.libsonnet":                  "{}",
+		"ks/app.yaml":                         "",
+		"ks/components/params.libsonnet":      "{}",
+		"ks/components/deployment.jsonnet":    "{}",
+		"jb/jsonnetfile.json":                 "{}",
+		"jb/main.jsonnet":                     "{}",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	tk := filepath.Join(dir, "tk")
+	env := filepath.Join(tk, "environments", "dev")
+	envSpec := `{"apiVersion":"tanka.dev/v1alpha1","kind":"Environment","metadata":{"name":"environments/dev"},"spec":{"namespace":"dev"}}`
+
+	cases := []struct {
+		name     string
+		path     string
+		expected ProjectInfo
+		found    bool
+	}{
+		{
+			name: "tanka environment",
+			path: filepath.Join(env, "main.jsonnet"),
+			expected: ProjectInfo{
+				Type:     ProjectTypeTanka,
+				Root:     tk,
+				LibPaths: []string{env, filepath.Join(tk, "lib"), filepath.Join(tk, "vendor")},
+				ExtVar:   map[string]string{},
+				ExtCode:  map[string]string{TankaEnvironmentExtCode: envSpec},
+			},
+			found: true,
+		},
+		{
+			name: "below tanka environment",
+			path: filepath.Join(env, "app", "app.jsonnet"),
+			expected: ProjectInfo{
+				Type:     ProjectTypeTanka,
+				Root:     tk,
+				LibPaths: []string{env, filepath.Join(tk, "lib"), filepath.Join(tk, "vendor")},
+				ExtVar:   map[string]string{},
+				ExtCode:  map[string]string{TankaEnvironmentExtCode: envSpec},
+			},
+			found: true,
+		},
+		{
+			name: "tanka lib",
+			path: filepath.Join(tk, "lib", "k.libsonnet"),
+			expected: ProjectInfo{
+				Type:     ProjectTypeTanka,
+				Root:     tk,
+				LibPaths: []string{filepath.Join(tk, "lib"), filepath.Join(tk, "vendor")},
+				ExtVar:   map[string]string{},
+				ExtCode:  map[string]string{},
+			},
+			found: true,
+		},
+		{
+			name: "ksonnet component",
+			path: filepath.Join(dir, "ks", "components", "deployment.jsonnet"),
+			expected: ProjectInfo{
+				Type:    ProjectTypeKsonnet,
+				Root:    filepath.Join(dir, "ks"),
+				ExtVar:  map[string]string{},
+				ExtCode: map[string]string{"__ksonnet/params": "{}"},
+			},
+			found: true,
+		},
+		{
+			name: "jsonnet-bundler without environments",
+			path: filepath.Join(dir, "jb", "main.jsonnet"),
+		},
+		{
+			name: "not in a project",
+			path: filepath.Join(dir, "main.jsonnet"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, found, err := DetectProject(tc.path)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.found, found)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestNewIdentifyConfig_project(t *testing.T) {
+	dir, err := ioutil.TempDir("", "detect-project")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	env := filepath.Join(dir, "environments", "default")
+	require.NoError(t, os.MkdirAll(env, 0755))
+
+	for path, content := range map[string]string{
+		filepath.Join(dir, "tkrc.yaml"):    "",
+		filepath.Join(env, "main.jsonnet"): "{}",
+		filepath.Join(env, "spec.json"):    `{"metadata": {"name": "default"}}`,
+	} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	ic, err := NewIdentifyConfig(filepath.Join(env, "main.jsonnet"), "/client", filepath.Join(dir, "lib"))
+	require.NoError(t, err)
+
+	expected := []string{env, filepath.Join(dir, "lib"), filepath.Join(dir, "vendor"), "/client"}
+	assert.Equal(t, expected, ic.jsonnetLibPaths)
+	assert.Equal(t, `{"metadata":{"name":"default"}}`, ic.extCode[TankaEnvironmentExtCode])
+}
@@ -0,0 +1,629 @@
+package token
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/astext"
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/google/go-jsonnet/parser"
+)
+
+var fieldOperators = map[string]bool{
+	":":    true,
+	"::":   true,
+	":::":  true,
+	"+:":   true,
+	"+::":  true,
+	"+:::": true,
+}
+
+// removeUnusedLocal removes the bind of an unused local. If the bind is
+// the only bind in a local, the whole local is removed.
+// nolint: gocyclo
+func removeUnusedLocal(req CodeActionRequest, tokens Tokens, diagnostic CodeActionDiagnostic) ([]CodeAction, error) {
+	i := tokenIndexAt(tokens, diagnostic.Range.Start.ToJsonnet())
+	if i == -1 {
+		return nil, nil
+	}
+
+	name := bindNameIndex(tokens, i)
+	if name == -1 {
+		return nil, nil
+	}
+
+	eq := name + 1
+	if tokens[eq].Kind == TokenParenL {
+		eq = closingBracket(tokens, eq) + 1
+	}
+	if eq == 0 || eq >= len(tokens) || tokens[eq].Kind != TokenOperator || tokens[eq].Data != "=" {
+		return nil, nil
+	}
+
+	end := bindEnd(tokens, eq+1)
+	bodyEnd := tokens[end-1].Loc.End
+	terminator := tokens[end].Kind
+	local := name - 1
+	first := tokens[local].Kind == TokenLocal
+
+	var begin, finish ast.Location
+	switch {
+	case first && isObjectLocal(tokens, local) && terminator == TokenComma:
+		begin, finish = tokens[local].Loc.Begin, trailingEnd(tokens, end)
+	case first && isObjectLocal(tokens, local) && tokens[local-1].Kind == TokenComma:
+		// the last field in the object.
+		begin, finish = tokens[local-1].Loc.Begin, bodyEnd
+	case first && isObjectLocal(tokens, local):
+		begin, finish = tokens[local].Loc.Begin, bodyEnd
+	case first && terminator == TokenSemicolon:
+		begin, finish = tokens[local].Loc.Begin, trailingEnd(tokens, end)
+	case first && terminator == TokenComma:
+		begin, finish = tokens[name].Loc.Begin, trailingEnd(tokens, end)
+	case !first:
+		begin, finish = tokens[local].Loc.Begin, bodyEnd
+	default:
+		return nil, nil
+	}
+
+	action := CodeAction{
+		Title: fmt.Sprintf("Remove unused local %s", tokens[name].Data),
+		Edits: []Edit{
+			{Range: rangeBetween(begin, finish)},
+		},
+	}
+
+	return []CodeAction{action}, nil
+}
+
+// addMissingImport imports a library with the name of an undefined
+// variable. Libraries are found by searching the lib paths for
+// `<name>.libsonnet` and `<name>.jsonnet`.
+func addMissingImport(req CodeActionRequest, tokens Tokens, diagnostic CodeActionDiagnostic) ([]CodeAction, error) {
+	i := tokenIndexAt(tokens, diagnostic.Range.Start.ToJsonnet())
+	if i == -1 || tokens[i].Kind != TokenIdentifier {
+		return nil, nil
+	}
+
+	name := tokens[i].Data
+
+	// the import is added to the start of the line containing the first
+	// token, so comments at the top of the file stay on top.
+	insertAt := ast.Location{Line: tokens[0].Loc.Begin.Line, Column: 1}
+
+	var actions []CodeAction
+	for _, importName := range findLibraries(name, req.LibPaths) {
+		quoted := quoteString(importName)
+		actions = append(actions, CodeAction{
+			Title: fmt.Sprintf("Import %s from %s", name, quoted),
+			Edits: []Edit{
+				{
+					Range:   rangeBetween(insertAt, insertAt),
+					NewText: fmt.Sprintf("local %s = import %s;\n", name, quoted),
+				},
+			},
+		})
+	}
+
+	return actions, nil
+}
+
+// hideField converts the visibility of the object field in a range to
+// hidden.
+func hideField(req CodeActionRequest, tokens Tokens) ([]CodeAction, error) {
+	name, op, ok := objectField(tokens, req.Range.Start)
+	if !ok {
+		return nil, nil
+	}
+
+	data := tokens[op].Data
+	if strings.TrimPrefix(data, "+") == "::" {
+		return nil, nil
+	}
+
+	newOp := "::"
+	if strings.HasPrefix(data, "+") {
+		newOp = "+::"
+	}
+
+	action := CodeAction{
+		Title: fmt.Sprintf("Hide field %s", tokens[name].Data),
+		Edits: []Edit{
+			{Range: jpos.FromJsonnetRange(tokens[op].Loc), NewText: newOp},
+		},
+	}
+
+	return []CodeAction{action}, nil
+}
+
+// mixinField converts the object field in a range to a field which is
+// merged with the inherited field using `+:`.
+func mixinField(req CodeActionRequest, tokens Tokens) ([]CodeAction, error) {
+	name, op, ok := objectField(tokens, req.Range.Start)
+	if !ok {
+		return nil, nil
+	}
+
+	data := tokens[op].Data
+	if strings.HasPrefix(data, "+") {
+		return nil, nil
+	}
+
+	// methods can't be merged.
+	if tokens[op-1].Kind == TokenParenR {
+		return nil, nil
+	}
+
+	newOp := "+" + data
+
+	action := CodeAction{
+		Title: fmt.Sprintf("Merge field %s with inherited field using %s", tokens[name].Data, newOp),
+		Edits: []Edit{
+			{Range: jpos.FromJsonnetRange(tokens[op].Loc), NewText: newOp},
+		},
+	}
+
+	return []CodeAction{action}, nil
+}
+
+// formatConcatenation converts a string concatenation in a range to a
+// format string, e.g. `'hello ' + name` becomes `'hello %s' % [name]`.
+// Concatenations are only converted if the first or second operand is a
+// string literal, since otherwise the leading operands could be added
+// as numbers.
+func formatConcatenation(req CodeActionRequest, tokens Tokens) ([]CodeAction, error) {
+	node, err := Parse(req.Filename, req.Source, nil)
+	if err != nil {
+		// source that doesn't parse can't be refactored.
+		return nil, nil
+	}
+
+	concatenation := findConcatenation(node, req.Range.Start)
+	if concatenation == nil {
+		return nil, nil
+	}
+
+	operands := concatenationOperands(concatenation)
+	if !isLiteralString(operands[0]) && !isLiteralString(operands[1]) {
+		return nil, nil
+	}
+
+	var format strings.Builder
+	var args []string
+	for _, operand := range operands {
+		if s, ok := operand.(*ast.LiteralString); ok {
+			format.WriteString(strings.Replace(s.Value, "%", "%%", -1))
+			continue
+		}
+
+		format.WriteString("%s")
+		args = append(args, sourceText(req.Source, *operand.Loc()))
+	}
+
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	action := CodeAction{
+		Title: "Convert concatenation to format string",
+		Edits: []Edit{
+			{
+				Range:   jpos.FromJsonnetRange(*concatenation.Loc()),
+				NewText: fmt.Sprintf("%s %% [%s]", quoteString(format.String()), strings.Join(args, ", ")),
+			},
+		},
+	}
+
+	return []CodeAction{action}, nil
+}
+
+// tokenIndexAt returns the index of the token beginning at loc, or -1
+// if no token begins at loc.
+func tokenIndexAt(tokens Tokens, loc ast.Location) int {
+	for i := range tokens {
+		if tokens[i].Loc.Begin == loc {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// tokenIndexContaining returns the index of the first token containing
+// pos, or -1 if no token contains pos.
+func tokenIndexContaining(tokens Tokens, pos jpos.Position) int {
+	for i := range tokens {
+		if tokens[i].Kind == TokenEndOfFile {
+			break
+		}
+		if pos.IsInJsonnetRange(tokens[i].Loc) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isOpeningBracket(kind TokenKind) bool {
+	return kind == TokenBraceL || kind == TokenBracketL || kind == TokenParenL
+}
+
+func isClosingBracket(kind TokenKind) bool {
+	return kind == TokenBraceR || kind == TokenBracketR || kind == TokenParenR
+}
+
+// closingBracket returns the index of the bracket closing the bracket
+// at i, or -1 if it isn't closed.
+func closingBracket(tokens Tokens, i int) int {
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		switch {
+		case isOpeningBracket(tokens[j].Kind):
+			depth++
+		case isClosingBracket(tokens[j].Kind):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+
+	return -1
+}
+
+// enclosingBracket returns the index of the innermost unclosed bracket
+// before i, or -1 if there isn't one.
+func enclosingBracket(tokens Tokens, i int) int {
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		switch {
+		case isClosingBracket(tokens[j].Kind):
+			depth++
+		case isOpeningBracket(tokens[j].Kind):
+			if depth == 0 {
+				return j
+			}
+			depth--
+		}
+	}
+
+	return -1
+}
+
+// bindNameIndex returns the index of the name of the local bind which
+// is reported at the token at i. Locals are reported at their name,
+// except for object locals which are reported at their body.
+func bindNameIndex(tokens Tokens, i int) int {
+	if isBindName(tokens, i) {
+		return i
+	}
+
+	j := i - 1
+	if j < 0 || tokens[j].Kind != TokenOperator || tokens[j].Data != "=" {
+		return -1
+	}
+
+	j--
+	if j >= 0 && tokens[j].Kind == TokenParenR {
+		j = enclosingBracket(tokens, j) - 1
+	}
+
+	if j >= 0 && isBindName(tokens, j) {
+		return j
+	}
+
+	return -1
+}
+
+func isBindName(tokens Tokens, i int) bool {
+	if i < 1 || i+1 >= len(tokens) || tokens[i].Kind != TokenIdentifier {
+		return false
+	}
+
+	switch tokens[i-1].Kind {
+	case TokenLocal, TokenComma:
+	default:
+		return false
+	}
+
+	next := tokens[i+1]
+	return next.Kind == TokenParenL || (next.Kind == TokenOperator && next.Data == "=")
+}
+
+// isObjectLocal returns true if the local keyword at i declares an
+// object local.
+func isObjectLocal(tokens Tokens, i int) bool {
+	if i < 1 {
+		return false
+	}
+
+	switch tokens[i-1].Kind {
+	case TokenBraceL, TokenComma:
+	default:
+		return false
+	}
+
+	opening := enclosingBracket(tokens, i)
+	return opening != -1 && tokens[opening].Kind == TokenBraceL
+}
+
+// bindEnd returns the index of the token ending the bind body starting
+// at i. The body ends at the first comma, semicolon or closing bracket
+// which doesn't belong to an expression within the body.
+func bindEnd(tokens Tokens, i int) int {
+	depth, pending := 0, 0
+	for j := i; j < len(tokens); j++ {
+		kind := tokens[j].Kind
+		switch {
+		case kind == TokenEndOfFile:
+			return j
+		case isOpeningBracket(kind):
+			depth++
+		case isClosingBracket(kind):
+			if depth == 0 {
+				return j
+			}
+			depth--
+		case depth > 0:
+		case kind == TokenLocal, kind == TokenAssert:
+			// locals and asserts in the body end with their own semicolon.
+			pending++
+		case kind == TokenSemicolon:
+			if pending == 0 {
+				return j
+			}
+			pending--
+		case kind == TokenComma:
+			if pending == 0 {
+				return j
+			}
+		}
+	}
+
+	return len(tokens) - 1
+}
+
+// trailingEnd returns the location after the token at i and the
+// whitespace following it. Comments after the token are kept.
+func trailingEnd(tokens Tokens, i int) ast.Location {
+	if i+1 >= len(tokens) {
+		return tokens[i].Loc.End
+	}
+
+	for _, f := range tokens[i+1].Fodder() {
+		if !f.IsWhitespace() {
+			return tokens[i].Loc.End
+		}
+	}
+
+	return tokens[i+1].Loc.Begin
+}
+
+// objectField returns the indexes of the name and assignment operator
+// of the object field at pos.
+func objectField(tokens Tokens, pos jpos.Position) (int, int, bool) {
+	i := tokenIndexContaining(tokens, pos)
+	if i == -1 {
+		return 0, 0, false
+	}
+
+	var name, op int
+	if isFieldOperator(tokens[i]) {
+		op, name = i, i-1
+		if name >= 0 && tokens[name].Kind == TokenParenR {
+			name = enclosingBracket(tokens, name) - 1
+		}
+	} else {
+		name, op = i, i+1
+		if op < len(tokens) && tokens[op].Kind == TokenParenL {
+			op = closingBracket(tokens, op)
+			if op == -1 {
+				return 0, 0, false
+			}
+			op++
+		}
+	}
+
+	if name < 1 || op >= len(tokens) || !isFieldOperator(tokens[op]) {
+		return 0, 0, false
+	}
+
+	switch tokens[name].Kind {
+	case TokenIdentifier, TokenStringDouble, TokenStringSingle,
+		TokenVerbatimStringDouble, TokenVerbatimStringSingle:
+	default:
+		return 0, 0, false
+	}
+
+	switch tokens[name-1].Kind {
+	case TokenBraceL, TokenComma:
+	default:
+		return 0, 0, false
+	}
+
+	opening := enclosingBracket(tokens, name)
+	if opening == -1 || tokens[opening].Kind != TokenBraceL {
+		return 0, 0, false
+	}
+
+	return name, op, true
+}
+
+func isFieldOperator(t Token) bool {
+	return t.Kind == TokenOperator && fieldOperators[t.Data]
+}
+
+// findConcatenation finds the innermost string concatenation containing
+// pos. A concatenation is a chain of `+` operators.
+func findConcatenation(node ast.Node, pos jpos.Position) *ast.Binary {
+	var found *ast.Binary
+
+	var visit func(n ast.Node, inChain bool)
+	visit = func(n ast.Node, inChain bool) {
+		if n == nil || !pos.IsInJsonnetRange(*n.Loc()) {
+			return
+		}
+
+		if b, ok := n.(*ast.Binary); ok && b.Op == ast.BopPlus {
+			if !inChain {
+				found = b
+			}
+
+			visit(b.Left, true)
+			visit(b.Right, false)
+			return
+		}
+
+		for _, child := range parsedChildren(n) {
+			visit(child, false)
+		}
+	}
+
+	visit(node, false)
+
+	return found
+}
+
+// concatenationOperands returns the operands of a chain of `+`
+// operators.
+func concatenationOperands(b *ast.Binary) []ast.Node {
+	var operands []ast.Node
+	if left, ok := b.Left.(*ast.Binary); ok && left.Op == ast.BopPlus {
+		operands = concatenationOperands(left)
+	} else {
+		operands = append(operands, b.Left)
+	}
+
+	return append(operands, b.Right)
+}
+
+func isLiteralString(node ast.Node) bool {
+	_, ok := node.(*ast.LiteralString)
+	return ok
+}
+
+// parsedChildren returns the children of a node which hasn't been
+// desugared.
+func parsedChildren(node ast.Node) []ast.Node {
+	switch n := node.(type) {
+	case *astext.Partial:
+		return nil
+	case *astext.PartialIndex:
+		return []ast.Node{n.Target}
+	case *ast.Local:
+		var nodes []ast.Node
+		for _, bind := range n.Binds {
+			nodes = append(nodes, bind.Body)
+		}
+		return append(nodes, n.Body)
+	default:
+		return parser.Children(node)
+	}
+}
+
+// findLibraries returns the import names of the libraries in the lib
+// paths named name.
+func findLibraries(name string, libPaths []string) []string {
+	found := make(map[string]bool)
+
+	for _, libPath := range libPaths {
+		_ = filepath.Walk(libPath, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			if fi.IsDir() {
+				if path != libPath && strings.HasPrefix(fi.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			ext := filepath.Ext(path)
+			switch ext {
+			case ".jsonnet", ".libsonnet":
+			default:
+				return nil
+			}
+
+			if strings.TrimSuffix(fi.Name(), ext) != name {
+				return nil
+			}
+
+			for _, importName := range ImportNames(path, []string{libPath}) {
+				found[importName] = true
+			}
+
+			return nil
+		})
+	}
+
+	var names []string
+	for importName := range found {
+		names = append(names, importName)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// quoteString quotes s as a single quoted Jsonnet string.
+func quoteString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+
+	for _, r := range s {
+		switch r {
+		case '\'':
+			sb.WriteString(`\'`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+
+	sb.WriteByte('\'')
+	return sb.String()
+}
+
+// sourceText returns the text in source covered by a location range.
+func sourceText(source string, loc ast.LocationRange) string {
+	return source[sourceOffset(source, loc.Begin):sourceOffset(source, loc.End)]
+}
+
+// sourceOffset converts a location to a byte offset in source.
+func sourceOffset(source string, loc ast.Location) int {
+	offset := 0
+	for line := 1; line < loc.Line; line++ {
+		i := strings.IndexByte(source[offset:], '\n')
+		if i == -1 {
+			return len(source)
+		}
+		offset += i + 1
+	}
+
+	offset += loc.Column - 1
+	if offset > len(source) {
+		return len(source)
+	}
+
+	return offset
+}
+
+func rangeBetween(begin, end ast.Location) jpos.Range {
+	return jpos.NewRange(jpos.FromJsonnetLocation(begin), jpos.FromJsonnetLocation(end))
+}
@@ -0,0 +1,351 @@
+package token
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/google/go-jsonnet/ast"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+)
+
+var (
+	reIdentifier = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+)
+
+// Document is Jsonnet source and the path it belongs to.
+type Document struct {
+	Path   string
+	Source string
+}
+
+// PrepareRename checks if the item at a position can be renamed. It
+// returns the range of the item and its current name.
+func PrepareRename(filename, source string, pos jpos.Position, libPaths []string) (jpos.Range, string, error) {
+	if err := checkRenameToken(filename, source, pos); err != nil {
+		return jpos.Range{}, "", err
+	}
+
+	r := newResolver(libPaths)
+	node, err := r.addSource(filename, source)
+	if err != nil {
+		return jpos.Range{}, "", err
+	}
+
+	decl, name, err := r.renameTarget(node, pos)
+	if err != nil {
+		return jpos.Range{}, "", err
+	}
+
+	for _, loc := range r.references(decl, name) {
+		if loc.FileName == filename && pos.IsInJsonnetRange(loc) {
+			return jpos.FromJsonnetRange(loc), name, nil
+		}
+	}
+
+	return jpos.Range{}, "", errors.Errorf("unable to find %q at %s", name, pos.String())
+}
+
+// Rename returns the locations that need to be updated when renaming
+// the item at a position. Other documents that reference the item, e.g.
+// files importing filename, are searched as well.
+func Rename(ctx context.Context, doc Document, pos jpos.Position, newName string, libPaths []string, documents []Document) ([]jpos.Location, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "rename")
+	defer span.Finish()
+
+	if !isValidIdentifier(newName) {
+		return nil, errors.Errorf("%q is not a valid identifier", newName)
+	}
+
+	if err := checkRenameToken(doc.Path, doc.Source, pos); err != nil {
+		return nil, err
+	}
+
+	r := newResolver(libPaths)
+	node, err := r.addSource(doc.Path, doc.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	decl, name, err := r.renameTarget(node, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, other := range documents {
+		if _, ok := r.files[other.Path]; ok {
+			continue
+		}
+
+		// only documents that mention the name can reference it.
+		if !strings.Contains(other.Source, name) {
+			continue
+		}
+
+		if _, err := r.addSource(other.Path, other.Source); err != nil {
+			// documents that don't parse can't be updated.
+			continue
+		}
+	}
+
+	var locations []jpos.Location
+	for _, loc := range r.references(decl, name) {
+		locations = append(locations, jpos.LocationFromJsonnet(loc))
+	}
+
+	return locations, nil
+}
+
+// renameTarget finds the declaration of the item at pos and its name.
+func (r *resolver) renameTarget(node ast.Node, pos jpos.Position) (ast.LocationRange, string, error) {
+	found, err := locateNode(node, pos)
+	if err != nil {
+		return ast.LocationRange{}, "", err
+	}
+
+	switch n := found.(type) {
+	case *ast.Var:
+		if n.Id == "std" {
+			return ast.LocationRange{}, "", errors.New("std can't be renamed")
+		}
+	case *ast.Index:
+		if _, ok := literalIndex(n.Index); !ok {
+			return ast.LocationRange{}, "", errors.New("computed indexes can't be renamed")
+		}
+	case *ast.DesugaredObject:
+		for k, loc := range n.FieldLocs {
+			if _, ok := k.(string); !ok && pos.IsInJsonnetRange(loc) {
+				return ast.LocationRange{}, "", errors.New("computed field names can't be renamed")
+			}
+		}
+	}
+
+	decl, err := r.definition(found, pos)
+	if err != nil {
+		return ast.LocationRange{}, "", errors.New("nothing to rename at this position")
+	}
+
+	name, err := r.declarationName(decl)
+	if err != nil {
+		return ast.LocationRange{}, "", err
+	}
+
+	return decl, name, nil
+}
+
+// declarationName finds the name declared at decl.
+func (r *resolver) declarationName(decl ast.LocationRange) (string, error) {
+	for n := range r.parents {
+		switch n := n.(type) {
+		case *ast.Local:
+			for _, bind := range n.Binds {
+				if sameLocation(bind.VarLoc, decl) {
+					return string(bind.Variable), nil
+				}
+			}
+		case *ast.Function:
+			for _, id := range n.Parameters.Required {
+				if sameLocation(n.Parameters.RequiredLocs[id], decl) {
+					return string(id), nil
+				}
+			}
+			for _, param := range n.Parameters.Optional {
+				if sameLocation(param.Loc, decl) {
+					return string(param.Name), nil
+				}
+			}
+		case *ast.DesugaredObject:
+			for k, loc := range n.FieldLocs {
+				name, ok := k.(string)
+				if ok && sameLocation(loc, decl) {
+					return name, nil
+				}
+			}
+		}
+	}
+
+	return "", errors.New("unable to find declaration")
+}
+
+// references finds the ranges of every use of the item declared at decl
+// in the files known to the resolver. The declaration is included.
+// nolint: gocyclo
+func (r *resolver) references(decl ast.LocationRange, name string) []ast.LocationRange {
+	seen := make(map[string]ast.LocationRange)
+	add := func(loc ast.LocationRange, last bool) {
+		nameLoc, ok := r.nameIn(loc, name, last)
+		if !ok {
+			return
+		}
+		seen[nameLoc.String()] = nameLoc
+	}
+
+	for n := range r.parents {
+		switch n := n.(type) {
+		case *ast.Var:
+			if string(n.Id) != name {
+				continue
+			}
+			b, err := r.lookup(n)
+			if err == nil && sameLocation(b.loc, decl) {
+				add(*n.Loc(), false)
+			}
+		case *ast.Index:
+			if index, ok := literalIndex(n.Index); !ok || index != name {
+				continue
+			}
+			f, err := r.field(n.Target, name)
+			if err == nil && sameLocation(f.loc, decl) {
+				add(*n.Loc(), true)
+			}
+		case *ast.SuperIndex:
+			if index, ok := literalIndex(n.Index); !ok || index != name {
+				continue
+			}
+			f, err := r.superField(n, name)
+			if err == nil && sameLocation(f.loc, decl) {
+				add(*n.Loc(), true)
+			}
+		case *ast.Local:
+			for _, bind := range n.Binds {
+				if sameLocation(bind.VarLoc, decl) {
+					add(bind.VarLoc, false)
+				}
+			}
+		case *ast.Function:
+			for _, id := range n.Parameters.Required {
+				if loc := n.Parameters.RequiredLocs[id]; sameLocation(loc, decl) {
+					add(loc, false)
+				}
+			}
+			for _, param := range n.Parameters.Optional {
+				if sameLocation(param.Loc, decl) {
+					add(param.Loc, false)
+				}
+			}
+		case *ast.DesugaredObject:
+			loc, ok := n.FieldLocs[name]
+			if !ok {
+				continue
+			}
+			if sameLocation(loc, decl) {
+				add(loc, false)
+				continue
+			}
+
+			// fields overriding the declaration through a mixin are
+			// references as well.
+			if super, ok := r.supers[n]; ok {
+				f, err := r.field(super, name)
+				if err == nil && sameLocation(f.loc, decl) {
+					add(loc, false)
+				}
+			}
+		}
+	}
+
+	var keys []string
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var locations []ast.LocationRange
+	for _, k := range keys {
+		locations = append(locations, seen[k])
+	}
+
+	return locations
+}
+
+// nameIn finds name within loc using the source. If last is true, the
+// last occurrence is used.
+func (r *resolver) nameIn(loc ast.LocationRange, name string, last bool) (ast.LocationRange, bool) {
+	source, ok := r.sources[loc.FileName]
+	if !ok {
+		return ast.LocationRange{}, false
+	}
+
+	lines := strings.Split(source, "\n")
+
+	line, start, end := loc.Begin.Line, loc.Begin.Column-1, -1
+	if loc.End.Line == loc.Begin.Line {
+		end = loc.End.Column - 1
+	}
+	if last && loc.End.Line != loc.Begin.Line {
+		line, start, end = loc.End.Line, 0, loc.End.Column-1
+	}
+
+	if line < 1 || line > len(lines) {
+		return ast.LocationRange{}, false
+	}
+
+	text := lines[line-1]
+	if end < 0 || end > len(text) {
+		end = len(text)
+	}
+	if start < 0 || start > end {
+		return ast.LocationRange{}, false
+	}
+
+	text = text[start:end]
+
+	i := strings.Index(text, name)
+	if last {
+		i = strings.LastIndex(text, name)
+	}
+	if i < 0 {
+		return ast.LocationRange{}, false
+	}
+
+	begin := start + i + 1
+	return ast.LocationRange{
+		FileName: loc.FileName,
+		Begin:    ast.Location{Line: line, Column: begin},
+		End:      ast.Location{Line: line, Column: begin + len(name)},
+	}, true
+}
+
+// checkRenameToken rejects positions that are over keywords.
+func checkRenameToken(filename, source string, pos jpos.Position) error {
+	tokens, err := Lex(filename, source)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tokens {
+		if !pos.IsInJsonnetRange(t.Loc) {
+			continue
+		}
+
+		if t.Kind >= TokenAssert && t.Kind <= TokenTrue {
+			return errors.Errorf("keyword %s can't be renamed", t.Kind.String())
+		}
+		if t.Kind == TokenDollar {
+			return errors.New("$ can't be renamed")
+		}
+	}
+
+	return nil
+}
+
+func isValidIdentifier(s string) bool {
+	if !reIdentifier.MatchString(s) {
+		return false
+	}
+
+	for _, keyword := range (&Scope{}).Keywords() {
+		if s == keyword {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameLocation(a, b ast.LocationRange) bool {
+	return a.FileName == b.FileName && a.Begin == b.Begin && a.End == b.End
+}
@@ -0,0 +1,159 @@
+package token
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareRename(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		pos      jpos.Position
+		expected jpos.Range
+		text     string
+		isErr    bool
+	}{
+		{
+			name:     "local",
+			source:   "local x=1; x",
+			pos:      jpos.New(1, 12),
+			expected: jpos.NewRangeFromCoords(1, 12, 1, 13),
+			text:     "x",
+		},
+		{
+			name:     "field",
+			source:   "local o={foo: 1}; o.foo",
+			pos:      jpos.New(1, 22),
+			expected: jpos.NewRangeFromCoords(1, 21, 1, 24),
+			text:     "foo",
+		},
+		{
+			name:   "keyword",
+			source: "local x=1; x",
+			pos:    jpos.New(1, 2),
+			isErr:  true,
+		},
+		{
+			name:   "std",
+			source: "std.length([])",
+			pos:    jpos.New(1, 2),
+			isErr:  true,
+		},
+		{
+			name:   "computed field name",
+			source: "local k='a'; {[k]: 1}",
+			pos:    jpos.New(1, 15),
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, text, err := PrepareRename("file.jsonnet", tc.source, tc.pos, nil)
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, r)
+			assert.Equal(t, tc.text, text)
+		})
+	}
+}
+
+func TestRename(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "definition"))
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "file.jsonnet")
+	lib := filepath.Join(dir, "lib", "foo.libsonnet")
+	libPaths := []string{filepath.Join(dir, "lib")}
+
+	cases := []struct {
+		name      string
+		source    string
+		pos       jpos.Position
+		documents []Document
+		expected  []jpos.Location
+		isErr     bool
+	}{
+		{
+			name:   "local",
+			source: "local x=1; x + x",
+			pos:    jpos.New(1, 7),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 12, 1, 13)),
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 16, 1, 17)),
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 7, 1, 8)),
+			},
+		},
+		{
+			name:   "shadowed local",
+			source: "local x=1; local f(x)=x; x",
+			pos:    jpos.New(1, 26),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 26, 1, 27)),
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 7, 1, 8)),
+			},
+		},
+		{
+			name:   "object field",
+			source: "local o={a: 1, b: self.a}; o.a",
+			pos:    jpos.New(1, 30),
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 10, 1, 11)),
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 24, 1, 25)),
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 30, 1, 31)),
+			},
+		},
+		{
+			name:   "field in import",
+			source: `local x = import "foo.libsonnet"; x.service`,
+			pos:    jpos.New(1, 38),
+			documents: []Document{
+				{
+					Path:   filepath.Join(dir, "other.jsonnet"),
+					Source: `(import "foo.libsonnet").service.port`,
+				},
+			},
+			expected: []jpos.Location{
+				jpos.NewLocation(file, jpos.NewRangeFromCoords(1, 37, 1, 44)),
+				jpos.NewLocation(lib, jpos.NewRangeFromCoords(3, 3, 3, 10)),
+				jpos.NewLocation(filepath.Join(dir, "other.jsonnet"), jpos.NewRangeFromCoords(1, 26, 1, 33)),
+			},
+		},
+		{
+			name:   "invalid name",
+			source: "local x=1; x",
+			pos:    jpos.New(1, 7),
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			newName := "renamed"
+			if tc.isErr {
+				newName = "local"
+			}
+
+			doc := Document{Path: file, Source: tc.source}
+			ctx := context.Background()
+			got, err := Rename(ctx, doc, tc.pos, newName, libPaths, tc.documents)
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
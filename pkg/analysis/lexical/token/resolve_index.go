@@ -39,3 +39,21 @@ func resolveIndex(i *ast.Index) []string {
 
 	return path
 }
+
+// resolvableIndex returns true if the index can be resolved to a path,
+// i.e. its innermost target is a variable or self.
+func resolvableIndex(i *ast.Index) bool {
+	var cur ast.Node = i
+	for {
+		switch c := cur.(type) {
+		case *ast.Apply:
+			cur = c.Target
+		case *ast.Index:
+			cur = c.Target
+		case *ast.Self, *ast.Var:
+			return true
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package token
+
+import (
+	"io/ioutil"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/astext"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxResolveDepth limits how many hops a resolver will take while
+	// following a value. It guards against self referencing locals.
+	maxResolveDepth = 100
+)
+
+// binding is a declaration of an identifier.
+type binding struct {
+	id    ast.Identifier
+	loc   ast.LocationRange
+	body  ast.Node
+	param bool
+}
+
+// environment is the set of bindings visible at a node.
+type environment struct {
+	parent   *environment
+	bindings map[ast.Identifier]*binding
+	self     ast.Node
+	object   *ast.DesugaredObject
+}
+
+func newEnvironment(parent *environment) *environment {
+	e := &environment{
+		parent:   parent,
+		bindings: make(map[ast.Identifier]*binding),
+	}
+
+	if parent != nil {
+		e.self = parent.self
+		e.object = parent.object
+	}
+
+	return e
+}
+
+func (e *environment) declare(b *binding) {
+	e.bindings[b.id] = b
+}
+
+func (e *environment) lookup(id ast.Identifier) (*binding, bool) {
+	for cur := e; cur != nil; cur = cur.parent {
+		if b, ok := cur.bindings[id]; ok {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+// resolvedField is an object field found by the resolver.
+type resolvedField struct {
+	name   string
+	object *ast.DesugaredObject
+	field  ast.DesugaredObjectField
+	loc    ast.LocationRange
+}
+
+// resolver statically follows identifiers, indexes and imports to the
+// nodes they refer to. Imported files are loaded from disk, or from the
+// node cache if they can't be found.
+type resolver struct {
+	libPaths  []string
+	nodeCache *NodeCache
+	envs      map[ast.Node]*environment
+	parents   map[ast.Node]ast.Node
+	supers    map[*ast.DesugaredObject]ast.Node
+	selves    map[*ast.DesugaredObject]ast.Node
+	files     map[string]ast.Node
+	sources   map[string]string
+	inferrer  *typeInferrer
+}
+
+func newResolver(libPaths []string) *resolver {
+	return &resolver{
+		libPaths: libPaths,
+		envs:     make(map[ast.Node]*environment),
+		parents:  make(map[ast.Node]ast.Node),
+		supers:   make(map[*ast.DesugaredObject]ast.Node),
+		selves:   make(map[*ast.DesugaredObject]ast.Node),
+		files:    make(map[string]ast.Node),
+		sources:  make(map[string]string),
+	}
+}
+
+// addSource parses source and registers it with the resolver.
+func (r *resolver) addSource(filename, source string) (ast.Node, error) {
+	node, err := ReadSource(filename, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r.sources[filename] = source
+	r.addNode(filename, node)
+
+	return node, nil
+}
+
+// addNode registers a desugared node with the resolver.
+func (r *resolver) addNode(filename string, node ast.Node) {
+	r.files[filename] = node
+	r.visit(nil, node, newEnvironment(nil))
+}
+
+// nolint: gocyclo
+func (r *resolver) visit(parent, n ast.Node, env *environment) {
+	if n == nil {
+		return
+	}
+
+	r.envs[n] = env
+	r.parents[n] = parent
+
+	switch n := n.(type) {
+	case *ast.Apply:
+		r.visit(n, n.Target, env)
+		for _, arg := range n.Arguments.Positional {
+			r.visit(n, arg, env)
+		}
+		for _, arg := range n.Arguments.Named {
+			r.visit(n, arg.Arg, env)
+		}
+	case *ast.Array:
+		for _, elem := range n.Elements {
+			r.visit(n, elem, env)
+		}
+	case *ast.Binary:
+		if n.Op == ast.BopPlus {
+			if right, ok := n.Right.(*ast.DesugaredObject); ok {
+				r.supers[right] = n.Left
+				r.selves[right] = n
+			}
+			if left, ok := n.Left.(*ast.DesugaredObject); ok {
+				r.selves[left] = n
+			}
+		}
+		r.visit(n, n.Left, env)
+		r.visit(n, n.Right, env)
+	case *ast.Conditional:
+		r.visit(n, n.Cond, env)
+		r.visit(n, n.BranchTrue, env)
+		r.visit(n, n.BranchFalse, env)
+	case *ast.DesugaredObject:
+		objectEnv := newEnvironment(env)
+		objectEnv.object = n
+		objectEnv.self = n
+		if self, ok := r.selves[n]; ok {
+			objectEnv.self = self
+		}
+
+		for _, field := range n.Fields {
+			// field names are evaluated outside of the object.
+			r.visit(n, field.Name, env)
+			r.visit(n, field.Body, objectEnv)
+		}
+		for _, assert := range n.Asserts {
+			r.visit(n, assert, objectEnv)
+		}
+	case *ast.Error:
+		r.visit(n, n.Expr, env)
+	case *ast.Function:
+		fnEnv := newEnvironment(env)
+		for _, param := range n.Parameters.Required {
+			fnEnv.declare(&binding{
+				id:    param,
+				loc:   n.Parameters.RequiredLocs[param],
+				param: true,
+			})
+		}
+		for _, param := range n.Parameters.Optional {
+			fnEnv.declare(&binding{
+				id:    param.Name,
+				loc:   param.Loc,
+				body:  param.DefaultArg,
+				param: true,
+			})
+		}
+		for _, param := range n.Parameters.Optional {
+			r.visit(n, param.DefaultArg, fnEnv)
+		}
+		r.visit(n, n.Body, fnEnv)
+	case *ast.Index:
+		r.visit(n, n.Target, env)
+		r.visit(n, n.Index, env)
+	case *ast.InSuper:
+		r.visit(n, n.Index, env)
+	case *ast.Local:
+		localEnv := newEnvironment(env)
+		for _, bind := range n.Binds {
+			localEnv.declare(&binding{
+				id:   bind.Variable,
+				loc:  bind.VarLoc,
+				body: bind.Body,
+			})
+		}
+		for _, bind := range n.Binds {
+			r.visit(n, bind.Body, localEnv)
+		}
+		r.visit(n, n.Body, localEnv)
+	case *ast.SuperIndex:
+		r.visit(n, n.Index, env)
+	case *ast.Unary:
+		r.visit(n, n.Expr, env)
+	case *astext.PartialIndex:
+		r.visit(n, n.Target, env)
+	}
+}
+
+// importFile loads, parses and registers an import relative to the file
+// that contains it.
+func (r *resolver) importFile(from, name string) (ast.Node, string, error) {
+	path, err := ResolveImport(from, name, r.libPaths)
+	if err != nil {
+		if r.nodeCache == nil {
+			return nil, "", err
+		}
+
+		if node, ok := r.files[name]; ok {
+			return node, name, nil
+		}
+
+		ne, cacheErr := r.nodeCache.Get(name)
+		if cacheErr != nil {
+			return nil, "", err
+		}
+
+		r.files[name] = ne.Node
+		r.visit(nil, ne.Node, newEnvironment(nil))
+
+		return ne.Node, name, nil
+	}
+
+	if node, ok := r.files[path]; ok {
+		return node, path, nil
+	}
+
+	/* #nosec */
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	node, err := r.addSource(path, string(source))
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "reading import %q", name)
+	}
+
+	return node, path, nil
+}
+
+// value follows a node until it reaches something that is not a
+// reference to another node.
+func (r *resolver) value(n ast.Node) (ast.Node, error) {
+	return r.valueDepth(n, 0)
+}
+
+// nolint: gocyclo
+func (r *resolver) valueDepth(n ast.Node, depth int) (ast.Node, error) {
+	if depth > maxResolveDepth {
+		return nil, errors.New("maximum resolve depth exceeded")
+	}
+	depth++
+
+	switch n := n.(type) {
+	case nil:
+		return nil, errors.New("unable to resolve a nil node")
+	case *ast.Local:
+		return r.valueDepth(n.Body, depth)
+	case *ast.Var:
+		b, err := r.lookup(n)
+		if err != nil {
+			return nil, err
+		}
+		if b.body == nil {
+			return nil, errors.Errorf("%q has no static value", string(n.Id))
+		}
+		return r.valueDepth(b.body, depth)
+	case *ast.Self:
+		env := r.envs[n]
+		if env == nil || env.self == nil {
+			return nil, errors.New("self used outside of an object")
+		}
+		return r.valueDepth(env.self, depth)
+	case *ast.SuperIndex:
+		name, ok := literalIndex(n.Index)
+		if !ok {
+			return nil, errors.New("super index is not a string")
+		}
+		f, err := r.superField(n, name)
+		if err != nil {
+			return nil, err
+		}
+		return r.valueDepth(f.field.Body, depth)
+	case *ast.Index:
+		name, ok := literalIndex(n.Index)
+		if !ok {
+			return nil, errors.New("index is not a string")
+		}
+		f, err := r.fieldDepth(n.Target, name, depth)
+		if err != nil {
+			return nil, err
+		}
+		return r.valueDepth(f.field.Body, depth)
+	case *ast.Import:
+		node, _, err := r.importFile(n.Loc().FileName, n.File.Value)
+		if err != nil {
+			return nil, err
+		}
+		return r.valueDepth(node, depth)
+	case *ast.Apply:
+		target, err := r.valueDepth(n.Target, depth)
+		if err != nil {
+			return nil, err
+		}
+		fn, ok := target.(*ast.Function)
+		if !ok {
+			return nil, errors.Errorf("unable to apply a %T", target)
+		}
+		return r.valueDepth(fn.Body, depth)
+	default:
+		return n, nil
+	}
+}
+
+// lookup finds the binding for a variable.
+func (r *resolver) lookup(v *ast.Var) (*binding, error) {
+	env := r.envs[v]
+	if env == nil {
+		return nil, errors.Errorf("no scope for %q", string(v.Id))
+	}
+
+	b, ok := env.lookup(v.Id)
+	if !ok {
+		return nil, errors.Errorf("%q is not declared", string(v.Id))
+	}
+
+	return b, nil
+}
+
+// field finds a field named name in the object n resolves to.
+func (r *resolver) field(n ast.Node, name string) (*resolvedField, error) {
+	return r.fieldDepth(n, name, 0)
+}
+
+func (r *resolver) fieldDepth(n ast.Node, name string, depth int) (*resolvedField, error) {
+	if depth > maxResolveDepth {
+		return nil, errors.New("maximum resolve depth exceeded")
+	}
+	depth++
+
+	v, err := r.valueDepth(n, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case *ast.DesugaredObject:
+		for _, field := range v.Fields {
+			fieldName, err := fieldName(field)
+			if err != nil || fieldName != name {
+				continue
+			}
+
+			return &resolvedField{
+				name:   name,
+				object: v,
+				field:  field,
+				loc:    v.FieldLocs[name],
+			}, nil
+		}
+	case *ast.Binary:
+		if v.Op != ast.BopPlus {
+			break
+		}
+
+		// fields on the right override fields on the left.
+		if f, err := r.fieldDepth(v.Right, name, depth); err == nil {
+			return f, nil
+		}
+		return r.fieldDepth(v.Left, name, depth)
+	}
+
+	return nil, errors.Errorf("unable to find field %q in %T", name, v)
+}
+
+// superField finds a field using the super object of the object
+// enclosing n.
+func (r *resolver) superField(n ast.Node, name string) (*resolvedField, error) {
+	env := r.envs[n]
+	if env == nil || env.object == nil {
+		return nil, errors.New("super used outside of an object")
+	}
+
+	super, ok := r.supers[env.object]
+	if !ok {
+		return nil, errors.New("object does not have a super object")
+	}
+
+	return r.field(super, name)
+}
+
+// resolveImportLoc returns the location of an imported file.
+func (r *resolver) resolveImportLoc(from, name string) (ast.LocationRange, error) {
+	path, err := ResolveImport(from, name, r.libPaths)
+	if err != nil {
+		return ast.LocationRange{}, err
+	}
+
+	return ast.LocationRange{
+		FileName: path,
+		Begin:    ast.Location{Line: 1, Column: 1},
+		End:      ast.Location{Line: 1, Column: 1},
+	}, nil
+}
+
+func literalIndex(n ast.Node) (string, bool) {
+	ls, ok := n.(*ast.LiteralString)
+	if !ok {
+		return "", false
+	}
+
+	return ls.Value, true
+}
@@ -1,6 +1,7 @@
 package token
 
 import (
+	"io/ioutil"
 	"sort"
 	"strings"
 
@@ -15,19 +16,37 @@ import (
 type ScopeEntry struct {
 	Detail        string
 	Documentation string
-	Node          ast.Node
+	// Doc is the parsed doc comment of the entry's declaration.
+	Doc  Doc
+	Node ast.Node
 }
 
 // Scope is scope.
 type Scope struct {
 	nodeCache *NodeCache
 	store     map[string]ScopeEntry
+	docs      docIndex
+	// comments are the doc comments of files declaring fields found in
+	// the scope, e.g. imported libraries.
+	comments map[string]*docComments
 }
 
 func newScope(nc *NodeCache) *Scope {
 	return &Scope{
 		store:     make(map[string]ScopeEntry),
 		nodeCache: nc,
+		docs:      docIndex{},
+		comments:  make(map[string]*docComments),
+	}
+}
+
+// setSource sets the source the scope was created from. Doc comments of
+// fields declared in the source are found in it rather than on disk.
+func (sm *Scope) setSource(filename, source string, node ast.Node) {
+	sm.docs = sourceDocIndex(filename, source, node)
+
+	if tokens, err := Lex(filename, source); err == nil {
+		sm.comments[filename] = newDocComments(tokens)
 	}
 }
 
@@ -74,23 +93,20 @@ func (sm *Scope) GetInPath(path []string) (*ScopeEntry, error) {
 		return e, nil
 	}
 
-	node, err := sm.findInPath(e.Node, path)
+	node, decl, err := sm.findInPath(e.Node, path)
 	if err != nil {
 		return nil, err
 	}
 
-	text := astext.TokenName(node)
-
-	return &ScopeEntry{
-		Node:   node,
-		Detail: text,
-	}, nil
+	return sm.fieldEntry(astext.TokenName(node), node, decl), nil
 }
 
-func findInObject(node ast.Node, path []string) (ast.Node, error) {
+// findInObject finds the value of the field at path in an object. It
+// also returns the location of the field's name.
+func findInObject(node ast.Node, path []string) (ast.Node, ast.LocationRange, error) {
 	o, ok := node.(*ast.Object)
 	if !ok {
-		return nil, errors.Errorf("not a regular object: %T", node)
+		return nil, ast.LocationRange{}, errors.Errorf("not a regular object: %T", node)
 	}
 
 	id, path := path[0], path[1:]
@@ -104,12 +120,12 @@ func findInObject(node ast.Node, path []string) (ast.Node, error) {
 		switch field.Kind {
 		case ast.ObjectFieldID:
 			if field.Id == nil {
-				return nil, errors.New("field id shouldn't be nil")
+				return nil, ast.LocationRange{}, errors.New("field id shouldn't be nil")
 			}
 			name = string(*field.Id)
 		case ast.ObjectFieldStr:
 			if field.Expr1 == nil {
-				return nil, errors.New("field id should be a string")
+				return nil, ast.LocationRange{}, errors.New("field id should be a string")
 			}
 			name = astext.TokenValue(field.Expr1)
 		}
@@ -121,21 +137,23 @@ func findInObject(node ast.Node, path []string) (ast.Node, error) {
 		}
 
 		if len(path) == 0 {
-			return field.Expr2, nil
+			return field.Expr2, o.FieldLocs[name], nil
 		}
 
 		return findInObject(field.Expr2, path)
 	}
 
-	return nil, errors.Errorf("unable to find field %q in [%s]",
+	return nil, ast.LocationRange{}, errors.Errorf("unable to find field %q in [%s]",
 		id, strings.Join(fieldNames, ","))
 
 }
 
-func findInDesugaredObject(node ast.Node, path []string) (ast.Node, error) {
+// findInDesugaredObject finds the value of the field at path in a
+// desugared object. It also returns the location of the field's name.
+func findInDesugaredObject(node ast.Node, path []string) (ast.Node, ast.LocationRange, error) {
 	o, ok := node.(*ast.DesugaredObject)
 	if !ok {
-		return nil, errors.Errorf("not a desugared object: %T", node)
+		return nil, ast.LocationRange{}, errors.Errorf("not a desugared object: %T", node)
 	}
 
 	id, path := path[0], path[1:]
@@ -147,7 +165,7 @@ func findInDesugaredObject(node ast.Node, path []string) (ast.Node, error) {
 
 		name, ok := field.Name.(*ast.LiteralString)
 		if !ok {
-			return nil, errors.New("field name was not a string")
+			return nil, ast.LocationRange{}, errors.New("field name was not a string")
 		}
 
 		fieldNames = append(fieldNames, name.Value)
@@ -164,21 +182,21 @@ func findInDesugaredObject(node ast.Node, path []string) (ast.Node, error) {
 		case *ast.DesugaredObject:
 			body = n
 		default:
-			return n, nil
+			return n, o.FieldLocs[name.Value], nil
 		}
 
 		if len(path) == 0 {
-			return body, nil
+			return body, o.FieldLocs[name.Value], nil
 		}
 
 		return findInDesugaredObject(body, path)
 	}
 
-	return nil, errors.Errorf("desugared: unable to find field %q in [%s]",
+	return nil, ast.LocationRange{}, errors.Errorf("desugared: unable to find field %q in [%s]",
 		id, strings.Join(fieldNames, ","))
 }
 
-func (sm *Scope) findInPath(node ast.Node, path []string) (ast.Node, error) {
+func (sm *Scope) findInPath(node ast.Node, path []string) (ast.Node, ast.LocationRange, error) {
 	switch node := node.(type) {
 	case *ast.DesugaredObject:
 		return findInDesugaredObject(node, path)
@@ -188,13 +206,13 @@ func (sm *Scope) findInPath(node ast.Node, path []string) (ast.Node, error) {
 		indexPath := resolveIndex(node)
 		o, err := sm.Get(indexPath[0])
 		if err != nil {
-			return nil, err
+			return nil, ast.LocationRange{}, err
 		}
 
 		path = append(indexPath[1:], path...)
 		return sm.findInPath(o.Node, path)
 	default:
-		return nil, errors.Errorf("not an object %T: [%s]",
+		return nil, ast.LocationRange{}, errors.Errorf("not an object %T: [%s]",
 			node, strings.Join(path, ","))
 	}
 }
@@ -216,10 +234,62 @@ func (sm *Scope) Get(key string) (*ScopeEntry, error) {
 
 func (sm *Scope) add(key ast.Identifier, node ast.Node) {
 	id := string(key)
-	sm.store[id] = ScopeEntry{
-		Detail: id,
-		Node:   node,
+	sm.store[id] = *sm.entry(id, node)
+}
+
+// entry creates an entry for node with the docs of its declaration.
+func (sm *Scope) entry(detail string, node ast.Node) *ScopeEntry {
+	doc := sm.docs[node]
+
+	return &ScopeEntry{
+		Detail:        detail,
+		Documentation: doc.Text(),
+		Doc:           doc,
+		Node:          node,
+	}
+}
+
+// fieldEntry creates an entry for the value of a field whose name is
+// declared at decl. Values of fields declared in other files, e.g.
+// imported libraries, aren't in the scope's docs, so their docs are found
+// in the file declaring them.
+func (sm *Scope) fieldEntry(detail string, node ast.Node, decl ast.LocationRange) *ScopeEntry {
+	se := sm.entry(detail, node)
+	if se.Documentation != "" || decl.FileName == "" {
+		return se
+	}
+
+	dc, ok := sm.comments[decl.FileName]
+	if !ok {
+		dc = readDocComments(decl.FileName)
+		sm.comments[decl.FileName] = dc
+	}
+
+	if dc == nil {
+		return se
+	}
+
+	se.Doc = dc.doc(decl.Begin)
+	se.Documentation = se.Doc.Text()
+
+	return se
+}
+
+// readDocComments reads the doc comments of a file. It returns nil if the
+// file can't be read or lexed.
+func readDocComments(filename string) *docComments {
+	/* #nosec */
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil
 	}
+
+	tokens, err := Lex(filename, string(data))
+	if err != nil {
+		return nil
+	}
+
+	return newDocComments(tokens)
 }
 
 func ReadSource(filename, source string, ch chan<- ParseDiagnostic) (ast.Node, error) {
@@ -258,12 +328,34 @@ func LocationScope(filename, source string, loc jlspos.Position, nodeCache *Node
 	}
 
 	sm := newScope(nodeCache)
+	sm.setSource(filename, source, node)
 	sm.addEvalScope(es)
 
 	return sm, nil
 }
 
+// resolveStd resolves a path in the standard library using the stdlib
+// catalogue. Functions resolve to a function node with the function's
+// parameters.
 func resolveStd(path []string) (*ScopeEntry, error) {
-	return nil, errors.Errorf("resolveStd for %s not implemented",
-		strings.Join(path, "."))
+	if len(path) != 1 {
+		return nil, errors.Errorf("resolveStd for %s not implemented",
+			strings.Join(path, "."))
+	}
+
+	stdlib, err := LoadStdlib()
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := stdlib.Function(path[0])
+	if !ok {
+		return nil, errors.Errorf("std.%s is not a standard library function", path[0])
+	}
+
+	return &ScopeEntry{
+		Detail:        f.Signature(),
+		Documentation: f.Documentation,
+		Node:          f.Node(),
+	}, nil
 }
@@ -3,7 +3,9 @@ package token
 import (
 	"bytes"
 	"fmt"
+	"strings"
 
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/astext"
 	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
 	"github.com/google/go-jsonnet/ast"
 	"github.com/ksonnet/ksonnet-lib/ksonnet-gen/printer"
@@ -12,8 +14,28 @@ import (
 
 // SignatureResponse is the response from SignatureHelper.
 type SignatureResponse struct {
-	Label      string
-	Parameters []string
+	Label           string
+	Documentation   string
+	Parameters      []SignatureParameter
+	ActiveParameter int
+}
+
+// SignatureParameter is a parameter in a signature.
+type SignatureParameter struct {
+	Name string
+	// Default is the source of the default argument of an optional
+	// parameter.
+	Default       string
+	Documentation string
+}
+
+// Label is the label of the parameter in the signature label.
+func (sp SignatureParameter) Label() string {
+	if sp.Default == "" {
+		return sp.Name
+	}
+
+	return sp.Name + "=" + sp.Default
 }
 
 // SignatureHelper retrieves the signature for a function at a position.
@@ -28,9 +50,13 @@ func SignatureHelper(source string, pos jpos.Position, nodeCache *NodeCache) (*S
 		return nil, err
 	}
 
+	// the cursor can be in an argument of the call.
 	apply, ok := found.(*ast.Apply)
 	if !ok {
-		return nil, nil
+		apply = enclosingApply(node, pos)
+		if apply == nil {
+			return nil, nil
+		}
 	}
 
 	es, err := eval(node, apply, nodeCache)
@@ -39,25 +65,16 @@ func SignatureHelper(source string, pos jpos.Position, nodeCache *NodeCache) (*S
 	}
 
 	s := newScope(nodeCache)
+	s.setSource("snippet.jsonnet", source, node)
 	s.addEvalScope(es)
 
-	var se *ScopeEntry
-	var name string
-	switch n := apply.Target.(type) {
-	case *ast.Var:
-		name = string(n.Id)
-		se, err = s.Get(name)
-		if err != nil {
-			return nil, err
-		}
-	case *ast.Index:
-		path := resolveIndex(n)
-		se, err = s.GetInPath(path)
-		if err != nil {
-			return nil, err
-		}
+	se, name, err := resolveFunction(apply.Target, s, nodeCache)
+	if err != nil {
+		return nil, err
+	}
 
-		name = string(path[len(path)-1])
+	if se == nil {
+		return nil, nil
 	}
 
 	funNode, ok := se.Node.(*ast.Function)
@@ -65,48 +82,185 @@ func SignatureHelper(source string, pos jpos.Position, nodeCache *NodeCache) (*S
 		return nil, errors.New("node was not a function")
 	}
 
-	var label bytes.Buffer
-	fmt.Fprintf(&label, "%s(", name)
+	var params []SignatureParameter
 
-	var params []string
+	for _, p := range funNode.Parameters.Required {
+		params = append(params, SignatureParameter{
+			Name:          string(p),
+			Documentation: se.Doc.Param(string(p)),
+		})
+	}
 
-	required := funNode.Parameters.Required
-	for i, p := range required {
-		label.WriteString(string(p))
-		if i < len(required)-1 {
-			label.WriteString(", ")
+	for _, p := range funNode.Parameters.Optional {
+		var nodeBuf bytes.Buffer
+		if err = printer.Fprint(&nodeBuf, p.DefaultArg); err != nil {
+			return nil, err
 		}
 
-		params = append(params, string(p))
+		params = append(params, SignatureParameter{
+			Name:    string(p.Name),
+			Default: nodeBuf.String(),
+			Documentation: joinNonEmpty(" ", se.Doc.Param(string(p.Name)),
+				fmt.Sprintf("Defaults to %s.", nodeBuf.String())),
+		})
 	}
 
-	optional := funNode.Parameters.Optional
-	if len(optional) > 0 && len(required) > 0 {
-		label.WriteString(", ")
+	var labels []string
+	for _, p := range params {
+		labels = append(labels, p.Label())
 	}
 
-	for i, p := range optional {
-		var nodeBuf bytes.Buffer
-		if err = printer.Fprint(&nodeBuf, p.DefaultArg); err != nil {
-			return nil, err
+	documentation := se.Documentation
+	if !se.Doc.IsEmpty() {
+		// parameters are documented separately.
+		doc := se.Doc
+		doc.Params = nil
+		documentation = doc.Text()
+	}
+
+	sr := &SignatureResponse{
+		Label:           fmt.Sprintf("%s(%s)", name, strings.Join(labels, ", ")),
+		Documentation:   documentation,
+		Parameters:      params,
+		ActiveParameter: activeParameter(apply, params, pos),
+	}
+
+	return sr, nil
+}
+
+// resolveFunction resolves the target of a call. Targets are variables,
+// or fields of objects in variables or imported files. It returns a nil
+// entry if the target can't be resolved statically.
+func resolveFunction(target ast.Node, s *Scope, nodeCache *NodeCache) (*ScopeEntry, string, error) {
+	switch n := target.(type) {
+	case *ast.Var:
+		name := string(n.Id)
+		se, err := s.Get(name)
+		if err != nil {
+			return nil, "", err
 		}
 
-		fmt.Fprintf(&label, "%s=%s", string(p.Name), nodeBuf.String())
+		return se, name, nil
+	case *ast.Index:
+		var path []string
+		cur := ast.Node(n)
+		for {
+			idx, ok := cur.(*ast.Index)
+			if !ok {
+				break
+			}
 
-		if i < len(optional)-1 {
-			label.WriteString(", ")
+			name, ok := idx.Index.(*ast.LiteralString)
+			if !ok {
+				return nil, "", nil
+			}
+
+			path = append([]string{name.Value}, path...)
+			cur = idx.Target
 		}
 
-		params = append(params, string(p.Name))
+		name := path[len(path)-1]
+
+		switch base := cur.(type) {
+		case *ast.Var:
+			se, err := s.GetInPath(append([]string{string(base.Id)}, path...))
+			if err != nil {
+				return nil, "", err
+			}
+
+			return se, name, nil
+		case *ast.Import:
+			ne, err := nodeCache.Get(base.File.Value)
+			if err != nil {
+				return nil, "", err
+			}
 
+			found, decl, err := s.findInPath(ne.Node, path)
+			if err != nil {
+				return nil, "", err
+			}
+
+			return s.fieldEntry(astext.TokenName(found), found, decl), name, nil
+		}
 	}
 
-	label.WriteString(")")
+	return nil, "", nil
+}
 
-	sr := &SignatureResponse{
-		Label:      label.String(),
-		Parameters: params,
+// enclosingApply finds the innermost call whose arguments contain pos.
+func enclosingApply(node ast.Node, pos jpos.Position) *ast.Apply {
+	if node == nil || !pos.IsInJsonnetRange(*node.Loc()) {
+		return nil
 	}
 
-	return sr, nil
+	for _, child := range parsedChildren(node) {
+		if apply := enclosingApply(child, pos); apply != nil {
+			return apply
+		}
+	}
+
+	apply, ok := node.(*ast.Apply)
+	if !ok {
+		return nil
+	}
+
+	// the cursor is in the target, not the arguments.
+	end := apply.Target.Loc().End
+	loc := pos.ToJsonnet()
+	if loc.Line < end.Line || (loc.Line == end.Line && loc.Column <= end.Column) {
+		return nil
+	}
+
+	return apply
+}
+
+// activeParameter returns the index of the parameter of the argument at
+// pos. After named arguments, it is the first parameter which hasn't been
+// given an argument.
+func activeParameter(apply *ast.Apply, params []SignatureParameter, pos jpos.Position) int {
+	loc := pos.ToJsonnet()
+	before := func(l ast.Location) bool {
+		return l.Line < loc.Line || (l.Line == loc.Line && l.Column < loc.Column)
+	}
+
+	index := func(name ast.Identifier) int {
+		for i, p := range params {
+			if p.Name == string(name) {
+				return i
+			}
+		}
+		return len(params)
+	}
+
+	for _, arg := range apply.Arguments.Named {
+		if pos.IsInJsonnetRange(arg.Loc) || pos.IsInJsonnetRange(*arg.Arg.Loc()) {
+			return index(arg.Name)
+		}
+	}
+
+	active := 0
+	for _, arg := range apply.Arguments.Positional {
+		if before(arg.Loc().End) {
+			active++
+		}
+	}
+
+	given := make(map[int]bool)
+	for _, arg := range apply.Arguments.Named {
+		if before(arg.Arg.Loc().End) {
+			given[index(arg.Name)] = true
+		}
+	}
+
+	if len(given) == 0 {
+		return active
+	}
+
+	for i := active; i < len(params); i++ {
+		if !given[i] {
+			return i
+		}
+	}
+
+	return len(params)
 }
@@ -1,6 +1,9 @@
 package token
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
@@ -21,7 +24,7 @@ func TestSignatureHelper(t *testing.T) {
 			pos:    jpos.New(1, 21),
 			expected: &SignatureResponse{
 				Label:      "id(x)",
-				Parameters: []string{"x"},
+				Parameters: []SignatureParameter{{Name: "x"}},
 			},
 		},
 		{
@@ -30,7 +33,7 @@ func TestSignatureHelper(t *testing.T) {
 			pos:    jpos.New(1, 23),
 			expected: &SignatureResponse{
 				Label:      "id(x, y)",
-				Parameters: []string{"x", "y"},
+				Parameters: []SignatureParameter{{Name: "x"}, {Name: "y"}},
 			},
 		},
 		{
@@ -39,7 +42,7 @@ func TestSignatureHelper(t *testing.T) {
 			pos:    jpos.New(1, 23),
 			expected: &SignatureResponse{
 				Label:      "id(x=1)",
-				Parameters: []string{"x"},
+				Parameters: []SignatureParameter{optionalParameter("x", "1")},
 			},
 		},
 		{
@@ -48,7 +51,7 @@ func TestSignatureHelper(t *testing.T) {
 			pos:    jpos.New(1, 30),
 			expected: &SignatureResponse{
 				Label:      "id(x=1, y=1)",
-				Parameters: []string{"x", "y"},
+				Parameters: []SignatureParameter{optionalParameter("x", "1"), optionalParameter("y", "1")},
 			},
 		},
 		{
@@ -57,7 +60,7 @@ func TestSignatureHelper(t *testing.T) {
 			pos:    jpos.New(1, 27),
 			expected: &SignatureResponse{
 				Label:      "id(x, y=1)",
-				Parameters: []string{"x", "y"},
+				Parameters: []SignatureParameter{{Name: "x"}, optionalParameter("y", "1")},
 			},
 		},
 		{
@@ -66,14 +69,135 @@ func TestSignatureHelper(t *testing.T) {
 			pos:    jpos.New(1, 26),
 			expected: &SignatureResponse{
 				Label:      "id(x)",
-				Parameters: []string{"x"},
+				Parameters: []SignatureParameter{{Name: "x"}},
+			},
+		},
+		{
+			name:   "nested field",
+			source: "local o={a:{id(x, y)::x}}; o.a.id(1, )",
+			pos:    jpos.New(1, 38),
+			expected: &SignatureResponse{
+				Label:           "id(x, y)",
+				Parameters:      []SignatureParameter{{Name: "x"}, {Name: "y"}},
+				ActiveParameter: 1,
+			},
+		},
+		{
+			name:   "import",
+			source: "local l = import 'lib.libsonnet'; l.f()",
+			pos:    jpos.New(1, 39),
+			expected: &SignatureResponse{
+				Label:      "f(a, b)",
+				Parameters: []SignatureParameter{{Name: "a"}, {Name: "b"}},
+			},
+		},
+		{
+			name:   "import target",
+			source: "(import 'lib.libsonnet').f(1, 2)",
+			pos:    jpos.New(1, 31),
+			expected: &SignatureResponse{
+				Label:           "f(a, b)",
+				Parameters:      []SignatureParameter{{Name: "a"}, {Name: "b"}},
+				ActiveParameter: 1,
+			},
+		},
+		{
+			name:   "active parameter",
+			source: "local id(x,y) = x; id(1, 2)",
+			pos:    jpos.New(1, 26),
+			expected: &SignatureResponse{
+				Label:           "id(x, y)",
+				Parameters:      []SignatureParameter{{Name: "x"}, {Name: "y"}},
+				ActiveParameter: 1,
+			},
+		},
+		{
+			name:   "after comma",
+			source: "local id(x,y) = x; id(1, )",
+			pos:    jpos.New(1, 26),
+			expected: &SignatureResponse{
+				Label:           "id(x, y)",
+				Parameters:      []SignatureParameter{{Name: "x"}, {Name: "y"}},
+				ActiveParameter: 1,
+			},
+		},
+		{
+			name:   "in named argument",
+			source: "local f(a, b=1, c=2) = a; f(1, c=3)",
+			pos:    jpos.New(1, 35),
+			expected: &SignatureResponse{
+				Label:           "f(a, b=1, c=2)",
+				Parameters:      []SignatureParameter{{Name: "a"}, optionalParameter("b", "1"), optionalParameter("c", "2")},
+				ActiveParameter: 2,
+			},
+		},
+		{
+			name:   "after named argument",
+			source: "local f(a, b=1, c=2) = a; f(b=1, )",
+			pos:    jpos.New(1, 34),
+			expected: &SignatureResponse{
+				Label:           "f(a, b=1, c=2)",
+				Parameters:      []SignatureParameter{{Name: "a"}, optionalParameter("b", "1"), optionalParameter("c", "2")},
+				ActiveParameter: 0,
+			},
+		},
+		{
+			name:   "after positional and named arguments",
+			source: "local f(a, b=1, c=2) = a; f(1, b=1, )",
+			pos:    jpos.New(1, 37),
+			expected: &SignatureResponse{
+				Label:           "f(a, b=1, c=2)",
+				Parameters:      []SignatureParameter{{Name: "a"}, optionalParameter("b", "1"), optionalParameter("c", "2")},
+				ActiveParameter: 2,
+			},
+		},
+		{
+			name:   "stdlib",
+			source: "std.mapWithKey()",
+			pos:    jpos.New(1, 16),
+			expected: &SignatureResponse{
+				Label:         "mapWithKey(func, obj)",
+				Documentation: stdDocs["mapWithKey"],
+				Parameters:    []SignatureParameter{{Name: "func"}, {Name: "obj"}},
+			},
+		},
+		{
+			name:   "stdlib optional",
+			source: "std.sort([])",
+			pos:    jpos.New(1, 11),
+			expected: &SignatureResponse{
+				Label:         "sort(arr, keyF=id)",
+				Documentation: stdDocs["sort"],
+				Parameters:    []SignatureParameter{{Name: "arr"}, optionalParameter("keyF", "id")},
+			},
+		},
+		{
+			name:   "not in a call",
+			source: "local a = 1; a",
+			pos:    jpos.New(1, 14),
+		},
+		{
+			name:   "documented",
+			source: "// Picks a value.\n// @param x the value\n// @param y the fallback\n// @return x or y\nlocal pick(x, y=1) = x; pick()",
+			pos:    jpos.New(5, 30),
+			expected: &SignatureResponse{
+				Label:         "pick(x, y=1)",
+				Documentation: "Picks a value.\n\nReturns: x or y",
+				Parameters: []SignatureParameter{
+					{Name: "x", Documentation: "the value"},
+					{Name: "y", Default: "1", Documentation: "the fallback Defaults to 1."},
+				},
 			},
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			lib, err := ReadSource("lib.libsonnet", "{f(a, b):: a}", nil)
+			require.NoError(t, err)
+
 			nodeCache := NewNodeCache()
+			nodeCache.store["lib.libsonnet"] = NodeEntry{Node: lib}
 
 			sr, err := SignatureHelper(tc.source, tc.pos, nodeCache)
 			require.NoError(t, err)
@@ -82,3 +206,62 @@ func TestSignatureHelper(t *testing.T) {
 		})
 	}
 }
+
+func TestSignatureHelper_importedDocs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signature")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	libPath := filepath.Join(dir, "lib.libsonnet")
+	lib := "{\n  // Creates a container.\n  // @param name the container name\n  f(name, port=80):: name,\n}"
+	require.NoError(t, ioutil.WriteFile(libPath, []byte(lib), 0644))
+
+	libNode, err := ReadSource(libPath, lib, nil)
+	require.NoError(t, err)
+
+	nodeCache := NewNodeCache()
+	nodeCache.store["lib.libsonnet"] = NodeEntry{Node: libNode}
+
+	expected := &SignatureResponse{
+		Label:         "f(name, port=80)",
+		Documentation: "Creates a container.",
+		Parameters: []SignatureParameter{
+			{Name: "name", Documentation: "the container name"},
+			{Name: "port", Default: "80", Documentation: "Defaults to 80."},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		source string
+		pos    jpos.Position
+	}{
+		{
+			name:   "local",
+			source: "local l = import 'lib.libsonnet'; l.f()",
+			pos:    jpos.New(1, 39),
+		},
+		{
+			name:   "import",
+			source: "(import 'lib.libsonnet').f()",
+			pos:    jpos.New(1, 28),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sr, err := SignatureHelper(tc.source, tc.pos, nodeCache)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, sr)
+		})
+	}
+}
+
+func optionalParameter(name, defaultArg string) SignatureParameter {
+	return SignatureParameter{
+		Name:          name,
+		Default:       defaultArg,
+		Documentation: "Defaults to " + defaultArg + ".",
+	}
+}
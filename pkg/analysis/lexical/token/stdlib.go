@@ -0,0 +1,218 @@
+package token
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	rice "github.com/GeertJohan/go.rice"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/ksonnet/ksonnet-lib/ksonnet-gen/printer"
+	"github.com/pkg/errors"
+)
+
+// StdParameter is a parameter of a standard library function.
+type StdParameter struct {
+	Name string
+	// Default is the source of the default argument of an optional
+	// parameter.
+	Default string
+}
+
+func (p StdParameter) String() string {
+	if p.Default == "" {
+		return p.Name
+	}
+
+	return p.Name + "=" + p.Default
+}
+
+// StdFunction is a function in the standard library.
+type StdFunction struct {
+	Name          string
+	Parameters    []StdParameter
+	Documentation string
+	// Builtin is true if the function is implemented natively by the
+	// Jsonnet VM instead of in std.jsonnet.
+	Builtin bool
+}
+
+// Signature is the signature of the function, e.g. `std.sort(arr, keyF=id)`.
+func (f StdFunction) Signature() string {
+	var params []string
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+
+	return fmt.Sprintf("std.%s(%s)", f.Name, strings.Join(params, ", "))
+}
+
+// Node creates a function node with the function's parameters.
+func (f StdFunction) Node() *ast.Function {
+	fn := &ast.Function{
+		Body: &ast.LiteralNull{},
+	}
+
+	for _, p := range f.Parameters {
+		if p.Default == "" {
+			fn.Parameters.Required = append(fn.Parameters.Required, ast.Identifier(p.Name))
+			continue
+		}
+
+		fn.Parameters.Optional = append(fn.Parameters.Optional, ast.NamedParameter{
+			Name:       ast.Identifier(p.Name),
+			DefaultArg: &ast.Var{Id: ast.Identifier(p.Default)},
+		})
+	}
+
+	return fn
+}
+
+// stdBuiltins are the functions go-jsonnet implements natively and their
+// parameters. Builtins replace functions of the same name in
+// std.jsonnet.
+var stdBuiltins = map[string][]string{
+	"extVar":          {"x"},
+	"length":          {"x"},
+	"toString":        {"a"},
+	"makeArray":       {"sz", "func"},
+	"flatMap":         {"func", "arr"},
+	"join":            {"sep", "arr"},
+	"filter":          {"func", "arr"},
+	"range":           {"from", "to"},
+	"primitiveEquals": {"a", "b"},
+	"objectFieldsEx":  {"obj", "hidden"},
+	"objectHasEx":     {"obj", "fname", "hidden"},
+	"type":            {"x"},
+	"char":            {"x"},
+	"codepoint":       {"x"},
+	"ceil":            {"x"},
+	"floor":           {"x"},
+	"sqrt":            {"x"},
+	"sin":             {"x"},
+	"cos":             {"x"},
+	"tan":             {"x"},
+	"asin":            {"x"},
+	"acos":            {"x"},
+	"atan":            {"x"},
+	"log":             {"x"},
+	"exp":             {"x"},
+	"mantissa":        {"x"},
+	"exponent":        {"x"},
+	"pow":             {"base", "exp"},
+	"modulo":          {"x", "y"},
+	"md5":             {"x"},
+	"strReplace":      {"str", "from", "to"},
+	"native":          {"x"},
+}
+
+// Stdlib is a catalogue of the standard library functions.
+type Stdlib struct {
+	functions map[string]StdFunction
+}
+
+var (
+	stdlib     *Stdlib
+	stdlibErr  error
+	stdlibOnce sync.Once
+)
+
+// LoadStdlib loads the standard library catalogue. It merges the
+// functions in std.jsonnet with the builtins of the Jsonnet VM.
+func LoadStdlib() (*Stdlib, error) {
+	stdlibOnce.Do(func() {
+		stdlib, stdlibErr = loadStdlibCatalogue()
+	})
+
+	return stdlib, stdlibErr
+}
+
+func loadStdlibCatalogue() (*Stdlib, error) {
+	box, err := rice.FindBox("ext")
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := box.String("std.jsonnet")
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := Parse("std.jsonnet", source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, ok := node.(*ast.Object)
+	if !ok {
+		return nil, errors.Errorf("std.jsonnet is a %T, not an object", node)
+	}
+
+	s := &Stdlib{
+		functions: make(map[string]StdFunction),
+	}
+
+	for _, field := range obj.Fields {
+		if field.Kind != ast.ObjectFieldID || field.Id == nil || !field.MethodSugar || field.Params == nil {
+			continue
+		}
+
+		f := StdFunction{
+			Name:          string(*field.Id),
+			Documentation: stdDocs[string(*field.Id)],
+		}
+
+		for _, p := range field.Params.Required {
+			f.Parameters = append(f.Parameters, StdParameter{Name: string(p)})
+		}
+
+		for _, p := range field.Params.Optional {
+			var buf bytes.Buffer
+			if err := printer.Fprint(&buf, p.DefaultArg); err != nil {
+				return nil, err
+			}
+
+			f.Parameters = append(f.Parameters, StdParameter{Name: string(p.Name), Default: buf.String()})
+		}
+
+		s.functions[f.Name] = f
+	}
+
+	for name, params := range stdBuiltins {
+		f := StdFunction{
+			Name:          name,
+			Documentation: stdDocs[name],
+			Builtin:       true,
+		}
+
+		for _, p := range params {
+			f.Parameters = append(f.Parameters, StdParameter{Name: p})
+		}
+
+		s.functions[name] = f
+	}
+
+	return s, nil
+}
+
+// Function returns the function with a name.
+func (s *Stdlib) Function(name string) (StdFunction, bool) {
+	f, ok := s.functions[name]
+	return f, ok
+}
+
+// Functions returns the functions sorted by name.
+func (s *Stdlib) Functions() []StdFunction {
+	var functions []StdFunction
+	for _, f := range s.functions {
+		functions = append(functions, f)
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+
+	return functions
+}
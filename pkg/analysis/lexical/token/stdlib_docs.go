@@ -0,0 +1,122 @@
+package token
+
+// stdDocs is the reference documentation for the standard library
+// functions.
+var stdDocs = map[string]string{
+	// external variables
+	"extVar": "If an external variable with the given name was defined, return its string value. Otherwise, raise an error.",
+
+	// types and reflection
+	"type":            "Return a string that indicates the type of the value. The possible return values are: \"array\", \"boolean\", \"function\", \"null\", \"number\", \"object\", and \"string\".",
+	"length":          "Depending on the type of the value given, either returns the number of elements in the array, the number of codepoints in the string, the number of parameters in the function, or the number of fields in the object. Raises an error if given a primitive value, i.e. null, true or false.",
+	"objectHas":       "Returns true if the given object has the field (given as a string), otherwise false. Raises an error if the arguments are not object and string respectively. Returns false if the field is hidden.",
+	"objectFields":    "Returns an array of strings, each element being a field from the given object. Does not include hidden fields.",
+	"objectHasAll":    "As std.objectHas but also includes hidden fields.",
+	"objectFieldsAll": "As std.objectFields but also includes hidden fields.",
+	"objectHasEx":     "Returns true if the given object has the field, including hidden fields if hidden is true.",
+	"objectFieldsEx":  "Returns the fields of the given object, including hidden fields if hidden is true.",
+	"prune":           "Recursively remove all \"empty\" members of a. \"Empty\" is defined as zero length arrays, zero length objects, or null values. The argument a may have any type.",
+	"mapWithKey":      "Apply the given function to all fields of the given object, also passing the field name. The function func is expected to take the field name as the first parameter and the field value as the second.",
+	"isArray":         "Returns true if the value is an array.",
+	"isBoolean":       "Returns true if the value is a boolean.",
+	"isFunction":      "Returns true if the value is a function.",
+	"isNumber":        "Returns true if the value is a number.",
+	"isObject":        "Returns true if the value is an object.",
+	"isString":        "Returns true if the value is a string.",
+	"equals":          "Returns true if a and b are equal. Arrays and objects are compared deeply.",
+	"primitiveEquals": "Returns true if the primitive values a and b are equal.",
+
+	// mathematical utilities
+	"abs":      "Returns the absolute value of n.",
+	"sign":     "Returns 1 if n is positive, -1 if n is negative and 0 if n is 0.",
+	"max":      "Returns the maximum of a and b.",
+	"min":      "Returns the minimum of a and b.",
+	"pow":      "Returns base raised to the power of exp.",
+	"exp":      "Returns e raised to the power of x.",
+	"log":      "Returns the natural logarithm of x.",
+	"exponent": "Returns the exponent of the number x when it is represented as a mantissa multiplied by a power of 2.",
+	"mantissa": "Returns the mantissa of the number x when it is represented as a mantissa multiplied by a power of 2.",
+	"floor":    "Returns the largest integer not greater than x.",
+	"ceil":     "Returns the smallest integer not less than x.",
+	"sqrt":     "Returns the square root of x.",
+	"sin":      "Returns the sine of x in radians.",
+	"cos":      "Returns the cosine of x in radians.",
+	"tan":      "Returns the tangent of x in radians.",
+	"asin":     "Returns the arcsine of x in radians.",
+	"acos":     "Returns the arccosine of x in radians.",
+	"atan":     "Returns the arctangent of x in radians.",
+	"mod":      "Returns a % b. If a is a string, it is formatted with b as in std.format.",
+	"modulo":   "Returns the floating point remainder of x divided by y.",
+
+	// assertions and debugging
+	"assertEqual": "Ensure that a == b. Returns true or throws an error message.",
+
+	// string manipulation
+	"toString":            "Convert the given argument to a string.",
+	"codepoint":           "Returns the positive integer representing the unicode codepoint of the character in the given single-character string. This function is the inverse of std.char.",
+	"char":                "Returns a string of length one whose only unicode codepoint has integer id x. This function is the inverse of std.codepoint.",
+	"substr":              "Returns a string that is the part of str that starts at offset from and is len codepoints long. If the string str is shorter than from+len, the suffix starting at position from will be returned.",
+	"startsWith":          "Returns whether the string a is prefixed by the string b.",
+	"endsWith":            "Returns whether the string a is suffixed by the string b.",
+	"split":               "Split the string str into an array of strings, divided by the single character c.",
+	"splitLimit":          "As std.split(str, c) but will stop after maxsplits splits, thereby the largest array it will return has length maxsplits + 1. A limit of -1 means unlimited.",
+	"strReplace":          "Returns a copy of the string in which all occurrences of string from have been replaced with string to.",
+	"asciiUpper":          "Returns a copy of the string in which all ASCII letters are capitalized.",
+	"asciiLower":          "Returns a copy of the string in which all ASCII letters are lower cased.",
+	"stringChars":         "Split the string str into an array of strings, each containing a single codepoint.",
+	"format":              "Format the string str using the values in vals. The values can be an array, an object, or in other cases are treated as if they were provided in a singleton array. The string formatting follows the same rules as Python. The % operator can be used as a shorthand for this function.",
+	"escapeStringBash":    "Wrap str in single quotes, and escape any single quotes within str by changing them to a sequence '\"'\"'. This allows injection of arbitrary strings as arguments of commands in bash scripts.",
+	"escapeStringDollars": "Convert $ to $$ in str. This allows injection of arbitrary strings into systems that use $ for string interpolation (like Terraform).",
+	"escapeStringJson":    "Convert str to allow it to be embedded in a JSON representation, within a string. This adds quotes, escapes backslashes, and escapes unprintable characters.",
+	"escapeStringPython":  "Convert str to allow it to be embedded in Python. This is an alias for std.escapeStringJson.",
+
+	// parsing
+	"parseInt":          "Parses a signed decimal integer from the input string.",
+	"parseOctal":        "Parses an unsigned octal integer from the input string. Initial zeroes are tolerated.",
+	"parseHex":          "Parses an unsigned hexadecimal integer, from the input string. Case insensitive.",
+	"base64":            "Encodes the given value into a base64 string. The encoding sequence is A-Za-z0-9+/ with = to pad the output to a multiple of 4 characters. The value can be a string or an array of numbers, but the codepoints / numbers must be in the 0 to 255 range.",
+	"base64Decode":      "Decodes the given base64 string into a string. The string must contain characters that are in the 0 to 255 range.",
+	"base64DecodeBytes": "Decodes the given base64 string into an array of bytes (number values).",
+	"md5":               "Encodes the given value into an MD5 string.",
+
+	// manifestation
+	"manifestIni":        "Convert the given structure to a string in INI format. This allows using Jsonnet's object model to build a configuration to be consumed by an application expecting an INI file. The data is in the form of a set of sections, each containing a key/value mapping.",
+	"manifestPython":     "Convert the given value to a JSON-like form that is compatible with Python. The chief differences are True / False / None instead of true / false / null.",
+	"manifestPythonVars": "Convert the given object to a JSON-like form that is compatible with Python. The key difference to std.manifestPython is that the top level is represented as a list of Python global variables.",
+	"manifestJson":       "Convert the given object to a JSON form. Under the covers, it calls std.manifestJsonEx with a 4-space indent.",
+	"manifestJsonEx":     "Convert the given object to a JSON form. indent is a string containing one or more whitespaces that are used for indentation.",
+	"manifestYamlDoc":    "Convert the given value to a YAML form. Note that std.manifestJson could also be used for this purpose, because any JSON is also valid YAML. But this function will produce more canonical-looking YAML.",
+	"manifestYamlStream": "Given an array of values, emit a YAML \"stream\", which is a sequence of documents separated by --- and ending with ....",
+	"manifestXmlJsonml":  "Convert the given JsonML-encoded value to a string containing the XML. JsonML is designed to preserve \"mixed-mode content\" (i.e., textual data outside of or next to elements).",
+
+	// arrays
+	"makeArray":     "Create a new array of sz elements by calling func(i) to initialize each element. func is a function that takes a single parameter, the index of the element it should initialize.",
+	"count":         "Return the number of times that x occurs in arr.",
+	"map":           "Apply the given function to every element of the array to form a new array.",
+	"mapWithIndex":  "Similar to std.map, but it also passes to the function the element's index in the array. The function func is expected to take the index as the first parameter and the element as the second.",
+	"filterMap":     "It first filters, then maps the given array, using the two functions provided.",
+	"flatMap":       "Apply the given function to every element of arr to form a new array then flatten the result. The function func is expected to return an array.",
+	"filter":        "Return a new array containing all the elements of arr for which the func function returns true.",
+	"foldl":         "Classic foldl function. Calls the function func on the result of the previous function call and each array element, or init in the case of the initial element. Traverses the array from left to right.",
+	"foldr":         "Classic foldr function. Calls the function func on the result of the previous function call and each array element, or init in the case of the initial element. Traverses the array from right to left.",
+	"range":         "Return an array of ascending numbers between the two limits, inclusively.",
+	"join":          "If sep is a string, then arr must be an array of strings, in which case they are concatenated with sep used as a delimiter. If sep is an array, then arr must be an array of arrays, in which case the arrays are concatenated in the same way, to produce a single array.",
+	"lines":         "Concatenate an array of strings into a text file with newline characters after each string. This is suitable for constructing bash scripts and the like.",
+	"flattenArrays": "Concatenate an array of arrays into a single array.",
+	"deepJoin":      "Concatenate an array of strings and arrays of strings, recursively.",
+	"sort":          "Sorts the array using the <= operator. Optional argument keyF is a single argument function used to extract comparison key from each array element.",
+	"uniq":          "Removes successive duplicates. When given a sorted array, removes all duplicates. Optional argument keyF is a single argument function used to extract comparison key from each array element.",
+	"slice":         "Selects the elements of an array or a string from index to end with step and returns an array or a string respectively. Note that it's recommended to use dedicated slicing syntax both for arrays and strings (e.g. arr[0:4:1] instead of std.slice(arr, 0, 4, 1)).",
+
+	// sets
+	"set":       "Shortcut for std.uniq(std.sort(arr)).",
+	"setInter":  "Set intersection operation (values in both a and b).",
+	"setUnion":  "Set union operation (values in any of a or b). Note that + on sets will simply concatenate the arrays, possibly forming an array that is not a set (due to not being ordered without duplicates).",
+	"setDiff":   "Set difference operation (values in a but not b).",
+	"setMember": "Returns true if x is a member of array, otherwise false.",
+
+	// encoding
+	"mergePatch":  "Applies patch to target according to RFC7396.",
+	"resolvePath": "Replaces the last component of the path f with r.",
+	"native":      "Returns the native function with the given name, which was registered with the VM.",
+}
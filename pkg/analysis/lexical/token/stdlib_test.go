@@ -0,0 +1,72 @@
+package token
+
+import (
+	"testing"
+
+	jlspos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadStdlib(t *testing.T) {
+	stdlib, err := LoadStdlib()
+	require.NoError(t, err)
+
+	cases := []struct {
+		name      string
+		signature string
+		builtin   bool
+	}{
+		{name: "mapWithKey", signature: "std.mapWithKey(func, obj)"},
+		{name: "sort", signature: "std.sort(arr, keyF=id)"},
+		{name: "length", signature: "std.length(x)", builtin: true},
+		{name: "join", signature: "std.join(sep, arr)", builtin: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, ok := stdlib.Function(tc.name)
+			require.True(t, ok)
+
+			assert.Equal(t, tc.signature, f.Signature())
+			assert.Equal(t, tc.builtin, f.Builtin)
+			assert.NotEmpty(t, f.Documentation)
+		})
+	}
+
+	_, ok := stdlib.Function("missing")
+	assert.False(t, ok)
+
+	// every function in the catalogue is documented.
+	for _, f := range stdlib.Functions() {
+		assert.NotEmpty(t, f.Documentation, f.Name)
+	}
+}
+
+func TestResolveStd(t *testing.T) {
+	se, err := resolveStd([]string{"mapWithKey"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "std.mapWithKey(func, obj)", se.Detail)
+	assert.Equal(t, stdDocs["mapWithKey"], se.Documentation)
+
+	_, err = resolveStd([]string{"missing"})
+	require.Error(t, err)
+
+	_, err = resolveStd([]string{"a", "b"})
+	require.Error(t, err)
+}
+
+func TestIdentify_std(t *testing.T) {
+	ic, err := NewIdentifyConfig("file.jsonnet")
+	require.NoError(t, err)
+
+	item, err := Identify("std.mapWithKey", jlspos.New(1, 6), NewNodeCache(), ic)
+	require.NoError(t, err)
+
+	assert.Equal(t, "std.mapWithKey(func, obj)", item.String())
+	require.NotNil(t, item.Signature())
+	assert.Equal(t, stdDocs["mapWithKey"], item.Signature().Documentation())
+	assert.Equal(t, []string{"func", "obj"}, item.Signature().Parameters())
+}
@@ -0,0 +1,287 @@
+package token
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/text"
+	"github.com/google/go-jsonnet/ast"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// IndexedSymbol is a symbol declared in a file.
+type IndexedSymbol struct {
+	// Name is the name of the symbol.
+	Name string
+	// ContainerName is the dotted path of the locals and fields that
+	// contain the symbol.
+	ContainerName string
+	// Kind is the kind of symbol.
+	Kind lsp.SymbolKind
+	// Location is where the symbol is declared.
+	Location jpos.Location
+}
+
+// SymbolIndex is an index of the locals and fields declared in a set
+// of Jsonnet files.
+type SymbolIndex struct {
+	files map[string][]IndexedSymbol
+
+	mu sync.RWMutex
+}
+
+// NewSymbolIndex creates an instance of SymbolIndex.
+func NewSymbolIndex() *SymbolIndex {
+	return &SymbolIndex{
+		files: make(map[string][]IndexedSymbol),
+	}
+}
+
+// IndexDir indexes the Jsonnet files in dir and its sub directories.
+// Hidden directories are skipped. Files that can't be parsed are
+// skipped as well.
+func (si *SymbolIndex) IndexDir(ctx context.Context, dir string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "indexDir")
+	defer span.Finish()
+
+	span.LogFields(
+		log.String("dir", dir),
+	)
+
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			if path != dir && strings.HasPrefix(fi.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !IsJsonnetFile(path) {
+			return nil
+		}
+
+		if err := si.IndexFile(ctx, path); err != nil {
+			span.LogFields(
+				log.String("path", path),
+				log.Error(err),
+			)
+		}
+
+		return nil
+	})
+}
+
+// IndexFile indexes a file on disk.
+func (si *SymbolIndex) IndexFile(ctx context.Context, path string) error {
+	/* #nosec */
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return si.IndexSource(path, string(source))
+}
+
+// IndexSource indexes source for path. If source can't be parsed, the
+// existing symbols for path are kept.
+func (si *SymbolIndex) IndexSource(path, source string) error {
+	node, err := ReadSource(path, source, nil)
+	if err != nil {
+		return err
+	}
+
+	symbols := indexSymbols(node)
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	si.files[path] = symbols
+	return nil
+}
+
+// Remove removes path from the index.
+func (si *SymbolIndex) Remove(path string) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	delete(si.files, path)
+}
+
+// Files returns the files in the index.
+func (si *SymbolIndex) Files() []string {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+
+	var files []string
+	for path := range si.files {
+		files = append(files, path)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+// Search finds symbols with names fuzzy matching query. If the query
+// contains a `.`, it is matched against the container name and name
+// of symbols. The best matches are returned first. If limit is greater
+// than zero, at most limit symbols are returned.
+func (si *SymbolIndex) Search(query string, limit int) []IndexedSymbol {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+
+	type match struct {
+		symbol IndexedSymbol
+		score  int
+	}
+
+	var matches []match
+	for _, symbols := range si.files {
+		for _, symbol := range symbols {
+			name := symbol.Name
+			if strings.Contains(query, ".") && symbol.ContainerName != "" {
+				name = symbol.ContainerName + "." + symbol.Name
+			}
+
+			score, ok := text.FuzzyMatch(query, name)
+			if !ok {
+				continue
+			}
+
+			matches = append(matches, match{symbol: symbol, score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.symbol.Name != b.symbol.Name {
+			return a.symbol.Name < b.symbol.Name
+		}
+		return a.symbol.Location.String() < b.symbol.Location.String()
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	symbols := make([]IndexedSymbol, 0, len(matches))
+	for _, m := range matches {
+		symbols = append(symbols, m.symbol)
+	}
+
+	return symbols
+}
+
+// IsJsonnetFile returns true if path has a Jsonnet extension.
+func IsJsonnetFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".jsonnet", ".libsonnet":
+		return true
+	default:
+		return false
+	}
+}
+
+// indexSymbols collects the locals and fields declared in node.
+func indexSymbols(node ast.Node) []IndexedSymbol {
+	var symbols []IndexedSymbol
+	seen := make(map[string]bool)
+
+	add := func(name, container string, kind lsp.SymbolKind, loc ast.LocationRange) {
+		// object locals are copied into every field while desugaring.
+		key := name + "@" + loc.String()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		symbols = append(symbols, IndexedSymbol{
+			Name:          name,
+			ContainerName: container,
+			Kind:          kind,
+			Location:      jpos.LocationFromJsonnet(loc),
+		})
+	}
+
+	var visit func(n ast.Node, container string)
+	visit = func(n ast.Node, container string) {
+		switch n := n.(type) {
+		case *ast.Local:
+			for _, bind := range n.Binds {
+				if !bind.VarLoc.IsSet() {
+					visit(bind.Body, container)
+					continue
+				}
+
+				name := string(bind.Variable)
+				add(name, container, symbolKind(bind.Body), bind.VarLoc)
+				visit(bind.Body, qualifiedName(container, name))
+			}
+			visit(n.Body, container)
+			return
+		case *ast.DesugaredObject:
+			for _, field := range n.Fields {
+				name, err := fieldName(field)
+				loc, ok := n.FieldLocs[name]
+				if err != nil || !ok {
+					visit(field.Name, container)
+					visit(field.Body, container)
+					continue
+				}
+
+				kind := lsp.SKField
+				if _, ok := unwrapLocals(field.Body).(*ast.Function); ok {
+					kind = lsp.SKMethod
+				}
+
+				add(name, container, kind, loc)
+				visit(field.Body, qualifiedName(container, name))
+			}
+			for _, assert := range n.Asserts {
+				visit(assert, container)
+			}
+			return
+		}
+
+		for _, child := range children(n) {
+			visit(child, container)
+		}
+	}
+
+	visit(node, "")
+
+	return symbols
+}
+
+// unwrapLocals returns the body of nested locals.
+func unwrapLocals(n ast.Node) ast.Node {
+	for {
+		local, ok := n.(*ast.Local)
+		if !ok {
+			return n
+		}
+		n = local.Body
+	}
+}
+
+func qualifiedName(container, name string) string {
+	if container == "" {
+		return name
+	}
+
+	return container + "." + name
+}
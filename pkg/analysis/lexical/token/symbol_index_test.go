@@ -0,0 +1,137 @@
+package token
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolIndex_IndexDir(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "symbol_index"))
+	require.NoError(t, err)
+
+	si := NewSymbolIndex()
+	err = si.IndexDir(context.Background(), dir)
+	require.NoError(t, err)
+
+	expected := []string{
+		filepath.Join(dir, "app.jsonnet"),
+		filepath.Join(dir, "lib", "k.libsonnet"),
+	}
+	assert.Equal(t, expected, si.Files())
+
+	got := si.Search("deploymentSpec", 1)
+	require.Len(t, got, 1)
+
+	expectedSymbol := IndexedSymbol{
+		Name:     "deploymentSpec",
+		Kind:     lsp.SKMethod,
+		Location: jpos.NewLocation(filepath.Join(dir, "lib", "k.libsonnet"), jpos.NewRangeFromCoords(2, 3, 2, 17)),
+	}
+	assert.Equal(t, expectedSymbol, got[0])
+
+	si.Remove(filepath.Join(dir, "lib", "k.libsonnet"))
+	assert.Empty(t, si.Search("deploymentSpec", 0))
+}
+
+func TestSymbolIndex_Search(t *testing.T) {
+	source := `local lib = { port: 80 };
+{
+  local hidden = 1,
+  service: {
+    port: lib.port,
+    targetPort(p):: p,
+  },
+  a: hidden,
+}`
+
+	si := NewSymbolIndex()
+	require.NoError(t, si.IndexSource("file.jsonnet", source))
+
+	cases := []struct {
+		name     string
+		query    string
+		limit    int
+		expected []IndexedSymbol
+	}{
+		{
+			name:  "fuzzy",
+			query: "tport",
+			expected: []IndexedSymbol{
+				{
+					Name:          "targetPort",
+					ContainerName: "service",
+					Kind:          lsp.SKMethod,
+					Location:      jpos.NewLocation("file.jsonnet", jpos.NewRangeFromCoords(6, 5, 6, 15)),
+				},
+			},
+		},
+		{
+			name:  "ranked",
+			query: "port",
+			expected: []IndexedSymbol{
+				{
+					Name:          "port",
+					ContainerName: "lib",
+					Kind:          lsp.SKField,
+					Location:      jpos.NewLocation("file.jsonnet", jpos.NewRangeFromCoords(1, 15, 1, 19)),
+				},
+				{
+					Name:          "port",
+					ContainerName: "service",
+					Kind:          lsp.SKField,
+					Location:      jpos.NewLocation("file.jsonnet", jpos.NewRangeFromCoords(5, 5, 5, 9)),
+				},
+				{
+					Name:          "targetPort",
+					ContainerName: "service",
+					Kind:          lsp.SKMethod,
+					Location:      jpos.NewLocation("file.jsonnet", jpos.NewRangeFromCoords(6, 5, 6, 15)),
+				},
+			},
+		},
+		{
+			name:  "limit",
+			query: "port",
+			limit: 1,
+			expected: []IndexedSymbol{
+				{
+					Name:          "port",
+					ContainerName: "lib",
+					Kind:          lsp.SKField,
+					Location:      jpos.NewLocation("file.jsonnet", jpos.NewRangeFromCoords(1, 15, 1, 19)),
+				},
+			},
+		},
+		{
+			name:  "container",
+			query: "service.port",
+			limit: 1,
+			expected: []IndexedSymbol{
+				{
+					Name:          "port",
+					ContainerName: "service",
+					Kind:          lsp.SKField,
+					Location:      jpos.NewLocation("file.jsonnet", jpos.NewRangeFromCoords(5, 5, 5, 9)),
+				},
+			},
+		},
+		{
+			name:     "no match",
+			query:    "xyz",
+			expected: []IndexedSymbol{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := si.Search(tc.query, tc.limit)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package token
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/astext"
 	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
@@ -51,10 +52,23 @@ func (s *Symbol) SelectionRange() jpos.Range {
 	return s.selectionRange
 }
 
-type symbolVisitor struct{}
+// Children are the symbols declared within the symbol, e.g. the fields
+// of an object.
+func (s *Symbol) Children() []Symbol {
+	return s.children
+}
 
-func newSymbolVisitor() *symbolVisitor {
-	return &symbolVisitor{}
+type symbolVisitor struct {
+	tokens Tokens
+	docs   *docComments
+	ti     *typeInferrer
+}
+
+func newSymbolVisitor(tokens Tokens) *symbolVisitor {
+	return &symbolVisitor{
+		tokens: tokens,
+		docs:   newDocComments(tokens),
+	}
 }
 
 // nolint: gocyclo
@@ -68,6 +82,12 @@ func (s *symbolVisitor) visit(n ast.Node) []Symbol {
 		}
 	case *ast.Apply:
 		syms = append(syms, s.visit(n.Target)...)
+		for _, arg := range n.Arguments.Positional {
+			syms = append(syms, s.visit(arg)...)
+		}
+		for _, arg := range n.Arguments.Named {
+			syms = append(syms, s.visit(arg.Arg)...)
+		}
 	case *ast.Binary:
 		syms = append(syms, s.visit(n.Left)...)
 		syms = append(syms, s.visit(n.Right)...)
@@ -76,10 +96,7 @@ func (s *symbolVisitor) visit(n ast.Node) []Symbol {
 		syms = append(syms, s.visit(n.BranchTrue)...)
 		syms = append(syms, s.visit(n.BranchFalse)...)
 	case *ast.DesugaredObject:
-		for _, field := range n.Fields {
-			syms = append(syms, s.visit(field.Name)...)
-			syms = append(syms, s.visit(field.Body)...)
-		}
+		syms = append(syms, s.visitObject(n)...)
 	case *ast.Error:
 		syms = append(syms, s.visit(n.Expr)...)
 	case *ast.Function:
@@ -100,24 +117,22 @@ func (s *symbolVisitor) visit(n ast.Node) []Symbol {
 	case *ast.LiteralString:
 	case *ast.Local:
 		for _, bind := range n.Binds {
-			if string(bind.Variable) != "$" {
-				// TODO figure out the exact ranges. The AST doesn't provide
-				sym := Symbol{
-					name:           string(bind.Variable),
-					kind:           symbolKind(bind.Body),
-					selectionRange: jpos.FromJsonnetRange(*bind.Body.Loc()),
-					enclosingRange: jpos.FromJsonnetRange(*bind.Body.Loc()),
-				}
-
-				syms = append(syms, sym)
+			if !bind.VarLoc.IsSet() {
+				// binds created while desugaring don't have a name in
+				// the source.
+				syms = append(syms, s.visit(bind.Body)...)
+				continue
 			}
 
-			syms = append(syms, s.visit(bind.Body)...)
-
+			sym := s.symbol(string(bind.Variable), s.kind(bind.Body), bind.VarLoc)
+			sym.children = s.visit(bind.Body)
+			syms = append(syms, sym)
 		}
 		syms = append(syms, s.visit(n.Body)...)
-	case *astext.Partial, *astext.PartialIndex:
+	case *astext.Partial:
 		// nothing to do
+	case *astext.PartialIndex:
+		syms = append(syms, s.visit(n.Target)...)
 	case *ast.Self:
 	case *ast.SuperIndex:
 		syms = append(syms, s.visit(n.Index)...)
@@ -125,6 +140,8 @@ func (s *symbolVisitor) visit(n ast.Node) []Symbol {
 		syms = append(syms, s.visit(n.Expr)...)
 	case *ast.Var:
 		// nothing to do
+	case nil:
+		// partial sources can have missing nodes.
 	default:
 		panic(fmt.Sprintf("unexpected node %T", n))
 	}
@@ -132,19 +149,199 @@ func (s *symbolVisitor) visit(n ast.Node) []Symbol {
 	return syms
 }
 
-// Symbols retrieves symbols from source.
+// visitObject creates symbols for the object locals and fields of an
+// object. Fields with computed names don't have symbols.
+func (s *symbolVisitor) visitObject(o *ast.DesugaredObject) []Symbol {
+	var binds ast.LocalBinds
+	if len(o.Fields) > 0 {
+		binds = objectLocalBinds(o.Fields[0].Body)
+	}
+
+	syms := s.objectLocals(o, binds)
+
+	for _, field := range o.Fields {
+		body := field.Body
+		if len(objectLocalBinds(body)) > 0 {
+			body = body.(*ast.Local).Body
+		}
+
+		name, err := fieldName(field)
+		loc, ok := o.FieldLocs[name]
+		if err != nil || !ok {
+			syms = append(syms, s.visit(field.Name)...)
+			syms = append(syms, s.visit(body)...)
+			continue
+		}
+
+		kind := lsp.SKField
+		if _, ok := body.(*ast.Function); ok {
+			kind = lsp.SKMethod
+		}
+
+		sym := s.symbol(name, kind, loc)
+		sym.detail = fieldSymbolDetail(field)
+		sym.children = s.visit(body)
+		syms = append(syms, sym)
+	}
+
+	for _, assert := range o.Asserts {
+		syms = append(syms, s.visit(assert)...)
+	}
+
+	return syms
+}
+
+// objectLocals creates symbols for the object locals of o. Desugaring
+// copies object locals into every field without their names' locations,
+// so the names are found in the tokens of the object.
+func (s *symbolVisitor) objectLocals(o *ast.DesugaredObject, binds ast.LocalBinds) []Symbol {
+	start := tokenIndexAt(s.tokens, o.Loc().Begin)
+	if start == -1 || s.tokens[start].Kind != TokenBraceL {
+		return nil
+	}
+
+	end := closingBracket(s.tokens, start)
+	if end == -1 {
+		end = len(s.tokens) - 1
+	}
+
+	bodies := make(map[string]ast.Node)
+	for _, bind := range binds {
+		bodies[string(bind.Variable)] = bind.Body
+	}
+
+	var syms []Symbol
+	for i := start + 1; i < end; i++ {
+		if isOpeningBracket(s.tokens[i].Kind) {
+			if j := closingBracket(s.tokens, i); j != -1 {
+				i = j
+			}
+			continue
+		}
+
+		if s.tokens[i].Kind != TokenLocal || !isObjectLocal(s.tokens, i) || i+1 >= end {
+			continue
+		}
+
+		name := s.tokens[i+1]
+		body, ok := bodies[name.Data]
+		if !ok {
+			// objects without fields don't keep their locals.
+			syms = append(syms, s.symbol(name.Data, lsp.SKVariable, name.Loc))
+			continue
+		}
+
+		sym := s.symbol(name.Data, s.kind(body), name.Loc)
+		sym.children = s.visit(body)
+		syms = append(syms, sym)
+	}
+
+	return syms
+}
+
+// symbol creates a symbol whose name is at loc. The symbol encloses the
+// name and the value assigned to it.
+func (s *symbolVisitor) symbol(name string, kind lsp.SymbolKind, loc ast.LocationRange) Symbol {
+	sym := Symbol{
+		name:           name,
+		kind:           kind,
+		selectionRange: jpos.FromJsonnetRange(loc),
+		enclosingRange: jpos.FromJsonnetRange(loc),
+		isDeprecated:   s.docs.doc(loc.Begin).Deprecated,
+	}
+
+	i := tokenIndexAt(s.tokens, loc.Begin)
+	if i == -1 {
+		return sym
+	}
+
+	end := bindEnd(s.tokens, i+1)
+	if end-1 > i {
+		sym.enclosingRange = rangeBetween(loc.Begin, s.tokens[end-1].Loc.End)
+	}
+
+	return sym
+}
+
+// objectLocalBinds returns the object locals which desugaring added to a
+// field body.
+func objectLocalBinds(body ast.Node) ast.LocalBinds {
+	local, ok := body.(*ast.Local)
+	if !ok || len(local.Binds) == 0 || local.Binds[0].VarLoc.IsSet() {
+		return nil
+	}
+
+	return local.Binds
+}
+
+// fieldSymbolDetail marks hidden fields and fields which are mixed into
+// the field they override with `+:`.
+func fieldSymbolDetail(field ast.DesugaredObjectField) string {
+	var markers []string
+	if field.Hide == ast.ObjectFieldHidden {
+		markers = append(markers, "(hidden)")
+	}
+	if field.PlusSuper {
+		markers = append(markers, "(mixin)")
+	}
+
+	return strings.Join(markers, " ")
+}
+
+// Symbols retrieves symbols from source. Fields are nested within the
+// symbol of their object, and symbols declared in the value of a local
+// or field are nested within the local or field.
 func Symbols(source string) ([]Symbol, error) {
-	node, err := ReadSource("symbols.jsonnet", source, nil)
+	r := newResolver(nil)
+	node, err := r.addSource("symbols.jsonnet", source)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := Lex("symbols.jsonnet", source)
 	if err != nil {
 		return nil, err
 	}
 
-	sv := newSymbolVisitor()
+	sv := newSymbolVisitor(tokens)
+	sv.ti = r.typeInferrer()
 	symbols := sv.visit(node)
 
 	return symbols, nil
 }
 
+// kind returns the symbol kind of a value. The kind of a value which
+// isn't a literal is the kind of its inferred type.
+func (s *symbolVisitor) kind(n ast.Node) lsp.SymbolKind {
+	kind := symbolKind(n)
+	if kind != lsp.SKVariable || s.ti == nil {
+		return kind
+	}
+
+	return typeSymbolKind(s.ti.infer(n))
+}
+
+func typeSymbolKind(t *Type) lsp.SymbolKind {
+	switch t.Kind {
+	case TypeArray:
+		return lsp.SKArray
+	case TypeObject:
+		return lsp.SKObject
+	case TypeFunction:
+		return lsp.SKFunction
+	case TypeBoolean:
+		return lsp.SKBoolean
+	case TypeNull:
+		return lsp.SKNull
+	case TypeNumber:
+		return lsp.SKNumber
+	case TypeString:
+		return lsp.SKString
+	default:
+		return lsp.SKVariable
+	}
+}
+
 func symbolKind(node ast.Node) lsp.SymbolKind {
 	switch node.(type) {
 	case *ast.Array:
@@ -22,8 +22,8 @@ func TestSymbols(t *testing.T) {
 				{
 					name:           "a",
 					kind:           lsp.SKString,
-					selectionRange: jpos.NewRange(jpos.New(1, 9), jpos.New(1, 12)),
-					enclosingRange: jpos.NewRange(jpos.New(1, 9), jpos.New(1, 12)),
+					selectionRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 8)),
+					enclosingRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 12)),
 				},
 			},
 		},
@@ -34,14 +34,14 @@ func TestSymbols(t *testing.T) {
 				{
 					name:           "a",
 					kind:           lsp.SKNumber,
-					selectionRange: jpos.NewRange(jpos.New(1, 9), jpos.New(1, 10)),
-					enclosingRange: jpos.NewRange(jpos.New(1, 9), jpos.New(1, 10)),
+					selectionRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 8)),
+					enclosingRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 10)),
 				},
 				{
 					name:           "b",
 					kind:           lsp.SKNumber,
-					selectionRange: jpos.NewRange(jpos.New(1, 14), jpos.New(1, 15)),
-					enclosingRange: jpos.NewRange(jpos.New(1, 14), jpos.New(1, 15)),
+					selectionRange: jpos.NewRange(jpos.New(1, 12), jpos.New(1, 13)),
+					enclosingRange: jpos.NewRange(jpos.New(1, 12), jpos.New(1, 15)),
 				},
 			},
 		},
@@ -52,14 +52,14 @@ func TestSymbols(t *testing.T) {
 				{
 					name:           "a",
 					kind:           lsp.SKNumber,
-					selectionRange: jpos.NewRange(jpos.New(1, 9), jpos.New(1, 10)),
-					enclosingRange: jpos.NewRange(jpos.New(1, 9), jpos.New(1, 10)),
+					selectionRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 8)),
+					enclosingRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 10)),
 				},
 				{
 					name:           "b",
 					kind:           lsp.SKNumber,
-					selectionRange: jpos.NewRange(jpos.New(1, 20), jpos.New(1, 21)),
-					enclosingRange: jpos.NewRange(jpos.New(1, 20), jpos.New(1, 21)),
+					selectionRange: jpos.NewRange(jpos.New(1, 18), jpos.New(1, 19)),
+					enclosingRange: jpos.NewRange(jpos.New(1, 18), jpos.New(1, 21)),
 				},
 			},
 		},
@@ -70,11 +70,137 @@ func TestSymbols(t *testing.T) {
 				{
 					name:           "id",
 					kind:           lsp.SKFunction,
-					selectionRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 16)),
+					selectionRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 9)),
 					enclosingRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 16)),
 				},
 			},
 		},
+		{
+			name:   "function with local",
+			source: "local id(x) = local y = x; y; id(1)",
+			expected: []Symbol{
+				{
+					name:           "id",
+					kind:           lsp.SKFunction,
+					selectionRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 9)),
+					enclosingRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 29)),
+					children: []Symbol{
+						{
+							name:           "y",
+							kind:           lsp.SKVariable,
+							selectionRange: jpos.NewRange(jpos.New(1, 21), jpos.New(1, 22)),
+							enclosingRange: jpos.NewRange(jpos.New(1, 21), jpos.New(1, 26)),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "object fields",
+			source: "local o = {\n  a: 1,\n  b:: {c: 'c'},\n  d+: {},\n  f(x):: x,\n};\no",
+			expected: []Symbol{
+				{
+					name:           "o",
+					kind:           lsp.SKObject,
+					selectionRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 8)),
+					enclosingRange: jpos.NewRange(jpos.New(1, 7), jpos.New(6, 2)),
+					children: []Symbol{
+						{
+							name:           "a",
+							kind:           lsp.SKField,
+							selectionRange: jpos.NewRange(jpos.New(2, 3), jpos.New(2, 4)),
+							enclosingRange: jpos.NewRange(jpos.New(2, 3), jpos.New(2, 7)),
+						},
+						{
+							name:           "b",
+							detail:         "(hidden)",
+							kind:           lsp.SKField,
+							selectionRange: jpos.NewRange(jpos.New(3, 3), jpos.New(3, 4)),
+							enclosingRange: jpos.NewRange(jpos.New(3, 3), jpos.New(3, 15)),
+							children: []Symbol{
+								{
+									name:           "c",
+									kind:           lsp.SKField,
+									selectionRange: jpos.NewRange(jpos.New(3, 8), jpos.New(3, 9)),
+									enclosingRange: jpos.NewRange(jpos.New(3, 8), jpos.New(3, 14)),
+								},
+							},
+						},
+						{
+							name:           "d",
+							detail:         "(mixin)",
+							kind:           lsp.SKField,
+							selectionRange: jpos.NewRange(jpos.New(4, 3), jpos.New(4, 4)),
+							enclosingRange: jpos.NewRange(jpos.New(4, 3), jpos.New(4, 9)),
+						},
+						{
+							name:           "f",
+							detail:         "(hidden)",
+							kind:           lsp.SKMethod,
+							selectionRange: jpos.NewRange(jpos.New(5, 3), jpos.New(5, 4)),
+							enclosingRange: jpos.NewRange(jpos.New(5, 3), jpos.New(5, 11)),
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "top level object with object local",
+			source: "{\n  local x = {y: 1},\n  'z': x,\n}",
+			expected: []Symbol{
+				{
+					name:           "x",
+					kind:           lsp.SKObject,
+					selectionRange: jpos.NewRange(jpos.New(2, 9), jpos.New(2, 10)),
+					enclosingRange: jpos.NewRange(jpos.New(2, 9), jpos.New(2, 19)),
+					children: []Symbol{
+						{
+							name:           "y",
+							kind:           lsp.SKField,
+							selectionRange: jpos.NewRange(jpos.New(2, 14), jpos.New(2, 15)),
+							enclosingRange: jpos.NewRange(jpos.New(2, 14), jpos.New(2, 18)),
+						},
+					},
+				},
+				{
+					name:           "z",
+					kind:           lsp.SKField,
+					selectionRange: jpos.NewRange(jpos.New(3, 3), jpos.New(3, 6)),
+					enclosingRange: jpos.NewRange(jpos.New(3, 3), jpos.New(3, 9)),
+				},
+			},
+		},
+		{
+			name:   "inferred kind",
+			source: "local a = 1 + 2; a",
+			expected: []Symbol{
+				{
+					name:           "a",
+					kind:           lsp.SKNumber,
+					selectionRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 8)),
+					enclosingRange: jpos.NewRange(jpos.New(1, 7), jpos.New(1, 16)),
+				},
+			},
+		},
+		{
+			name:   "deprecated",
+			source: "{\n  // @deprecated use b\n  a: 1,\n  b: 2,\n}",
+			expected: []Symbol{
+				{
+					name:           "a",
+					kind:           lsp.SKField,
+					isDeprecated:   true,
+					selectionRange: jpos.NewRange(jpos.New(3, 3), jpos.New(3, 4)),
+					enclosingRange: jpos.NewRange(jpos.New(3, 3), jpos.New(3, 7)),
+				},
+				{
+					name:           "b",
+					kind:           lsp.SKField,
+					selectionRange: jpos.NewRange(jpos.New(4, 3), jpos.New(4, 4)),
+					enclosingRange: jpos.NewRange(jpos.New(4, 3), jpos.New(4, 7)),
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
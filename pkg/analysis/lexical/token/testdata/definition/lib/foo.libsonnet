@@ -0,0 +1,4 @@
+local base = { deployment: { spec: { replicas: 1 } } };
+base + {
+  service: { port: 80 },
+}
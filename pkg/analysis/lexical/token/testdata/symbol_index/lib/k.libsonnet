@@ -0,0 +1,3 @@
+{
+  deploymentSpec(replicas):: { replicas: replicas },
+}
@@ -0,0 +1,342 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/google/go-jsonnet/ast"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// TypeDiagnostics infers the types of the expressions in a desugared
+// node and reports operations which can't succeed with the inferred
+// types, e.g. calling a number, adding an object to a number, indexing a
+// field an object doesn't have, or calling a function with the wrong
+// arguments. Values whose types can't be inferred are never reported.
+// Diagnostics are sorted by location.
+func TypeDiagnostics(ctx context.Context, filename string, node ast.Node, libPaths []string, nodeCache *NodeCache) []static.Diagnostic {
+	span, _ := opentracing.StartSpanFromContext(ctx, "typeDiagnostics")
+	defer span.Finish()
+
+	r := newResolver(libPaths)
+	r.nodeCache = nodeCache
+	r.addNode(filename, node)
+
+	tc := &typeChecker{
+		filename: filename,
+		ti:       r.typeInferrer(),
+		visited:  make(map[ast.Node]bool),
+		reported: make(map[static.Diagnostic]bool),
+		guards:   make(map[fieldGuard]int),
+	}
+	tc.check(node)
+
+	sort.SliceStable(tc.diagnostics, func(i, j int) bool {
+		return isBefore(tc.diagnostics[i].Loc.Begin, tc.diagnostics[j].Loc.Begin)
+	})
+
+	return tc.diagnostics
+}
+
+type typeChecker struct {
+	filename    string
+	ti          *typeInferrer
+	visited     map[ast.Node]bool
+	reported    map[static.Diagnostic]bool
+	diagnostics []static.Diagnostic
+	// guards are the fields which are known to exist in the branch being
+	// checked, e.g. `o.b` in `if std.objectHas(o, 'b') then o.b`.
+	guards map[fieldGuard]int
+}
+
+// fieldGuard is a field checked with `std.objectHas`, `std.objectHasAll`
+// or `in`.
+type fieldGuard struct {
+	target guardTarget
+	name   string
+}
+
+// guardTarget identifies an object by the binding or object it starts
+// from and the fields indexed from there, e.g. `o.a` is the binding of
+// `o` and the path `.a`.
+type guardTarget struct {
+	root interface{}
+	path string
+}
+
+func (tc *typeChecker) report(n ast.Node, format string, args ...interface{}) {
+	loc := n.Loc()
+	if loc == nil || !loc.IsSet() || loc.FileName != tc.filename {
+		// nodes created while desugaring can't be reported.
+		return
+	}
+
+	d := static.Diagnostic{
+		Code:    static.TypeMismatch,
+		Message: fmt.Sprintf(format, args...),
+		Loc:     *loc,
+	}
+
+	// desugaring copies object locals into every field of their object.
+	if tc.reported[d] {
+		return
+	}
+	tc.reported[d] = true
+
+	tc.diagnostics = append(tc.diagnostics, d)
+}
+
+// check checks n and its children.
+func (tc *typeChecker) check(n ast.Node) {
+	if tc.visited[n] {
+		return
+	}
+	tc.visited[n] = true
+
+	switch n := n.(type) {
+	case *ast.Apply:
+		tc.checkApply(n)
+	case *ast.Index:
+		tc.checkIndex(n)
+	case *ast.Binary:
+		tc.checkBinary(n)
+	case *ast.Unary:
+		t := tc.ti.infer(n.Expr)
+		switch {
+		case n.Op == ast.UopNot && t.IsKnown() && t.Kind != TypeBoolean:
+			tc.report(n, "operator ! expects a boolean, got %s", t)
+		case n.Op != ast.UopNot && t.IsKnown() && t.Kind != TypeNumber:
+			tc.report(n, "operator %s expects a number, got %s", n.Op, t)
+		}
+	case *ast.Conditional:
+		if t := tc.ti.infer(n.Cond); t.IsKnown() && t.Kind != TypeBoolean {
+			tc.report(n.Cond, "condition must be a boolean, got %s", t)
+		}
+
+		tc.check(n.Cond)
+		tc.checkGuarded(n.BranchTrue, tc.fieldGuards(n.Cond, true))
+		tc.checkGuarded(n.BranchFalse, tc.fieldGuards(n.Cond, false))
+		return
+	}
+
+	for _, child := range children(n) {
+		tc.check(child)
+	}
+}
+
+// checkGuarded checks n knowing the guarded fields exist.
+func (tc *typeChecker) checkGuarded(n ast.Node, guards []fieldGuard) {
+	for _, g := range guards {
+		tc.guards[g]++
+	}
+
+	tc.check(n)
+
+	for _, g := range guards {
+		tc.guards[g]--
+	}
+}
+
+// fieldGuards returns the fields which exist when cond evaluates to
+// holds.
+func (tc *typeChecker) fieldGuards(cond ast.Node, holds bool) []fieldGuard {
+	switch n := cond.(type) {
+	case *ast.Unary:
+		if n.Op == ast.UopNot {
+			return tc.fieldGuards(n.Expr, !holds)
+		}
+	case *ast.Binary:
+		// both sides of `a && b` hold if it is true, and neither side of
+		// `a || b` holds if it is false.
+		if (n.Op == ast.BopAnd && holds) || (n.Op == ast.BopOr && !holds) {
+			return append(tc.fieldGuards(n.Left, holds), tc.fieldGuards(n.Right, holds)...)
+		}
+	case *ast.Apply:
+		if !holds || len(n.Arguments.Positional) < 2 {
+			return nil
+		}
+
+		if !isStdCall(n, "objectHas") && !isStdCall(n, "objectHasAll") && !isStdCall(n, "objectHasEx") {
+			return nil
+		}
+
+		name, ok := literalIndex(n.Arguments.Positional[1])
+		if !ok {
+			return nil
+		}
+
+		target, ok := tc.guardTarget(n.Arguments.Positional[0])
+		if !ok {
+			return nil
+		}
+
+		return []fieldGuard{{target: target, name: name}}
+	}
+
+	return nil
+}
+
+// guardTarget identifies the object n refers to. Only variables, `self`
+// and fields indexed from them can be identified.
+func (tc *typeChecker) guardTarget(n ast.Node) (guardTarget, bool) {
+	switch n := n.(type) {
+	case *ast.Var:
+		b, err := tc.ti.r.lookup(n)
+		if err != nil {
+			return guardTarget{}, false
+		}
+		return guardTarget{root: b}, true
+	case *ast.Self:
+		env := tc.ti.r.envs[n]
+		if env == nil || env.self == nil {
+			return guardTarget{}, false
+		}
+		return guardTarget{root: env.self}, true
+	case *ast.Index:
+		name, ok := literalIndex(n.Index)
+		if !ok {
+			return guardTarget{}, false
+		}
+
+		target, ok := tc.guardTarget(n.Target)
+		if !ok {
+			return guardTarget{}, false
+		}
+
+		target.path += "." + name
+		return target, true
+	}
+
+	return guardTarget{}, false
+}
+
+// isGuarded returns true if the field name of target is known to exist.
+func (tc *typeChecker) isGuarded(target ast.Node, name string) bool {
+	gt, ok := tc.guardTarget(target)
+	if !ok {
+		return false
+	}
+
+	return tc.guards[fieldGuard{target: gt, name: name}] > 0
+}
+
+func (tc *typeChecker) checkApply(n *ast.Apply) {
+	t := tc.ti.infer(n.Target)
+	if !t.IsKnown() {
+		return
+	}
+
+	if t.Kind != TypeFunction {
+		tc.report(n, "cannot call a value of type %s", t)
+		return
+	}
+
+	positional := len(n.Arguments.Positional)
+	if positional > len(t.Params) {
+		tc.report(n, "too many arguments: expected at most %d, got %d", len(t.Params), positional)
+		return
+	}
+
+	given := make(map[string]bool)
+	for _, p := range t.Params[:positional] {
+		given[p.Name] = true
+	}
+
+	for _, arg := range n.Arguments.Named {
+		name := string(arg.Name)
+		if !hasParam(t.Params, name) {
+			tc.report(n, "unknown argument %s", name)
+			continue
+		}
+		if given[name] {
+			tc.report(n, "argument %s is given more than once", name)
+		}
+		given[name] = true
+	}
+
+	var missing []string
+	for _, p := range t.Params {
+		if !p.Optional && !given[p.Name] {
+			missing = append(missing, p.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		tc.report(n, "missing arguments: %s", strings.Join(missing, ", "))
+	}
+}
+
+func hasParam(params []TypeParam, name string) bool {
+	for _, p := range params {
+		if p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (tc *typeChecker) checkIndex(n *ast.Index) {
+	t := tc.ti.infer(n.Target)
+	index := tc.ti.infer(n.Index)
+
+	switch t.Kind {
+	case TypeNull, TypeBoolean, TypeNumber, TypeFunction:
+		tc.report(n, "cannot index a value of type %s", t)
+	case TypeObject:
+		if index.IsKnown() && index.Kind != TypeString {
+			tc.report(n.Index, "object index must be a string, got %s", index)
+			return
+		}
+
+		name, ok := literalIndex(n.Index)
+		if !ok || t.Open || tc.isGuarded(n.Target, name) {
+			return
+		}
+
+		if _, ok := t.Field(name); !ok {
+			tc.report(n, "field %s does not exist", name)
+		}
+	case TypeArray, TypeString:
+		if index.IsKnown() && index.Kind != TypeNumber {
+			tc.report(n.Index, "%s index must be a number, got %s", t.Kind, index)
+		}
+	}
+}
+
+// nolint: gocyclo
+func (tc *typeChecker) checkBinary(n *ast.Binary) {
+	left, right := tc.ti.infer(n.Left), tc.ti.infer(n.Right)
+
+	switch n.Op {
+	case ast.BopPlus:
+		if !left.IsKnown() || !right.IsKnown() || left.Kind == TypeString || right.Kind == TypeString {
+			return
+		}
+
+		switch {
+		case left.Kind != right.Kind:
+			tc.report(n, "cannot add %s and %s", left.Kind, right.Kind)
+		case left.Kind != TypeNumber && left.Kind != TypeArray && left.Kind != TypeObject:
+			tc.report(n, "cannot add %s and %s", left.Kind, right.Kind)
+		}
+	case ast.BopMult, ast.BopDiv, ast.BopMinus, ast.BopShiftL, ast.BopShiftR,
+		ast.BopBitwiseAnd, ast.BopBitwiseXor, ast.BopBitwiseOr:
+		for _, t := range []*Type{left, right} {
+			if t.IsKnown() && t.Kind != TypeNumber {
+				tc.report(n, "operator %s expects numbers, got %s", n.Op, t.Kind)
+				return
+			}
+		}
+	case ast.BopAnd, ast.BopOr:
+		for _, t := range []*Type{left, right} {
+			if t.IsKnown() && t.Kind != TypeBoolean {
+				tc.report(n, "operator %s expects booleans, got %s", n.Op, t.Kind)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package token
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeDiagnostics(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "definition"))
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "file.jsonnet")
+	libPaths := []string{filepath.Join(dir, "lib")}
+
+	type diagnostic struct {
+		message string
+		loc     [4]int
+	}
+
+	cases := []struct {
+		name     string
+		source   string
+		expected []diagnostic
+	}{
+		{
+			name:   "valid",
+			source: "local f(x, y=1) = x + y; local o = {a: f(1, y=2)}; [o.a, 'a' + 1, [1] + [], self]",
+		},
+		{
+			name:     "call a number",
+			source:   "local a = 1; a()",
+			expected: []diagnostic{{"cannot call a value of type number", [4]int{1, 14, 1, 17}}},
+		},
+		{
+			name:     "too many arguments",
+			source:   "local f(x) = x; f(1, 2)",
+			expected: []diagnostic{{"too many arguments: expected at most 1, got 2", [4]int{1, 17, 1, 24}}},
+		},
+		{
+			name:     "missing arguments",
+			source:   "local f(x, y, z=1) = x; f()",
+			expected: []diagnostic{{"missing arguments: x, y", [4]int{1, 25, 1, 28}}},
+		},
+		{
+			name:   "named arguments",
+			source: "local f(x) = x; [f(1, x=2), f(z=1)]",
+			expected: []diagnostic{
+				{"argument x is given more than once", [4]int{1, 18, 1, 27}},
+				{"unknown argument z", [4]int{1, 29, 1, 35}},
+				{"missing arguments: x", [4]int{1, 29, 1, 35}},
+			},
+		},
+		{
+			name:     "std arity",
+			source:   "std.length()",
+			expected: []diagnostic{{"missing arguments: x", [4]int{1, 1, 1, 13}}},
+		},
+		{
+			name:     "missing field",
+			source:   "local o = {a: 1}; o.b",
+			expected: []diagnostic{{"field b does not exist", [4]int{1, 19, 1, 22}}},
+		},
+		{
+			name:     "missing imported field",
+			source:   "(import 'foo.libsonnet').ingress",
+			expected: []diagnostic{{"field ingress does not exist", [4]int{1, 1, 1, 33}}},
+		},
+		{
+			name: "guarded fields",
+			source: `local o = {a: 1};
+[
+  if std.objectHas(o, 'b') then o.b,
+  if std.objectHasAll(o, 'b') && true then o.b else null,
+  if !('b' in o) then null else o.b,
+  if 'b' in o then null else o.b,
+  if std.objectHas(o, 'b') then o.c,
+]`,
+			expected: []diagnostic{
+				{"field b does not exist", [4]int{6, 30, 6, 33}},
+				{"field c does not exist", [4]int{7, 33, 7, 36}},
+			},
+		},
+		{
+			name:   "fields of self can be added by mixins",
+			source: "{a: self.b}",
+		},
+		{
+			name:   "fields of objects in self can be added by mixins",
+			source: "{_config:: {namespace: 'default'}, deployment: {ns: $._config.name}}",
+		},
+		{
+			name:   "fields of objects in self indexed with self",
+			source: "{_config:: {a: 1}, x: self._config.b}",
+		},
+		{
+			name:   "local bound to an object in self",
+			source: "{local cfg = $._config, _config:: {}, x: cfg.image}",
+		},
+		{
+			name:   "fields of objects in super",
+			source: "{_config:: {a: 1}} + {x: super._config.b}",
+		},
+		{
+			name:     "fields of objects in locals are closed",
+			source:   "local o = {a: {b: 1}}; o.a.c",
+			expected: []diagnostic{{"field c does not exist", [4]int{1, 24, 1, 29}}},
+		},
+		{
+			name:     "index a number",
+			source:   "local a = 1; a.b",
+			expected: []diagnostic{{"cannot index a value of type number", [4]int{1, 14, 1, 17}}},
+		},
+		{
+			name:     "array index",
+			source:   "[1]['a']",
+			expected: []diagnostic{{"array index must be a number, got string", [4]int{1, 5, 1, 8}}},
+		},
+		{
+			name:     "add mismatched types",
+			source:   "{a: 1} + 1",
+			expected: []diagnostic{{"cannot add object and number", [4]int{1, 1, 1, 11}}},
+		},
+		{
+			name:     "arithmetic on a string",
+			source:   "'a' - 1",
+			expected: []diagnostic{{"operator - expects numbers, got string", [4]int{1, 1, 1, 8}}},
+		},
+		{
+			name:     "logical operator",
+			source:   "true && 1",
+			expected: []diagnostic{{"operator && expects booleans, got number", [4]int{1, 1, 1, 10}}},
+		},
+		{
+			name:     "not a boolean",
+			source:   "!1",
+			expected: []diagnostic{{"operator ! expects a boolean, got number", [4]int{1, 1, 1, 3}}},
+		},
+		{
+			name:     "condition",
+			source:   "if 'a' then 1 else 2",
+			expected: []diagnostic{{"condition must be a boolean, got string", [4]int{1, 4, 1, 7}}},
+		},
+		{
+			name:   "unknown types",
+			source: "function(x, y) x(y.a) + y - -x",
+		},
+		{
+			name:     "object locals are checked once",
+			source:   "{local a = 1(), b: a, c: a}",
+			expected: []diagnostic{{"cannot call a value of type number", [4]int{1, 12, 1, 15}}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, err := ReadSource(file, tc.source, nil)
+			require.NoError(t, err)
+
+			got := TypeDiagnostics(context.Background(), file, node, libPaths, NewNodeCache())
+
+			var diagnostics []diagnostic
+			for _, d := range got {
+				assert.Equal(t, static.TypeMismatch, d.Code)
+				assert.Equal(t, file, d.Loc.FileName)
+
+				diagnostics = append(diagnostics, diagnostic{
+					message: d.Message,
+					loc:     [4]int{d.Loc.Begin.Line, d.Loc.Begin.Column, d.Loc.End.Line, d.Loc.End.Column},
+				})
+			}
+
+			assert.Equal(t, tc.expected, diagnostics)
+		})
+	}
+}
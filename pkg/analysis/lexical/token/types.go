@@ -0,0 +1,286 @@
+package token
+
+import (
+	"github.com/google/go-jsonnet/ast"
+)
+
+// TypeKind is the kind of a statically inferred type.
+type TypeKind int
+
+const (
+	// TypeAny is the kind of a value whose type can't be inferred.
+	TypeAny TypeKind = iota
+	// TypeNull is the kind of null.
+	TypeNull
+	// TypeBoolean is the kind of booleans.
+	TypeBoolean
+	// TypeNumber is the kind of numbers.
+	TypeNumber
+	// TypeString is the kind of strings.
+	TypeString
+	// TypeArray is the kind of arrays.
+	TypeArray
+	// TypeObject is the kind of objects.
+	TypeObject
+	// TypeFunction is the kind of functions.
+	TypeFunction
+)
+
+var typeKindNames = map[TypeKind]string{
+	TypeAny:      "any",
+	TypeNull:     "null",
+	TypeBoolean:  "boolean",
+	TypeNumber:   "number",
+	TypeString:   "string",
+	TypeArray:    "array",
+	TypeObject:   "object",
+	TypeFunction: "function",
+}
+
+func (k TypeKind) String() string {
+	return typeKindNames[k]
+}
+
+// Type is the statically inferred type of an expression.
+type Type struct {
+	Kind TypeKind
+	// Elem is the type of the elements of an array. It is nil if the
+	// array is empty.
+	Elem *Type
+	// Params are the parameters of a function.
+	Params []TypeParam
+	// Returns is the type of the value returned by a function.
+	Returns *Type
+	// Open is true if an object can have fields which aren't known,
+	// e.g. fields with computed names or fields added by objects
+	// composed with it.
+	Open bool
+
+	fields []*FieldType
+}
+
+// TypeParam is a parameter of a function type.
+type TypeParam struct {
+	Name string
+	// Optional is true if the parameter has a default argument.
+	Optional bool
+}
+
+// FieldType is a field of an object type. The type of a field is
+// inferred when it is first used, so fields can refer to other fields
+// of their object.
+type FieldType struct {
+	Name   string
+	Hidden bool
+	// Node is the value of the field. It is nil if the field type was
+	// combined from multiple fields.
+	Node ast.Node
+
+	hide      ast.ObjectFieldHide
+	plusSuper bool
+	resolve   func() *Type
+	typ       *Type
+}
+
+// Type is the type of the field's value.
+func (f *FieldType) Type() *Type {
+	if f.typ == nil {
+		// a field which refers to itself has an unknown type.
+		f.typ = newType(TypeAny)
+		if f.resolve != nil {
+			f.typ = f.resolve()
+		}
+	}
+
+	return f.typ
+}
+
+func newType(kind TypeKind) *Type {
+	return &Type{Kind: kind}
+}
+
+func arrayOf(elem *Type) *Type {
+	return &Type{Kind: TypeArray, Elem: elem}
+}
+
+// IsKnown returns true if the type was inferred.
+func (t *Type) IsKnown() bool {
+	return t != nil && t.Kind != TypeAny
+}
+
+// Fields returns the known fields of an object.
+func (t *Type) Fields() []*FieldType {
+	return t.fields
+}
+
+// Field returns the field named name.
+func (t *Type) Field(name string) (*FieldType, bool) {
+	for _, f := range t.fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+// String returns the name of the type, e.g. `array of string`.
+func (t *Type) String() string {
+	if t == nil {
+		return TypeAny.String()
+	}
+
+	if t.Kind == TypeArray && t.Elem.IsKnown() {
+		return "array of " + t.Elem.String()
+	}
+
+	return t.Kind.String()
+}
+
+// openDeep returns a copy of a type whose objects can have unknown
+// fields at every depth, e.g. the type of `self`, whose fields can be
+// extended by objects composed with it using `+:`.
+func (t *Type) openDeep() *Type {
+	switch t.Kind {
+	case TypeObject:
+		opened := *t
+		opened.Open = true
+		opened.fields = nil
+		for _, f := range t.fields {
+			f := f
+			field := *f
+			field.typ = nil
+			field.resolve = func() *Type { return f.Type().openDeep() }
+			opened.fields = append(opened.fields, &field)
+		}
+		return &opened
+	case TypeArray:
+		if t.Elem != nil {
+			return arrayOf(t.Elem.openDeep())
+		}
+	}
+
+	return t
+}
+
+// joinTypes returns the type of a value which is either a or b.
+func joinTypes(a, b *Type) *Type {
+	if a.Kind != b.Kind {
+		return newType(TypeAny)
+	}
+
+	switch a.Kind {
+	case TypeArray:
+		return arrayOf(joinElems(a.Elem, b.Elem))
+	case TypeObject:
+		t := &Type{Kind: TypeObject, Open: true}
+		for _, fa := range a.fields {
+			fb, ok := b.Field(fa.Name)
+			if !ok {
+				continue
+			}
+
+			fa := fa
+			t.fields = append(t.fields, &FieldType{
+				Name:    fa.Name,
+				Hidden:  fa.Hidden && fb.Hidden,
+				hide:    fa.hide,
+				resolve: func() *Type { return joinTypes(fa.Type(), fb.Type()) },
+			})
+		}
+		return t
+	case TypeFunction:
+		if len(a.Params) != len(b.Params) {
+			return newType(TypeAny)
+		}
+		for i := range a.Params {
+			if a.Params[i] != b.Params[i] {
+				return newType(TypeAny)
+			}
+		}
+		return &Type{
+			Kind:    TypeFunction,
+			Params:  a.Params,
+			Returns: joinTypes(a.Returns, b.Returns),
+		}
+	}
+
+	return a
+}
+
+// joinElems joins array element types. A nil element type is the
+// element type of an empty array.
+func joinElems(a, b *Type) *Type {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	default:
+		return joinTypes(a, b)
+	}
+}
+
+// plusTypes returns the type of `a + b`.
+func plusTypes(a, b *Type) *Type {
+	if a.Kind == TypeString || b.Kind == TypeString {
+		return newType(TypeString)
+	}
+
+	if a.Kind != b.Kind {
+		return newType(TypeAny)
+	}
+
+	switch a.Kind {
+	case TypeNumber:
+		return a
+	case TypeArray:
+		return arrayOf(joinElems(a.Elem, b.Elem))
+	case TypeObject:
+		return mergeObjectTypes(a, b)
+	}
+
+	return newType(TypeAny)
+}
+
+// mergeObjectTypes returns the type of the object composition
+// `base + t`. Fields of t override fields of base.
+func mergeObjectTypes(base, t *Type) *Type {
+	merged := &Type{
+		Kind:   TypeObject,
+		Open:   base.Open || t.Open,
+		fields: append([]*FieldType{}, base.fields...),
+	}
+
+	for _, f := range t.fields {
+		i := -1
+		for j, existing := range merged.fields {
+			if existing.Name == f.Name {
+				i = j
+				break
+			}
+		}
+
+		if i == -1 {
+			merged.fields = append(merged.fields, f)
+			continue
+		}
+
+		inherited := merged.fields[i]
+		override := *f
+		if f.hide == ast.ObjectFieldInherit {
+			override.Hidden = inherited.Hidden
+		}
+
+		if f.plusSuper {
+			f := f
+			override.Node = nil
+			override.typ = nil
+			override.resolve = func() *Type { return plusTypes(inherited.Type(), f.Type()) }
+		}
+
+		merged.fields[i] = &override
+	}
+
+	return merged
+}
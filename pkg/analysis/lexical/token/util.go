@@ -1,5 +1,9 @@
 package token
 
+import (
+	"github.com/google/go-jsonnet/ast"
+)
+
 func slicesEqual(a, b []string) bool {
 	if (a == nil) != (b == nil) {
 		return false
@@ -17,3 +21,58 @@ func slicesEqual(a, b []string) bool {
 
 	return true
 }
+
+// children returns the children of a desugared node.
+// nolint: gocyclo
+func children(n ast.Node) []ast.Node {
+	var nodes []ast.Node
+
+	switch n := n.(type) {
+	case *ast.Apply:
+		nodes = append(nodes, n.Target)
+		nodes = append(nodes, n.Arguments.Positional...)
+		for _, arg := range n.Arguments.Named {
+			nodes = append(nodes, arg.Arg)
+		}
+	case *ast.Array:
+		nodes = append(nodes, n.Elements...)
+	case *ast.Binary:
+		nodes = append(nodes, n.Left, n.Right)
+	case *ast.Conditional:
+		nodes = append(nodes, n.Cond, n.BranchTrue, n.BranchFalse)
+	case *ast.DesugaredObject:
+		for _, field := range n.Fields {
+			nodes = append(nodes, field.Name, field.Body)
+		}
+		nodes = append(nodes, n.Asserts...)
+	case *ast.Error:
+		nodes = append(nodes, n.Expr)
+	case *ast.Function:
+		for _, param := range n.Parameters.Optional {
+			nodes = append(nodes, param.DefaultArg)
+		}
+		nodes = append(nodes, n.Body)
+	case *ast.Index:
+		nodes = append(nodes, n.Target, n.Index)
+	case *ast.InSuper:
+		nodes = append(nodes, n.Index)
+	case *ast.Local:
+		for _, bind := range n.Binds {
+			nodes = append(nodes, bind.Body)
+		}
+		nodes = append(nodes, n.Body)
+	case *ast.SuperIndex:
+		nodes = append(nodes, n.Index)
+	case *ast.Unary:
+		nodes = append(nodes, n.Expr)
+	}
+
+	var out []ast.Node
+	for _, node := range nodes {
+		if node != nil {
+			out = append(out, node)
+		}
+	}
+
+	return out
+}
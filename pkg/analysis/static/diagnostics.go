@@ -0,0 +1,338 @@
+package static
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/astext"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// DiagnosticCode identifies the kind of problem a diagnostic reports.
+type DiagnosticCode string
+
+const (
+	// UnusedLocal is a local binding which is never referenced.
+	UnusedLocal DiagnosticCode = "unused-local"
+	// UnusedParameter is a function parameter which is never referenced.
+	UnusedParameter DiagnosticCode = "unused-parameter"
+	// UndefinedVariable is a reference to an identifier which is not in scope.
+	UndefinedVariable DiagnosticCode = "undefined-variable"
+	// ShadowedStd is a binding which hides the standard library.
+	ShadowedStd DiagnosticCode = "shadowed-std"
+	// DuplicateField is an object field which is defined more than once.
+	DuplicateField DiagnosticCode = "duplicate-field"
+	// TypeMismatch is an operation on a value whose inferred type doesn't
+	// support it, e.g. calling a number.
+	TypeMismatch DiagnosticCode = "type-mismatch"
+)
+
+// Severity is the severity of a diagnostic. The values match the
+// severities in the language server protocol.
+type Severity int
+
+const (
+	// SeverityError is an error.
+	SeverityError Severity = 1
+	// SeverityWarning is a warning.
+	SeverityWarning Severity = 2
+	// SeverityInformation is information.
+	SeverityInformation Severity = 3
+	// SeverityHint is a hint.
+	SeverityHint Severity = 4
+)
+
+var codeSeverities = map[DiagnosticCode]Severity{
+	UnusedLocal:       SeverityWarning,
+	UnusedParameter:   SeverityHint,
+	UndefinedVariable: SeverityError,
+	ShadowedStd:       SeverityWarning,
+	DuplicateField:    SeverityError,
+	TypeMismatch:      SeverityWarning,
+}
+
+// Severity returns the severity for diagnostics with the code. Unknown
+// codes are errors.
+func (c DiagnosticCode) Severity() Severity {
+	s, ok := codeSeverities[c]
+	if !ok {
+		return SeverityError
+	}
+
+	return s
+}
+
+// Diagnostic is a problem found while analyzing a node.
+type Diagnostic struct {
+	Code    DiagnosticCode
+	Message string
+	Loc     ast.LocationRange
+}
+
+// Diagnose finds unused locals and parameters, undefined variables, and
+// bindings which shadow std in a node which has been parsed, but not
+// desugared. Diagnostics are sorted by location.
+func Diagnose(node ast.Node) []Diagnostic {
+	d := &diagnoser{}
+
+	root := newDiagScope(nil)
+	root.vars["std"] = &diagBinding{used: true}
+
+	d.visit(node, root)
+
+	sort.SliceStable(d.diagnostics, func(i, j int) bool {
+		a, b := d.diagnostics[i].Loc.Begin, d.diagnostics[j].Loc.Begin
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return d.diagnostics
+}
+
+type diagBinding struct {
+	name ast.Identifier
+	loc  ast.LocationRange
+	// unused is the code reported if the binding is never referenced.
+	// Bindings with an empty code are not reported.
+	unused DiagnosticCode
+	used   bool
+}
+
+type diagScope struct {
+	parent *diagScope
+	vars   map[ast.Identifier]*diagBinding
+	order  []*diagBinding
+}
+
+func newDiagScope(parent *diagScope) *diagScope {
+	return &diagScope{
+		parent: parent,
+		vars:   make(map[ast.Identifier]*diagBinding),
+	}
+}
+
+func (s *diagScope) lookup(id ast.Identifier) (*diagBinding, bool) {
+	for cur := s; cur != nil; cur = cur.parent {
+		if b, ok := cur.vars[id]; ok {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+type diagnoser struct {
+	diagnostics []Diagnostic
+}
+
+func (d *diagnoser) report(code DiagnosticCode, loc ast.LocationRange, format string, args ...interface{}) {
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Loc:     loc,
+	})
+}
+
+func (d *diagnoser) declare(s *diagScope, id ast.Identifier, loc ast.LocationRange, unused DiagnosticCode) {
+	if id == "std" {
+		d.report(ShadowedStd, loc, "%s shadows the standard library", id)
+	}
+
+	b := &diagBinding{
+		name:   id,
+		loc:    loc,
+		unused: unused,
+	}
+
+	s.vars[id] = b
+	s.order = append(s.order, b)
+}
+
+// close reports the bindings in a scope which were never referenced.
+func (d *diagnoser) close(s *diagScope) {
+	for _, b := range s.order {
+		if b.used || b.unused == "" {
+			continue
+		}
+
+		switch b.unused {
+		case UnusedParameter:
+			d.report(b.unused, b.loc, "parameter %s is not used", b.name)
+		default:
+			d.report(b.unused, b.loc, "local %s is not used", b.name)
+		}
+	}
+}
+
+// nolint: gocyclo
+func (d *diagnoser) visit(node ast.Node, s *diagScope) {
+	switch n := node.(type) {
+	case nil:
+	case *ast.Apply:
+		d.visit(n.Target, s)
+		for _, arg := range n.Arguments.Positional {
+			d.visit(arg, s)
+		}
+		for _, arg := range n.Arguments.Named {
+			d.visit(arg.Arg, s)
+		}
+	case *ast.ApplyBrace:
+		d.visit(n.Left, s)
+		d.visit(n.Right, s)
+	case *ast.Array:
+		for _, elem := range n.Elements {
+			d.visit(elem, s)
+		}
+	case *ast.ArrayComp:
+		cs := d.visitForSpec(&n.Spec, s)
+		d.visit(n.Body, cs)
+	case *ast.Assert:
+		d.visit(n.Cond, s)
+		d.visit(n.Message, s)
+		d.visit(n.Rest, s)
+	case *ast.Binary:
+		d.visit(n.Left, s)
+		d.visit(n.Right, s)
+	case *ast.Conditional:
+		d.visit(n.Cond, s)
+		d.visit(n.BranchTrue, s)
+		d.visit(n.BranchFalse, s)
+	case *ast.Error:
+		d.visit(n.Expr, s)
+	case *ast.Function:
+		d.visitFunction(n, *n.Loc(), s)
+	case *ast.Index:
+		d.visit(n.Target, s)
+		d.visit(n.Index, s)
+	case *ast.InSuper:
+		d.visit(n.Index, s)
+	case *ast.Local:
+		ls := newDiagScope(s)
+		for _, bind := range n.Binds {
+			d.declare(ls, bind.Variable, bind.VarLoc, UnusedLocal)
+		}
+		// binds can be mutually recursive.
+		for _, bind := range n.Binds {
+			if bind.Fun != nil {
+				d.visitFunction(bind.Fun, bind.VarLoc, ls)
+				continue
+			}
+			d.visit(bind.Body, ls)
+		}
+		d.visit(n.Body, ls)
+		d.close(ls)
+	case *ast.Object:
+		d.visitObject(n.Fields, s)
+	case *ast.ObjectComp:
+		cs := d.visitForSpec(&n.Spec, s)
+		d.visitObject(n.Fields, cs)
+	case *ast.Parens:
+		d.visit(n.Inner, s)
+	case *ast.Slice:
+		d.visit(n.Target, s)
+		d.visit(n.BeginIndex, s)
+		d.visit(n.EndIndex, s)
+		d.visit(n.Step, s)
+	case *ast.SuperIndex:
+		d.visit(n.Index, s)
+	case *ast.Unary:
+		d.visit(n.Expr, s)
+	case *ast.Var:
+		b, ok := s.lookup(n.Id)
+		if !ok {
+			d.report(UndefinedVariable, *n.Loc(), "%s is not defined", n.Id)
+			return
+		}
+		b.used = true
+	case *astext.PartialIndex:
+		d.visit(n.Target, s)
+	}
+}
+
+// visitFunction visits a function. loc is used for parameters without
+// a location.
+func (d *diagnoser) visitFunction(fn *ast.Function, loc ast.LocationRange, s *diagScope) {
+	fs := newDiagScope(s)
+	for _, param := range fn.Parameters.Required {
+		paramLoc, ok := fn.Parameters.RequiredLocs[param]
+		if !ok {
+			paramLoc = loc
+		}
+		d.declare(fs, param, paramLoc, UnusedParameter)
+	}
+	for _, param := range fn.Parameters.Optional {
+		d.declare(fs, param.Name, param.Loc, UnusedParameter)
+	}
+
+	// default arguments can refer to other parameters.
+	for _, param := range fn.Parameters.Optional {
+		d.visit(param.DefaultArg, fs)
+	}
+	d.visit(fn.Body, fs)
+	d.close(fs)
+}
+
+// visitObject visits object fields. Computed field names are visited
+// in s and the remainder of the fields are visited in a scope
+// containing the object locals.
+func (d *diagnoser) visitObject(fields ast.ObjectFields, s *diagScope) {
+	objScope := newDiagScope(s)
+	for _, field := range fields {
+		if field.Kind == ast.ObjectLocal && field.Id != nil {
+			// object locals don't retain the location of their
+			// name, so the location of the body is used instead.
+			var loc ast.LocationRange
+			if field.Expr2 != nil {
+				loc = *field.Expr2.Loc()
+			}
+			d.declare(objScope, *field.Id, loc, UnusedLocal)
+		}
+	}
+
+	for _, field := range fields {
+		switch field.Kind {
+		case ast.ObjectFieldExpr, ast.ObjectNullExpr:
+			d.visit(field.Expr1, s)
+		}
+
+		if field.Method != nil {
+			var loc ast.LocationRange
+			if field.Expr2 != nil {
+				loc = *field.Expr2.Loc()
+			}
+			d.visitFunction(field.Method, loc, objScope)
+			continue
+		}
+
+		d.visit(field.Expr2, objScope)
+		d.visit(field.Expr3, objScope)
+	}
+
+	d.close(objScope)
+}
+
+// visitForSpec visits a comprehension's for specs. It returns the
+// scope containing the for variables.
+func (d *diagnoser) visitForSpec(spec *ast.ForSpec, s *diagScope) *diagScope {
+	if spec.Outer != nil {
+		s = d.visitForSpec(spec.Outer, s)
+	}
+
+	d.visit(spec.Expr, s)
+
+	var loc ast.LocationRange
+	if spec.Expr != nil {
+		loc = *spec.Expr.Loc()
+	}
+
+	fs := newDiagScope(s)
+	d.declare(fs, spec.VarName, loc, "")
+
+	for _, cond := range spec.Conditions {
+		d.visit(cond.Expr, fs)
+	}
+
+	return fs
+}
@@ -0,0 +1,118 @@
+package static_test
+
+import (
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	type diagnostic struct {
+		code static.DiagnosticCode
+		line int
+		col  int
+	}
+
+	cases := []struct {
+		name     string
+		source   string
+		expected []diagnostic
+	}{
+		{
+			name:   "no problems",
+			source: "local a = 1; local f(x) = x + a; { b: f(2), c: std.length([]) }",
+		},
+		{
+			name:   "unused local",
+			source: "local a = 1; local b = 2; a",
+			expected: []diagnostic{
+				{code: static.UnusedLocal, line: 1, col: 20},
+			},
+		},
+		{
+			name:   "unused parameter",
+			source: "local f(x, y=1) = x; f(1)",
+			expected: []diagnostic{
+				{code: static.UnusedParameter, line: 1, col: 12},
+			},
+		},
+		{
+			name:   "undefined variable",
+			source: "local a = 1; a + b",
+			expected: []diagnostic{
+				{code: static.UndefinedVariable, line: 1, col: 18},
+			},
+		},
+		{
+			name:   "shadowed std",
+			source: "local std = {}; std",
+			expected: []diagnostic{
+				{code: static.ShadowedStd, line: 1, col: 7},
+			},
+		},
+		{
+			name:   "recursive locals",
+			source: "local a = b, b = 1; a",
+		},
+		{
+			name:   "object locals",
+			source: "{ local a = 1, local b = 2, c: a }",
+			expected: []diagnostic{
+				{code: static.UnusedLocal, line: 1, col: 26},
+			},
+		},
+		{
+			name:   "object locals are not visible in field names",
+			source: "{ local a = 'x', [a]: a }",
+			expected: []diagnostic{
+				{code: static.UndefinedVariable, line: 1, col: 19},
+			},
+		},
+		{
+			name:   "methods",
+			source: "{ f(x, y):: x }",
+			expected: []diagnostic{
+				{code: static.UnusedParameter, line: 1, col: 8},
+			},
+		},
+		{
+			name:   "comprehensions",
+			source: "{ [k]: v for k in ['a'] for v in [k] if v != z }",
+			expected: []diagnostic{
+				{code: static.UndefinedVariable, line: 1, col: 46},
+			},
+		},
+		{
+			name:   "array comprehension",
+			source: "[x for x in [1, 2]]",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, err := token.Parse("file.jsonnet", tc.source, nil)
+			require.NoError(t, err)
+
+			var got []diagnostic
+			for _, d := range static.Diagnose(node) {
+				got = append(got, diagnostic{
+					code: d.Code,
+					line: d.Loc.Begin.Line,
+					col:  d.Loc.Begin.Column,
+				})
+			}
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestDiagnosticCode_Severity(t *testing.T) {
+	assert.Equal(t, static.SeverityWarning, static.UnusedLocal.Severity())
+	assert.Equal(t, static.SeverityHint, static.UnusedParameter.Severity())
+	assert.Equal(t, static.SeverityError, static.UndefinedVariable.Severity())
+	assert.Equal(t, static.SeverityError, static.DiagnosticCode("unknown").Severity())
+}
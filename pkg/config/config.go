@@ -5,38 +5,165 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/bryanl/jsonnet-language-server/pkg/formatter"
 	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
 	"github.com/bryanl/jsonnet-language-server/pkg/tracing"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
 	"github.com/pkg/errors"
 )
 
 const (
+	// SettingsSection is the section containing the jsonnet settings in
+	// the client configuration.
+	SettingsSection = "jsonnet"
+
 	// JsonnetLibPaths are jsonnet lib paths.
 	JsonnetLibPaths = "jsonnet.libPaths"
 
+	// JsonnetEvalDiagnostics enables evaluation diagnostics.
+	JsonnetEvalDiagnostics = "jsonnet.evalDiagnostics"
+
+	// JsonnetEvalTimeout is the evaluation timeout in milliseconds.
+	JsonnetEvalTimeout = "jsonnet.evalTimeout"
+
+	// JsonnetExtVars are external string variables.
+	JsonnetExtVars = "jsonnet.extVars"
+
+	// JsonnetExtCode are external code variables.
+	JsonnetExtCode = "jsonnet.extCode"
+
+	// JsonnetTLAVars are top level string arguments.
+	JsonnetTLAVars = "jsonnet.tlaVars"
+
+	// JsonnetTLACode are top level code arguments.
+	JsonnetTLACode = "jsonnet.tlaCode"
+
+	// JsonnetExtVarFiles are external string variables read from files.
+	JsonnetExtVarFiles = "jsonnet.extVarFiles"
+
+	// JsonnetExtCodeFiles are external code variables read from files.
+	JsonnetExtCodeFiles = "jsonnet.extCodeFiles"
+
+	// JsonnetTLAVarFiles are top level string arguments read from files.
+	JsonnetTLAVarFiles = "jsonnet.tlaVarFiles"
+
+	// JsonnetTLACodeFiles are top level code arguments read from files.
+	JsonnetTLACodeFiles = "jsonnet.tlaCodeFiles"
+
+	// JsonnetLint sets the level of diagnostics by code, e.g.
+	// `{"unused-parameter": "off"}`.
+	JsonnetLint = "jsonnet.lint"
+
+	// JsonnetSnippets are user defined completion snippets by name, e.g.
+	// `{"deployment": {"prefix": "deploy", "body": ["..."]}}`.
+	JsonnetSnippets = "jsonnet.snippets"
+
+	// JsonnetInlayHints toggles the kinds of inlay hints, e.g.
+	// `{"parameterNames": true, "values": false}`.
+	JsonnetInlayHints = "jsonnet.inlayHints"
+
+	// JsonnetFormat are formatting options, e.g.
+	// `{"stringStyle": "double", "trailingCommas": false}`.
+	JsonnetFormat = "jsonnet.format"
+
 	// TextDocumentUpdates are text document updates.
 	TextDocumentUpdates = "textDocument.update"
+
+	// defaultEvalTimeout is the default evaluation timeout.
+	defaultEvalTimeout = 5 * time.Second
 )
 
+// vmVariableSettings are the settings for external variables and top
+// level arguments, and how they are added to an IdentifyConfig.
+var vmVariableSettings = []struct {
+	key  string
+	file bool
+	set  func(*token.IdentifyConfig, string, string)
+}{
+	{key: JsonnetExtVars, set: (*token.IdentifyConfig).ExtVar},
+	{key: JsonnetExtCode, set: (*token.IdentifyConfig).ExtCode},
+	{key: JsonnetTLAVars, set: (*token.IdentifyConfig).TLAVar},
+	{key: JsonnetTLACode, set: (*token.IdentifyConfig).TLACode},
+	{key: JsonnetExtVarFiles, file: true, set: (*token.IdentifyConfig).ExtVar},
+	{key: JsonnetExtCodeFiles, file: true, set: (*token.IdentifyConfig).ExtCode},
+	{key: JsonnetTLAVarFiles, file: true, set: (*token.IdentifyConfig).TLAVar},
+	{key: JsonnetTLACodeFiles, file: true, set: (*token.IdentifyConfig).TLACode},
+}
+
+// VMVariableSettings are the keys of the settings for external variables
+// and top level arguments.
+func VMVariableSettings() []string {
+	var keys []string
+	for _, setting := range vmVariableSettings {
+		keys = append(keys, setting.key)
+	}
+
+	return keys
+}
+
+// Snippet is a user defined completion snippet. The body uses the LSP
+// snippet syntax.
+type Snippet struct {
+	// Prefix is the text completed by the snippet.
+	Prefix      string
+	Body        string
+	Description string
+}
+
 // Config is configuration setting for the server.
 type Config struct {
-	textDocuments   map[string]TextDocument
-	jsonnetLibPaths []string
-	nodeCache       *token.NodeCache
-	dispatchers     map[string]*Dispatcher
+	textDocuments    map[string]TextDocument
+	jsonnetLibPaths  []string
+	evalDiagnostics  bool
+	evalTimeout      time.Duration
+	vmVariables      map[string]map[string]string
+	lint             map[static.DiagnosticCode]static.Severity
+	snippets         []Snippet
+	inlayHints       token.InlayHintOptions
+	format           formatter.Options
+	settings         map[string]interface{}
+	projects         *projectCache
+	workspaceFolders *workspaceFolders
+	capabilities     lsp.ClientCapabilities
+	rootPath         string
+	nodeCache        *token.NodeCache
+	symbolIndex      *token.SymbolIndex
+	dispatchers      map[string]*Dispatcher
+
+	// mu guards textDocuments, vmVariables, jsonnetLibPaths and lint,
+	// which are read by watchers and evaluations while requests update
+	// them. Configurations for paths share it with their parent.
+	mu *sync.RWMutex
 }
 
 // New creates an instance of Config.
 func New() *Config {
 	return &Config{
-		textDocuments:   make(map[string]TextDocument),
-		jsonnetLibPaths: make([]string, 0),
-		nodeCache:       token.NewNodeCache(),
-		dispatchers:     map[string]*Dispatcher{},
+		textDocuments:    make(map[string]TextDocument),
+		jsonnetLibPaths:  make([]string, 0),
+		evalTimeout:      defaultEvalTimeout,
+		vmVariables:      make(map[string]map[string]string),
+		lint:             make(map[static.DiagnosticCode]static.Severity),
+		inlayHints:       token.DefaultInlayHintOptions(),
+		format:           formatter.DefaultOptions(),
+		settings:         make(map[string]interface{}),
+		projects:         newProjectCache(),
+		workspaceFolders: newWorkspaceFolders(),
+		nodeCache:        token.NewNodeCache(),
+		symbolIndex:      token.NewSymbolIndex(),
+		dispatchers:      map[string]*Dispatcher{},
+		mu:               &sync.RWMutex{},
 	}
 }
 
@@ -45,16 +172,281 @@ func (c *Config) NodeCache() *token.NodeCache {
 	return c.nodeCache
 }
 
+// SymbolIndex returns the workspace symbol index.
+func (c *Config) SymbolIndex() *token.SymbolIndex {
+	return c.symbolIndex
+}
+
+// RootPath returns the workspace root path.
+func (c *Config) RootPath() string {
+	return c.rootPath
+}
+
+// SetRootPath sets the workspace root path.
+func (c *Config) SetRootPath(path string) {
+	c.rootPath = path
+}
+
+// ClientCapabilities returns the capabilities of the client.
+func (c *Config) ClientCapabilities() lsp.ClientCapabilities {
+	return c.capabilities
+}
+
+// SnippetSupport returns true if the client supports snippets in
+// completion items.
+func (c *Config) SnippetSupport() bool {
+	completion := c.capabilities.TextDocument.Completion
+	return completion != nil && completion.CompletionItem != nil &&
+		completion.CompletionItem.SnippetSupport
+}
+
+// SetClientCapabilities sets the capabilities of the client.
+func (c *Config) SetClientCapabilities(capabilities lsp.ClientCapabilities) {
+	c.capabilities = capabilities
+}
+
 // JsonnetLibPaths returns Jsonnet lib paths.
 func (c *Config) JsonnetLibPaths() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return c.jsonnetLibPaths
 }
 
+// EvalDiagnostics returns true if evaluation diagnostics are enabled.
+func (c *Config) EvalDiagnostics() bool {
+	return c.evalDiagnostics
+}
+
+// EvalTimeout returns the timeout for evaluating a document.
+func (c *Config) EvalTimeout() time.Duration {
+	return c.evalTimeout
+}
+
+// Snippets returns the user defined completion snippets.
+func (c *Config) Snippets() []Snippet {
+	return c.snippets
+}
+
+// InlayHints returns the kinds of inlay hints which are shown.
+func (c *Config) InlayHints() token.InlayHintOptions {
+	return c.inlayHints
+}
+
+// FormatOptions returns the formatting options. The indentation is
+// replaced by the tab size of formatting requests.
+func (c *Config) FormatOptions() formatter.Options {
+	return c.format
+}
+
+// VMVariables returns the variables set by an external variable or top
+// level argument setting. The values of file settings are file paths.
+func (c *Config) VMVariables(k string) map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make(map[string]string)
+	for name, v := range c.vmVariables[k] {
+		out[name] = v
+	}
+
+	return out
+}
+
+// ForPath returns the configuration for the document at path. If the
+// document is in a workspace folder, the configuration uses the folder's
+// node cache and root path. Project and folder settings are merged with
+// the client settings: lib paths of the detected project type and
+// project lib paths are searched first, then folder and client lib
+// paths, and folder variables and lint levels replace the client's,
+// which replace the project's. The returned configuration
+// shares documents and watchers with c.
+func (c *Config) ForPath(path string) (*Config, error) {
+	// configurations for paths don't contain projects.
+	if c.projects == nil {
+		return c, nil
+	}
+
+	var project *Project
+
+	dir, inProject := FindProject(path)
+	if inProject {
+		p, err := c.projects.get(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		project = p
+	}
+
+	// errors detecting the project type are reported when the
+	// document is evaluated.
+	info, detected, err := token.DetectProject(path)
+	detected = err == nil && detected && len(info.LibPaths) > 0
+
+	folder, inFolder := c.workspaceFolders.find(path)
+	if !inProject && !inFolder && !detected {
+		return c, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// the path's configuration is a snapshot of the settings.
+	pc := *c
+	pc.projects = nil
+
+	// settings are listed from the least to the most specific.
+	settings := []*Project{project, {
+		libPaths:    c.jsonnetLibPaths,
+		vmVariables: c.vmVariables,
+		lint:        c.lint,
+	}}
+
+	if inFolder {
+		pc.rootPath = folder.Path
+		pc.nodeCache = folder.nodeCache
+		settings = append(settings, folder.settings)
+	}
+
+	pc.jsonnetLibPaths = []string{}
+	pc.vmVariables = make(map[string]map[string]string)
+	pc.lint = make(map[static.DiagnosticCode]static.Severity)
+
+	for _, setting := range vmVariableSettings {
+		pc.vmVariables[setting.key] = make(map[string]string)
+	}
+
+	for _, p := range settings {
+		if p == nil {
+			continue
+		}
+
+		for _, setting := range vmVariableSettings {
+			for name, v := range p.vmVariables[setting.key] {
+				pc.vmVariables[setting.key][name] = v
+			}
+		}
+
+		for code, severity := range p.lint {
+			pc.lint[code] = severity
+		}
+	}
+
+	// lib paths of the project type, e.g. a Tanka environment, are
+	// searched first, then project, folder and client lib paths.
+	if detected {
+		pc.jsonnetLibPaths = append(pc.jsonnetLibPaths, info.LibPaths...)
+	}
+	if project != nil {
+		pc.jsonnetLibPaths = append(pc.jsonnetLibPaths, project.libPaths...)
+	}
+	if inFolder {
+		pc.jsonnetLibPaths = append(pc.jsonnetLibPaths, folder.settings.libPaths...)
+	}
+	pc.jsonnetLibPaths = append(pc.jsonnetLibPaths, c.jsonnetLibPaths...)
+
+	seen := make(map[string]bool)
+	libPaths := pc.jsonnetLibPaths[:0]
+	for _, libPath := range pc.jsonnetLibPaths {
+		if !seen[libPath] {
+			seen[libPath] = true
+			libPaths = append(libPaths, libPath)
+		}
+	}
+	pc.jsonnetLibPaths = libPaths
+
+	return &pc, nil
+}
+
+// ReloadProject discards the loaded project containing path if path is
+// a project file, so it is loaded again when it is next used. It returns
+// true if a project was discarded.
+func (c *Config) ReloadProject(path string) bool {
+	if c.projects == nil || !IsProjectFile(path) {
+		return false
+	}
+
+	c.projects.remove(filepath.Dir(path))
+	return true
+}
+
+// DiagnosticSeverity returns the severity of diagnostics with a code in
+// the document at path. It returns false if the diagnostics are off.
+func (c *Config) DiagnosticSeverity(path string, code static.DiagnosticCode) (static.Severity, bool) {
+	pc, err := c.ForPath(path)
+	if err != nil {
+		pc = c
+	}
+
+	pc.mu.RLock()
+	severity, ok := pc.lint[code]
+	pc.mu.RUnlock()
+
+	if !ok {
+		return code.Severity(), true
+	}
+
+	return severity, severity != 0
+}
+
+// IdentifyConfig creates an IdentifyConfig for path using the lib paths,
+// external variables and top level arguments from the configuration for
+// path. Relative variable file paths are resolved from the root path of
+// the configuration for path.
+func (c *Config) IdentifyConfig(path string) (token.IdentifyConfig, error) {
+	pc, err := c.ForPath(path)
+	if err != nil {
+		return token.IdentifyConfig{}, err
+	}
+
+	ic, err := token.NewIdentifyConfig(path, pc.JsonnetLibPaths()...)
+	if err != nil {
+		return token.IdentifyConfig{}, err
+	}
+
+	for _, setting := range vmVariableSettings {
+		for name, v := range pc.VMVariables(setting.key) {
+			if setting.file {
+				if !filepath.IsAbs(v) {
+					v = filepath.Join(pc.rootPath, v)
+				}
+
+				data, err := ioutil.ReadFile(v)
+				if err != nil {
+					return token.IdentifyConfig{}, errors.Wrapf(err, "reading %q for %q in %q", v, name, setting.key)
+				}
+
+				v = string(data)
+			}
+
+			setting.set(&ic, name, v)
+		}
+	}
+
+	return ic, nil
+}
+
+// TextDocuments returns the text documents that are open.
+func (c *Config) TextDocuments() []TextDocument {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var docs []TextDocument
+	for _, td := range c.textDocuments {
+		docs = append(docs, td)
+	}
+
+	return docs
+}
+
 // StoreTextDocumentItem stores a text document item.
 func (c *Config) StoreTextDocumentItem(ctx context.Context, td TextDocument) error {
 	span, ctx := tracing.ChildSpan(ctx, "storeTextDocument")
 	defer span.Finish()
 
+	c.mu.Lock()
+
 	oldDoc, ok := c.textDocuments[td.uri]
 	if !ok {
 		oldDoc = td
@@ -64,34 +456,70 @@ func (c *Config) StoreTextDocumentItem(ctx context.Context, td TextDocument) err
 		log.String("textdocument.store", td.uri),
 	)
 
-	oldDoc.text = td.text
+	oldDoc.buffer = td.buffer
 	oldDoc.version = td.version
 
 	c.textDocuments[td.uri] = td
+	c.mu.Unlock()
+
 	c.dispatch(ctx, TextDocumentUpdates, td)
 	return nil
 }
 
-// UpdateTextDocumentItem updates a text document item with a change event.
+// UpdateTextDocumentItem updates a text document item with change
+// events. Changes are applied in order. Changes for versions older than
+// the stored document are rejected.
 func (c *Config) UpdateTextDocumentItem(ctx context.Context, dctdp lsp.DidChangeTextDocumentParams) error {
-	// The language server is configured to request for full content changes,
-	// so the text in the change event is a full document.
+	uriStr := dctdp.TextDocument.URI
+
+	c.mu.RLock()
+	td, ok := c.textDocuments[uriStr]
+	c.mu.RUnlock()
+
+	if !ok {
+		return errors.Errorf("text document %q is not open", uriStr)
+	}
 
-	td := TextDocument{
-		text:    dctdp.ContentChanges[0].Text,
-		uri:     dctdp.TextDocument.URI,
-		version: dctdp.TextDocument.Version,
+	if dctdp.TextDocument.Version <= td.version {
+		return errors.Errorf("text document %q change version %d is not newer than %d",
+			uriStr, dctdp.TextDocument.Version, td.version)
 	}
 
+	for _, change := range dctdp.ContentChanges {
+		if err := td.Apply(change); err != nil {
+			return err
+		}
+	}
+
+	td.version = dctdp.TextDocument.Version
+
 	return c.StoreTextDocumentItem(ctx, td)
 }
 
+// RefreshTextDocument re-dispatches an open text document so watchers
+// can re-analyze it, e.g. after one of its imports changed on disk.
+func (c *Config) RefreshTextDocument(ctx context.Context, uriStr string) error {
+	c.mu.RLock()
+	td, ok := c.textDocuments[uriStr]
+	c.mu.RUnlock()
+
+	if !ok {
+		return errors.Errorf("text document %q is not open", uriStr)
+	}
+
+	c.dispatch(ctx, TextDocumentUpdates, td)
+	return nil
+}
+
 // Text retrieves text from our local cache or from the file system.
 func (c *Config) Text(ctx context.Context, uriStr string) (*TextDocument, error) {
 	span, ctx := tracing.ChildSpan(ctx, "retrieveText")
 	defer span.Finish()
 
+	c.mu.RLock()
 	text, ok := c.textDocuments[uriStr]
+	c.mu.RUnlock()
+
 	if ok {
 		span.LogFields(
 			log.String("config.retrieveFromCache", uriStr),
@@ -114,14 +542,12 @@ func (c *Config) Text(ctx context.Context, uriStr string) (*TextDocument, error)
 		return nil, err
 	}
 
-	td := &TextDocument{
-		text: string(data),
-	}
+	td := NewTextDocument(uriStr, string(data))
 
-	return td, nil
+	return &td, nil
 }
 
-// Watch will call `fn`` when key `k` is updated. It returns a
+// Watch will call `fn“ when key `k` is updated. It returns a
 // cancel function.
 func (c *Config) Watch(k string, fn DispatchFn) DispatchCancelFn {
 	d := c.dispatcher(k)
@@ -143,25 +569,155 @@ func (c *Config) dispatch(ctx context.Context, k string, msg interface{}) {
 	d.Dispatch(ctx, msg)
 }
 
-// UpdateClientConfiguration updates the configuration.
+// unknownSettingErr is an error for a setting the server doesn't know.
+type unknownSettingErr struct {
+	key string
+}
+
+func (e *unknownSettingErr) Error() string {
+	return fmt.Sprintf("setting %q is unknown to the jsonnet language server", e.key)
+}
+
+// UpdateClientConfiguration updates the configuration. Settings are
+// applied in the order of their keys.
 func (c *Config) UpdateClientConfiguration(ctx context.Context, update map[string]interface{}) error {
-	for k, v := range update {
-		switch k {
-		case JsonnetLibPaths:
-			paths, err := interfaceToStrings(v)
-			if err != nil {
-				return errors.Wrapf(err, "setting %q", JsonnetLibPaths)
-			}
+	for _, k := range sortedKeys(update) {
+		if err := c.updateSetting(ctx, k, update[k]); err != nil {
+			return err
+		}
+	}
 
-			c.jsonnetLibPaths = paths
-			c.dispatch(ctx, JsonnetLibPaths, paths)
-		default:
-			return errors.Errorf("setting %q is unknown to the jsonnet language server", k)
+	return nil
+}
+
+func (c *Config) updateSetting(ctx context.Context, k string, v interface{}) error {
+	switch k {
+	case JsonnetLibPaths:
+		paths, err := interfaceToStrings(v)
+		if err != nil {
+			return errors.Wrapf(err, "setting %q", JsonnetLibPaths)
+		}
+
+		c.mu.Lock()
+		c.jsonnetLibPaths = paths
+		c.mu.Unlock()
+
+		c.dispatch(ctx, JsonnetLibPaths, paths)
+	case JsonnetEvalDiagnostics:
+		enabled, ok := v.(bool)
+		if !ok {
+			return errors.Errorf("setting %q: unable to convert %T to bool", JsonnetEvalDiagnostics, v)
+		}
+
+		c.evalDiagnostics = enabled
+		c.dispatch(ctx, JsonnetEvalDiagnostics, enabled)
+	case JsonnetEvalTimeout:
+		ms, err := interfaceToInt(v)
+		if err != nil {
+			return errors.Wrapf(err, "setting %q", JsonnetEvalTimeout)
+		}
+
+		if ms <= 0 {
+			return errors.Errorf("setting %q: timeout must be positive", JsonnetEvalTimeout)
+		}
+
+		c.evalTimeout = time.Duration(ms) * time.Millisecond
+	case JsonnetExtVars, JsonnetExtCode, JsonnetTLAVars, JsonnetTLACode,
+		JsonnetExtVarFiles, JsonnetExtCodeFiles, JsonnetTLAVarFiles, JsonnetTLACodeFiles:
+		variables, err := interfaceToStringMap(v)
+		if err != nil {
+			return errors.Wrapf(err, "setting %q", k)
+		}
+
+		c.mu.Lock()
+		c.vmVariables[k] = variables
+		c.mu.Unlock()
+
+		c.dispatch(ctx, k, variables)
+	case JsonnetLint:
+		lint, err := interfaceToLint(v)
+		if err != nil {
+			return errors.Wrapf(err, "setting %q", JsonnetLint)
+		}
+
+		c.mu.Lock()
+		c.lint = lint
+		c.mu.Unlock()
+
+		c.dispatch(ctx, JsonnetLint, lint)
+	case JsonnetSnippets:
+		snippets, err := interfaceToSnippets(v)
+		if err != nil {
+			return errors.Wrapf(err, "setting %q", JsonnetSnippets)
 		}
+
+		c.snippets = snippets
+	case JsonnetInlayHints:
+		options, err := interfaceToInlayHintOptions(v)
+		if err != nil {
+			return errors.Wrapf(err, "setting %q", JsonnetInlayHints)
+		}
+
+		c.inlayHints = options
+	case JsonnetFormat:
+		options, err := interfaceToFormatOptions(v)
+		if err != nil {
+			return errors.Wrapf(err, "setting %q", JsonnetFormat)
+		}
+
+		c.format = options
+	default:
+		return &unknownSettingErr{key: k}
 	}
+
+	c.settings[k] = v
+
 	return nil
 }
 
+// UpdateSettings updates the configuration from the jsonnet section of
+// the client settings, e.g. `{"libPaths": ["vendor"]}`. Only settings
+// whose values changed are updated, so watchers are only notified about
+// changes. Unknown settings are skipped, e.g. settings of newer versions
+// of the server, and the others are applied in the order of their keys.
+func (c *Config) UpdateSettings(ctx context.Context, section map[string]interface{}) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "updateSettings")
+	defer span.Finish()
+
+	for _, k := range sortedKeys(section) {
+		key := SettingsSection + "." + k
+		v := section[k]
+		if current, ok := c.settings[key]; ok && reflect.DeepEqual(current, v) {
+			continue
+		}
+
+		err := c.updateSetting(ctx, key, v)
+		if _, ok := err.(*unknownSettingErr); ok {
+			span.LogFields(
+				log.String("setting", key),
+				log.Error(err),
+			)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
 func (c *Config) String() string {
 	data, err := c.MarshalJSON()
 	if err != nil {
@@ -183,6 +739,141 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&cm)
 }
 
+// interfaceToSnippets converts snippets by name to snippets sorted by
+// name. Bodies can be a string or an array of lines, and the prefix
+// defaults to the name.
+func interfaceToSnippets(v interface{}) ([]Snippet, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("unable to convert %T to snippets", v)
+	}
+
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var snippets []Snippet
+	for _, name := range names {
+		fields, ok := m[name].(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("snippet %q: unable to convert %T to a snippet", name, m[name])
+		}
+
+		snippet := Snippet{Prefix: name}
+
+		if prefix, ok := fields["prefix"]; ok {
+			if snippet.Prefix, ok = prefix.(string); !ok {
+				return nil, errors.Errorf("snippet %q: prefix is not a string", name)
+			}
+		}
+
+		switch body := fields["body"].(type) {
+		case string:
+			snippet.Body = body
+		case nil:
+			return nil, errors.Errorf("snippet %q: body is missing", name)
+		default:
+			lines, err := interfaceToStrings(body)
+			if err != nil {
+				return nil, errors.Wrapf(err, "snippet %q: body", name)
+			}
+			snippet.Body = strings.Join(lines, "\n")
+		}
+
+		if description, ok := fields["description"]; ok {
+			if snippet.Description, ok = description.(string); !ok {
+				return nil, errors.Errorf("snippet %q: description is not a string", name)
+			}
+		}
+
+		snippets = append(snippets, snippet)
+	}
+
+	return snippets, nil
+}
+
+// interfaceToInlayHintOptions converts a map of inlay hint kinds to
+// booleans. Kinds which aren't set use their defaults.
+func interfaceToInlayHintOptions(v interface{}) (token.InlayHintOptions, error) {
+	options := token.DefaultInlayHintOptions()
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return options, errors.Errorf("unable to convert %T to inlay hint options", v)
+	}
+
+	for k, item := range m {
+		enabled, ok := item.(bool)
+		if !ok {
+			return options, errors.Errorf("value for %q was not a bool", k)
+		}
+
+		switch k {
+		case "parameterNames":
+			options.ParameterNames = enabled
+		case "values":
+			options.Values = enabled
+		default:
+			return options, errors.Errorf("%q is not one of parameterNames or values", k)
+		}
+	}
+
+	return options, nil
+}
+
+// interfaceToFormatOptions converts a map of formatting options. Options
+// which aren't set use their defaults.
+func interfaceToFormatOptions(v interface{}) (formatter.Options, error) {
+	options := formatter.DefaultOptions()
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return options, errors.Errorf("unable to convert %T to format options", v)
+	}
+
+	for k, item := range m {
+		if k == "stringStyle" {
+			style, ok := item.(string)
+			if !ok {
+				return options, errors.Errorf("value for %q was not a string", k)
+			}
+
+			switch style {
+			case "single":
+				options.StringStyle = formatter.StringStyleSingle
+			case "double":
+				options.StringStyle = formatter.StringStyleDouble
+			case "leave":
+				options.StringStyle = formatter.StringStyleLeave
+			default:
+				return options, errors.Errorf("string style %q is not one of single, double or leave", style)
+			}
+
+			continue
+		}
+
+		enabled, ok := item.(bool)
+		if !ok {
+			return options, errors.Errorf("value for %q was not a bool", k)
+		}
+
+		switch k {
+		case "trailingCommas":
+			options.TrailingCommas = enabled
+		case "padObjects":
+			options.PadObjects = enabled
+		case "sortImports":
+			options.SortImports = enabled
+		default:
+			return options, errors.Errorf("%q is not one of stringStyle, trailingCommas, padObjects or sortImports", k)
+		}
+	}
+
+	return options, nil
+}
+
 func interfaceToStrings(v interface{}) ([]string, error) {
 	switch v := v.(type) {
 	case []interface{}:
@@ -203,3 +894,37 @@ func interfaceToStrings(v interface{}) ([]string, error) {
 		return nil, errors.Errorf("unable to convert %T to array of strings", v)
 	}
 }
+
+func interfaceToStringMap(v interface{}) (map[string]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return map[string]string{}, nil
+	case map[string]interface{}:
+		out := make(map[string]string)
+		for k, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, errors.Errorf("value for %q was not a string", k)
+			}
+
+			out[k] = str
+		}
+
+		return out, nil
+	case map[string]string:
+		return v, nil
+	default:
+		return nil, errors.Errorf("unable to convert %T to map of strings", v)
+	}
+}
+
+func interfaceToInt(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	default:
+		return 0, errors.Errorf("unable to convert %T to int", v)
+	}
+}
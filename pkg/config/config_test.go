@@ -2,8 +2,19 @@ package config
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/bryanl/jsonnet-language-server/pkg/formatter"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -36,6 +47,116 @@ func TestConfig_UpdateClientConfiguration(t *testing.T) {
 			},
 			expected: []string{"new"},
 		},
+		{
+			name: "enable evaluation diagnostics",
+			update: map[string]interface{}{
+				"jsonnet.evalDiagnostics": true,
+			},
+			key: func(c *Config) interface{} {
+				return c.EvalDiagnostics()
+			},
+			expected: true,
+		},
+		{
+			name: "update evaluation timeout",
+			update: map[string]interface{}{
+				"jsonnet.evalTimeout": float64(250),
+			},
+			key: func(c *Config) interface{} {
+				return c.EvalTimeout()
+			},
+			expected: 250 * time.Millisecond,
+		},
+		{
+			name: "invalid evaluation timeout",
+			update: map[string]interface{}{
+				"jsonnet.evalTimeout": float64(0),
+			},
+			isErr: true,
+		},
+		{
+			name: "update ext vars",
+			update: map[string]interface{}{
+				"jsonnet.extVars": map[string]interface{}{"env": "dev"},
+			},
+			key: func(c *Config) interface{} {
+				return c.VMVariables(JsonnetExtVars)
+			},
+			expected: map[string]string{"env": "dev"},
+		},
+		{
+			name: "clear tla code files",
+			update: map[string]interface{}{
+				"jsonnet.tlaCodeFiles": nil,
+			},
+			key: func(c *Config) interface{} {
+				return c.VMVariables(JsonnetTLACodeFiles)
+			},
+			expected: map[string]string{},
+		},
+		{
+			name: "update inlay hints",
+			update: map[string]interface{}{
+				"jsonnet.inlayHints": map[string]interface{}{"values": true},
+			},
+			key: func(c *Config) interface{} {
+				return c.InlayHints()
+			},
+			expected: token.InlayHintOptions{ParameterNames: true, Values: true},
+		},
+		{
+			name: "invalid inlay hint kind",
+			update: map[string]interface{}{
+				"jsonnet.inlayHints": map[string]interface{}{"types": true},
+			},
+			isErr: true,
+		},
+		{
+			name: "update format",
+			update: map[string]interface{}{
+				"jsonnet.format": map[string]interface{}{
+					"stringStyle":    "double",
+					"trailingCommas": false,
+					"sortImports":    false,
+				},
+			},
+			key: func(c *Config) interface{} {
+				return c.FormatOptions()
+			},
+			expected: formatter.Options{
+				Indent:      2,
+				StringStyle: formatter.StringStyleDouble,
+				PadObjects:  true,
+			},
+		},
+		{
+			name: "invalid string style",
+			update: map[string]interface{}{
+				"jsonnet.format": map[string]interface{}{"stringStyle": "backtick"},
+			},
+			isErr: true,
+		},
+		{
+			name: "invalid format option",
+			update: map[string]interface{}{
+				"jsonnet.format": map[string]interface{}{"colons": true},
+			},
+			isErr: true,
+		},
+		{
+			name: "invalid lint level",
+			update: map[string]interface{}{
+				"jsonnet.lint": map[string]interface{}{"unused-local": "loud"},
+			},
+			isErr: true,
+		},
+		{
+			name: "invalid ext code value",
+			update: map[string]interface{}{
+				"jsonnet.extCode": map[string]interface{}{"replicas": float64(1)},
+			},
+			isErr: true,
+		},
 		{
 			name: "invalid setting type",
 			update: map[string]interface{}{
@@ -94,13 +215,182 @@ func TestConfig_UpdateClientConfiguration_watcher(t *testing.T) {
 	cancel()
 }
 
-func TestConfig_StoreTextDocumentItem_watcher(t *testing.T) {
+func TestConfig_UpdateSettings(t *testing.T) {
 	c := New()
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+	dispatched := make(chan interface{}, 2)
+	cancelLibPaths := c.Watch(JsonnetLibPaths, func(ctx context.Context, v interface{}) error {
+		dispatched <- v
+		return nil
+	})
+	defer cancelLibPaths()
 
-	tdi := TextDocument{
-		uri:  "file:///new",
-		text: "text",
+	cancelEval := c.Watch(JsonnetEvalDiagnostics, func(ctx context.Context, v interface{}) error {
+		dispatched <- v
+		return nil
+	})
+	defer cancelEval()
+
+	section := map[string]interface{}{
+		"libPaths":        []interface{}{"vendor"},
+		"evalDiagnostics": false,
 	}
+	require.NoError(t, c.UpdateSettings(ctx, section))
+
+	assert.Equal(t, []string{"vendor"}, c.JsonnetLibPaths())
+	assert.False(t, c.EvalDiagnostics())
+	<-dispatched
+	<-dispatched
+
+	// only changed settings are updated.
+	section["evalDiagnostics"] = true
+	require.NoError(t, c.UpdateSettings(ctx, section))
+
+	assert.True(t, c.EvalDiagnostics())
+	assert.Equal(t, true, <-dispatched)
+
+	select {
+	case v := <-dispatched:
+		t.Fatalf("unexpected dispatch of %v", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// unknown settings are skipped and the others are applied.
+	require.NoError(t, c.UpdateSettings(ctx, map[string]interface{}{
+		"unknown":         true,
+		"evalDiagnostics": false,
+	}))
+	assert.False(t, c.EvalDiagnostics())
+	assert.Equal(t, false, <-dispatched)
+
+	require.Error(t, c.UpdateSettings(ctx, map[string]interface{}{"evalTimeout": -1}))
+}
+
+func TestConfig_UpdateSettings_snippets(t *testing.T) {
+	cases := []struct {
+		name     string
+		snippets interface{}
+		expected []Snippet
+		isErr    bool
+	}{
+		{
+			name: "snippets",
+			snippets: map[string]interface{}{
+				"deployment": map[string]interface{}{
+					"prefix":      "deploy",
+					"body":        []interface{}{"{", "\tkind: 'Deployment',", "}"},
+					"description": "deployment",
+				},
+				"assert": map[string]interface{}{
+					"body": "assert ${1:condition};",
+				},
+			},
+			expected: []Snippet{
+				{Prefix: "assert", Body: "assert ${1:condition};"},
+				{Prefix: "deploy", Body: "{\n\tkind: 'Deployment',\n}", Description: "deployment"},
+			},
+		},
+		{
+			name:     "not an object",
+			snippets: []interface{}{"assert"},
+			isErr:    true,
+		},
+		{
+			name: "missing body",
+			snippets: map[string]interface{}{
+				"assert": map[string]interface{}{},
+			},
+			isErr: true,
+		},
+		{
+			name: "invalid prefix",
+			snippets: map[string]interface{}{
+				"assert": map[string]interface{}{"prefix": 1, "body": "assert"},
+			},
+			isErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+			err := c.UpdateSettings(ctx, map[string]interface{}{"snippets": tc.snippets})
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, c.Snippets())
+		})
+	}
+}
+
+func TestConfig_IdentifyConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cluster.txt"), []byte("prod"), 0644))
+
+	cases := []struct {
+		name   string
+		update map[string]interface{}
+		isErr  bool
+	}{
+		{
+			name: "variables",
+			update: map[string]interface{}{
+				JsonnetExtVars: map[string]string{"env": "dev"},
+				JsonnetTLACode: map[string]string{"replicas": "1"},
+			},
+		},
+		{
+			name: "relative file",
+			update: map[string]interface{}{
+				JsonnetExtVarFiles: map[string]string{"cluster": "cluster.txt"},
+			},
+		},
+		{
+			name: "absolute file",
+			update: map[string]interface{}{
+				JsonnetTLAVarFiles: map[string]string{"cluster": filepath.Join(dir, "cluster.txt")},
+			},
+		},
+		{
+			name: "missing file",
+			update: map[string]interface{}{
+				JsonnetExtCodeFiles: map[string]string{"cluster": "missing.jsonnet"},
+			},
+			isErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+			c.SetRootPath(dir)
+
+			ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+			require.NoError(t, c.UpdateClientConfiguration(ctx, tc.update))
+
+			_, err := c.IdentifyConfig(filepath.Join(dir, "main.jsonnet"))
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestConfig_StoreTextDocumentItem_watcher(t *testing.T) {
+	c := New()
+
+	tdi := NewTextDocument("file:///new", "text")
 
 	done := make(chan bool)
 
@@ -122,6 +412,101 @@ func TestConfig_StoreTextDocumentItem_watcher(t *testing.T) {
 	cancel()
 }
 
+func TestConfig_StoreTextDocumentItem_refreshing(t *testing.T) {
+	c := New()
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+	// watchers refresh the open documents while documents are stored.
+	refreshed := make(chan bool, 20)
+	cancel := c.Watch(JsonnetLint, func(ctx context.Context, v interface{}) error {
+		for _, td := range c.TextDocuments() {
+			filename, err := td.Filename()
+			if err != nil {
+				return err
+			}
+
+			c.DiagnosticSeverity(filename, static.UnusedLocal)
+			if _, err := c.ForPath(filename); err != nil {
+				return err
+			}
+		}
+
+		refreshed <- true
+		return nil
+	})
+	defer cancel()
+
+	for i := 0; i < 20; i++ {
+		update := map[string]interface{}{
+			JsonnetLibPaths: []interface{}{fmt.Sprintf("lib%d", i)},
+			JsonnetLint:     map[string]interface{}{"unused-local": "off"},
+		}
+		require.NoError(t, c.UpdateClientConfiguration(ctx, update))
+
+		td := NewTextDocument(fmt.Sprintf("file:///doc%d.jsonnet", i), "{}")
+		require.NoError(t, c.StoreTextDocumentItem(ctx, td))
+	}
+
+	for i := 0; i < 20; i++ {
+		<-refreshed
+	}
+
+	assert.Len(t, c.TextDocuments(), 20)
+}
+
+func TestConfig_IdentifyConfig_updating(t *testing.T) {
+	c := New()
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+	// watchers of variables evaluate documents while variables change.
+	evaluated := make(chan bool, 20)
+	cancel := c.Watch(JsonnetExtVars, func(ctx context.Context, v interface{}) error {
+		_, err := c.IdentifyConfig("/app/main.jsonnet")
+		evaluated <- true
+		return err
+	})
+	defer cancel()
+
+	for i := 0; i < 20; i++ {
+		update := map[string]interface{}{
+			JsonnetExtVars: map[string]interface{}{"env": fmt.Sprintf("env%d", i)},
+			JsonnetTLAVars: map[string]interface{}{"name": fmt.Sprintf("name%d", i)},
+		}
+		require.NoError(t, c.UpdateClientConfiguration(ctx, update))
+	}
+
+	for i := 0; i < 20; i++ {
+		<-evaluated
+	}
+
+	assert.Equal(t, map[string]string{"env": "env19"}, c.VMVariables(JsonnetExtVars))
+}
+
+func TestConfig_RefreshTextDocument(t *testing.T) {
+	c := New()
+
+	tdi := NewTextDocument("file:///new", "text")
+	c.textDocuments[tdi.uri] = tdi
+
+	done := make(chan bool)
+
+	fn := func(ctx context.Context, got interface{}) error {
+		assert.Equal(t, tdi, got)
+		done <- true
+
+		return nil
+	}
+
+	cancel := c.Watch(TextDocumentUpdates, fn)
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+	require.Error(t, c.RefreshTextDocument(ctx, "file:///missing"))
+	require.NoError(t, c.RefreshTextDocument(ctx, tdi.uri))
+
+	<-done
+	cancel()
+}
+
 func TestConfig_StoreTextDocumentItem(t *testing.T) {
 	cases := []struct {
 		name  string
@@ -136,10 +521,7 @@ func TestConfig_StoreTextDocumentItem(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			file := TextDocument{
-				uri:  tc.uri,
-				text: "text",
-			}
+			file := NewTextDocument(tc.uri, "text")
 
 			c := New()
 			require.Len(t, c.textDocuments, 0)
@@ -161,6 +543,40 @@ func TestConfig_StoreTextDocumentItem(t *testing.T) {
 	}
 }
 
+func TestConfig_SnippetSupport(t *testing.T) {
+	cases := []struct {
+		name         string
+		capabilities string
+		expected     bool
+	}{
+		{
+			name:         "supported",
+			capabilities: `{"textDocument": {"completion": {"completionItem": {"snippetSupport": true}}}}`,
+			expected:     true,
+		},
+		{
+			name:         "not supported",
+			capabilities: `{"textDocument": {"completion": {"completionItem": {"snippetSupport": false}}}}`,
+		},
+		{
+			name:         "missing",
+			capabilities: `{"textDocument": {}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var capabilities lsp.ClientCapabilities
+			require.NoError(t, json.Unmarshal([]byte(tc.capabilities), &capabilities))
+
+			c := New()
+			c.SetClientCapabilities(capabilities)
+
+			assert.Equal(t, tc.expected, c.SnippetSupport())
+		})
+	}
+}
+
 func TestConfig_String(t *testing.T) {
 	c := New()
 
@@ -177,3 +593,97 @@ func TestConfig_String(t *testing.T) {
 	expected := "{\"JsonnetLibPaths\":[\"/path\"]}"
 	assert.Equal(t, expected, got)
 }
+
+func TestConfig_UpdateTextDocumentItem(t *testing.T) {
+	cases := []struct {
+		name     string
+		uri      string
+		version  int
+		changes  []lsp.TextDocumentContentChangeEvent
+		expected string
+		isErr    bool
+	}{
+		{
+			name:    "full change",
+			uri:     "file:///file.jsonnet",
+			version: 2,
+			changes: []lsp.TextDocumentContentChangeEvent{
+				{Text: "{}"},
+			},
+			expected: "{}",
+		},
+		{
+			name:    "incremental changes",
+			uri:     "file:///file.jsonnet",
+			version: 2,
+			changes: []lsp.TextDocumentContentChangeEvent{
+				{
+					Range: &lsp.Range{
+						Start: lsp.Position{Line: 0, Character: 6},
+						End:   lsp.Position{Line: 0, Character: 7},
+					},
+					Text: "b",
+				},
+				{
+					Range: &lsp.Range{
+						Start: lsp.Position{Line: 1, Character: 0},
+						End:   lsp.Position{Line: 1, Character: 1},
+					},
+					Text: "{ b: b }",
+				},
+			},
+			expected: "local b = 1;\n{ b: b }",
+		},
+		{
+			name:    "out of order version",
+			uri:     "file:///file.jsonnet",
+			version: 1,
+			changes: []lsp.TextDocumentContentChangeEvent{
+				{Text: "{}"},
+			},
+			isErr: true,
+		},
+		{
+			name:    "document is not open",
+			uri:     "file:///other.jsonnet",
+			version: 2,
+			changes: []lsp.TextDocumentContentChangeEvent{
+				{Text: "{}"},
+			},
+			isErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := New()
+
+			td := NewTextDocument("file:///file.jsonnet", "local a = 1;\na")
+			td.version = 1
+			c.textDocuments[td.uri] = td
+
+			ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+
+			params := lsp.DidChangeTextDocumentParams{
+				TextDocument: lsp.VersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: tc.uri},
+					Version:                tc.version,
+				},
+				ContentChanges: tc.changes,
+			}
+
+			err := c.UpdateTextDocumentItem(ctx, params)
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := c.Text(ctx, tc.uri)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got.String())
+			assert.Equal(t, tc.version, got.Version())
+		})
+	}
+}
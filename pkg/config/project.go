@@ -0,0 +1,260 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProjectConfigFile is the name of the project configuration file.
+	// It contains the jsonnet settings section, e.g.
+	// `{"libPaths": ["lib"], "extVars": {"env": "dev"}}`.
+	ProjectConfigFile = ".jsonnet-ls.json"
+
+	// Jsonnetfile is the jsonnet-bundler dependency file.
+	Jsonnetfile = "jsonnetfile.json"
+
+	// JsonnetfileLock is the jsonnet-bundler lock file.
+	JsonnetfileLock = "jsonnetfile.lock.json"
+
+	// jsonnetBundlerVendor is the directory jsonnet-bundler installs
+	// dependencies into.
+	jsonnetBundlerVendor = "vendor"
+)
+
+// ProjectFiles are the files which mark the root directory of a project.
+var ProjectFiles = []string{ProjectConfigFile, Jsonnetfile, JsonnetfileLock}
+
+// IsProjectFile returns true if path is a project file.
+func IsProjectFile(path string) bool {
+	base := filepath.Base(path)
+	for _, name := range ProjectFiles {
+		if base == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Project is configuration for the documents in a directory containing
+// project files. Paths in a project are absolute.
+type Project struct {
+	// Dir is the project directory.
+	Dir string
+
+	libPaths    []string
+	vmVariables map[string]map[string]string
+	lint        map[static.DiagnosticCode]static.Severity
+}
+
+// FindProject finds the nearest directory containing path which
+// contains a project file.
+func FindProject(path string) (string, bool) {
+	dir := filepath.Dir(path)
+	for {
+		for _, name := range ProjectFiles {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir, true
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// LoadProject loads the project in dir. Settings are read from the
+// project configuration file; settings which can't be set in a project
+// are skipped. If dir contains jsonnet-bundler files, the jsonnet-bundler
+// vendor directory is added to the lib paths.
+func LoadProject(dir string) (*Project, error) {
+	span := opentracing.StartSpan("loadProject")
+	defer span.Finish()
+	span.SetTag("dir", dir)
+
+	p := newProject(dir)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, ProjectConfigFile))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		var section map[string]interface{}
+		if err := json.Unmarshal(data, &section); err != nil {
+			return nil, errors.Wrapf(err, "reading %s", filepath.Join(dir, ProjectConfigFile))
+		}
+
+		if err := p.update(span, section); err != nil {
+			return nil, errors.Wrapf(err, "reading %s", filepath.Join(dir, ProjectConfigFile))
+		}
+	}
+
+	for _, name := range []string{Jsonnetfile, JsonnetfileLock} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			p.libPaths = append(p.libPaths, filepath.Join(dir, jsonnetBundlerVendor))
+			break
+		}
+	}
+
+	return p, nil
+}
+
+func newProject(dir string) *Project {
+	return &Project{
+		Dir:         dir,
+		vmVariables: make(map[string]map[string]string),
+		lint:        make(map[static.DiagnosticCode]static.Severity),
+	}
+}
+
+func (p *Project) update(span opentracing.Span, section map[string]interface{}) error {
+	for k, v := range section {
+		key := SettingsSection + "." + k
+		file, isVariable := vmVariableSetting(key)
+
+		switch {
+		case key == JsonnetLibPaths:
+			paths, err := interfaceToStrings(v)
+			if err != nil {
+				return errors.Wrapf(err, "setting %q", k)
+			}
+
+			for _, path := range paths {
+				p.libPaths = append(p.libPaths, p.abs(path))
+			}
+		case key == JsonnetLint:
+			lint, err := interfaceToLint(v)
+			if err != nil {
+				return errors.Wrapf(err, "setting %q", k)
+			}
+
+			p.lint = lint
+		case isVariable:
+			variables, err := interfaceToStringMap(v)
+			if err != nil {
+				return errors.Wrapf(err, "setting %q", k)
+			}
+
+			if file {
+				for name, path := range variables {
+					variables[name] = p.abs(path)
+				}
+			}
+
+			p.vmVariables[key] = variables
+		default:
+			span.LogFields(
+				log.String("setting", key),
+				log.Error(errors.Errorf("setting %q can't be set in a project", k)),
+			)
+		}
+	}
+
+	return nil
+}
+
+func (p *Project) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(p.Dir, path)
+}
+
+// projectCache caches loaded projects by directory.
+type projectCache struct {
+	projects map[string]*Project
+
+	mu sync.Mutex
+}
+
+func newProjectCache() *projectCache {
+	return &projectCache{
+		projects: make(map[string]*Project),
+	}
+}
+
+func (pc *projectCache) get(dir string) (*Project, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if p, ok := pc.projects[dir]; ok {
+		return p, nil
+	}
+
+	p, err := LoadProject(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.projects[dir] = p
+	return p, nil
+}
+
+func (pc *projectCache) remove(dir string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	delete(pc.projects, dir)
+}
+
+// isProjectSetting returns true if key can be set in a project.
+func isProjectSetting(key string) bool {
+	_, isVariable := vmVariableSetting(key)
+	return isVariable || key == JsonnetLibPaths || key == JsonnetLint
+}
+
+// vmVariableSetting returns whether key is an external variable or top
+// level argument setting, and if its values are file paths.
+func vmVariableSetting(key string) (bool, bool) {
+	for _, setting := range vmVariableSettings {
+		if setting.key == key {
+			return setting.file, true
+		}
+	}
+
+	return false, false
+}
+
+var lintLevels = map[string]static.Severity{
+	"off":         0,
+	"error":       static.SeverityError,
+	"warning":     static.SeverityWarning,
+	"information": static.SeverityInformation,
+	"hint":        static.SeverityHint,
+}
+
+// interfaceToLint converts a map of diagnostic codes to levels, e.g.
+// `{"unused-parameter": "off"}`, to severities. Diagnostics which are
+// turned off have a severity of 0.
+func interfaceToLint(v interface{}) (map[static.DiagnosticCode]static.Severity, error) {
+	levels, err := interfaceToStringMap(v)
+	if err != nil {
+		return nil, err
+	}
+
+	lint := make(map[static.DiagnosticCode]static.Severity)
+	for code, level := range levels {
+		severity, ok := lintLevels[level]
+		if !ok {
+			return nil, errors.Errorf("level %q for %q is not one of off, error, warning, information or hint", level, code)
+		}
+
+		lint[static.DiagnosticCode(code)] = severity
+	}
+
+	return lint, nil
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeProjectFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestFindProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "project")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeProjectFiles(t, dir, map[string]string{
+		"app/jsonnetfile.json":              "{}",
+		"app/environments/dev/main.jsonnet": "{}",
+		"app/nested/.jsonnet-ls.json":       "{}",
+	})
+
+	cases := []struct {
+		name     string
+		path     string
+		expected string
+		found    bool
+	}{
+		{
+			name:     "in project directory",
+			path:     filepath.Join(dir, "app", "main.jsonnet"),
+			expected: filepath.Join(dir, "app"),
+			found:    true,
+		},
+		{
+			name:     "below project directory",
+			path:     filepath.Join(dir, "app", "environments", "dev", "main.jsonnet"),
+			expected: filepath.Join(dir, "app"),
+			found:    true,
+		},
+		{
+			name:     "nearest project",
+			path:     filepath.Join(dir, "app", "nested", "main.jsonnet"),
+			expected: filepath.Join(dir, "app", "nested"),
+			found:    true,
+		},
+		{
+			name: "not in a project",
+			path: filepath.Join(dir, "main.jsonnet"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, found := FindProject(tc.path)
+			assert.Equal(t, tc.found, found)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	cases := []struct {
+		name     string
+		files    map[string]string
+		expected func(dir string) *Project
+		isErr    bool
+	}{
+		{
+			name: "project configuration",
+			files: map[string]string{
+				".jsonnet-ls.json": `{
+					"libPaths": ["lib", "/abs"],
+					"extVars": {"env": "dev"},
+					"extCodeFiles": {"params": "params.libsonnet"},
+					"lint": {"unused-parameter": "off", "unused-local": "error"}
+				}`,
+			},
+			expected: func(dir string) *Project {
+				return &Project{
+					Dir:      dir,
+					libPaths: []string{filepath.Join(dir, "lib"), "/abs"},
+					vmVariables: map[string]map[string]string{
+						JsonnetExtVars:      {"env": "dev"},
+						JsonnetExtCodeFiles: {"params": filepath.Join(dir, "params.libsonnet")},
+					},
+					lint: map[static.DiagnosticCode]static.Severity{
+						static.UnusedParameter: 0,
+						static.UnusedLocal:     static.SeverityError,
+					},
+				}
+			},
+		},
+		{
+			name: "jsonnet-bundler",
+			files: map[string]string{
+				".jsonnet-ls.json":      `{"libPaths": ["lib"]}`,
+				"jsonnetfile.json":      "{}",
+				"jsonnetfile.lock.json": "{}",
+			},
+			expected: func(dir string) *Project {
+				return &Project{
+					Dir:         dir,
+					libPaths:    []string{filepath.Join(dir, "lib"), filepath.Join(dir, "vendor")},
+					vmVariables: map[string]map[string]string{},
+					lint:        map[static.DiagnosticCode]static.Severity{},
+				}
+			},
+		},
+		{
+			name: "invalid json",
+			files: map[string]string{
+				".jsonnet-ls.json": `{`,
+			},
+			isErr: true,
+		},
+		{
+			name: "unsupported settings are skipped",
+			files: map[string]string{
+				".jsonnet-ls.json": `{"evalTimeout": 10, "libPaths": ["lib"]}`,
+			},
+			expected: func(dir string) *Project {
+				return &Project{
+					Dir:         dir,
+					libPaths:    []string{filepath.Join(dir, "lib")},
+					vmVariables: map[string]map[string]string{},
+					lint:        map[static.DiagnosticCode]static.Severity{},
+				}
+			},
+		},
+		{
+			name: "invalid lint level",
+			files: map[string]string{
+				".jsonnet-ls.json": `{"lint": {"unused-local": "loud"}}`,
+			},
+			isErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "project")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			writeProjectFiles(t, dir, tc.files)
+
+			got, err := LoadProject(dir)
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected(dir), got)
+		})
+	}
+}
+
+func TestConfig_ForPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "project")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeProjectFiles(t, dir, map[string]string{
+		"app/.jsonnet-ls.json": `{
+			"libPaths": ["lib"],
+			"extVars": {"env": "dev", "cluster": "a"},
+			"lint": {"unused-parameter": "off"}
+		}`,
+		"app/jsonnetfile.json": "{}",
+	})
+
+	c := New()
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+	err = c.UpdateClientConfiguration(ctx, map[string]interface{}{
+		JsonnetLibPaths: []string{"/client"},
+		JsonnetExtVars:  map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "app", "main.jsonnet")
+
+	pc, err := c.ForPath(path)
+	require.NoError(t, err)
+
+	expectedLibPaths := []string{
+		filepath.Join(dir, "app", "lib"),
+		filepath.Join(dir, "app", "vendor"),
+		"/client",
+	}
+	assert.Equal(t, expectedLibPaths, pc.JsonnetLibPaths())
+	assert.Equal(t, map[string]string{"env": "prod", "cluster": "a"}, pc.VMVariables(JsonnetExtVars))
+	assert.Equal(t, []string{"/client"}, c.JsonnetLibPaths())
+
+	_, enabled := c.DiagnosticSeverity(path, static.UnusedParameter)
+	assert.False(t, enabled)
+
+	severity, enabled := c.DiagnosticSeverity(path, static.UnusedLocal)
+	assert.True(t, enabled)
+	assert.Equal(t, static.SeverityWarning, severity)
+
+	outside, err := c.ForPath(filepath.Join(dir, "main.jsonnet"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/client"}, outside.JsonnetLibPaths())
+
+	// projects are loaded again after their files change.
+	writeProjectFiles(t, dir, map[string]string{
+		"app/.jsonnet-ls.json": `{"libPaths": ["other"]}`,
+	})
+
+	assert.False(t, c.ReloadProject(path))
+	assert.True(t, c.ReloadProject(filepath.Join(dir, "app", ".jsonnet-ls.json")))
+
+	pc, err = c.ForPath(path)
+	require.NoError(t, err)
+
+	expectedLibPaths = []string{
+		filepath.Join(dir, "app", "other"),
+		filepath.Join(dir, "app", "vendor"),
+		"/client",
+	}
+	assert.Equal(t, expectedLibPaths, pc.JsonnetLibPaths())
+
+	_, enabled = c.DiagnosticSeverity(path, static.UnusedParameter)
+	assert.True(t, enabled)
+}
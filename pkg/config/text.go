@@ -7,7 +7,9 @@ import (
 
 	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/text"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	"github.com/pkg/errors"
 )
 
 // TextDocument is a document's text and and metadata.
@@ -15,13 +17,13 @@ type TextDocument struct {
 	uri        string
 	languageID string
 	version    int
-	text       string
+	buffer     *text.PieceTable
 }
 
-func NewTextDocument(uri, text string) TextDocument {
+func NewTextDocument(uri, source string) TextDocument {
 	return TextDocument{
-		uri:  uri,
-		text: text,
+		uri:    uri,
+		buffer: text.NewPieceTable(source),
 	}
 }
 
@@ -30,7 +32,7 @@ func NewTextDocumentFromItem(tdi lsp.TextDocumentItem) TextDocument {
 	return TextDocument{
 		uri:        tdi.URI,
 		languageID: tdi.LanguageID,
-		text:       tdi.Text,
+		buffer:     text.NewPieceTable(tdi.Text),
 		version:    tdi.Version,
 	}
 }
@@ -40,8 +42,43 @@ func (td *TextDocument) URI() string {
 	return td.uri
 }
 
+// Version returns the version of the text document.
+func (td *TextDocument) Version() int {
+	return td.version
+}
+
 func (td *TextDocument) String() string {
-	return td.text
+	if td.buffer == nil {
+		return ""
+	}
+
+	return td.buffer.String()
+}
+
+// Apply applies a change event to the text document. Change events
+// without a range replace the whole document. Ranges use UTF-16
+// characters.
+func (td *TextDocument) Apply(change lsp.TextDocumentContentChangeEvent) error {
+	if change.Range == nil {
+		td.buffer = text.NewPieceTable(change.Text)
+		return nil
+	}
+
+	buffer := td.buffer
+	if buffer == nil {
+		buffer = text.NewPieceTable("")
+	}
+
+	start := buffer.Offset(change.Range.Start.Line, change.Range.Start.Character)
+	end := buffer.Offset(change.Range.End.Line, change.Range.End.Character)
+
+	updated, err := buffer.Replace(start, end, change.Text)
+	if err != nil {
+		return errors.Wrapf(err, "applying change to %q", td.uri)
+	}
+
+	td.buffer = updated
+	return nil
 }
 
 func (td *TextDocument) Filename() (string, error) {
@@ -50,7 +87,7 @@ func (td *TextDocument) Filename() (string, error) {
 
 // Truncate returns text truncated at a position.
 func (td *TextDocument) Truncate(p position.Position) (string, error) {
-	scanner := bufio.NewScanner(strings.NewReader(td.text))
+	scanner := bufio.NewScanner(strings.NewReader(td.String()))
 	scanner.Split(bufio.ScanBytes)
 
 	var buf bytes.Buffer
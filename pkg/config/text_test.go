@@ -38,9 +38,7 @@ func TestTextDocument_Truncate(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			td := &TextDocument{
-				text: tc.source,
-			}
+			td := NewTextDocument("", tc.source)
 
 			got, err := td.Truncate(tc.pos)
 			if tc.isErr {
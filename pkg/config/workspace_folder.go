@@ -0,0 +1,194 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+)
+
+const (
+	// WorkspaceFolderUpdates are workspace folder updates. The message is
+	// the path of the folder which was added, removed or had its settings
+	// updated.
+	WorkspaceFolderUpdates = "workspaceFolder.update"
+)
+
+// WorkspaceFolder is a root folder in a multi-root workspace. Documents
+// in a folder have their own node cache and the folder's settings.
+type WorkspaceFolder struct {
+	// Path is the folder path.
+	Path string
+	// Name is the name of the folder in the client.
+	Name string
+
+	nodeCache *token.NodeCache
+	settings  *Project
+}
+
+// workspaceFolders are the workspace folders by path.
+type workspaceFolders struct {
+	folders map[string]*WorkspaceFolder
+
+	mu sync.RWMutex
+}
+
+func newWorkspaceFolders() *workspaceFolders {
+	return &workspaceFolders{
+		folders: make(map[string]*WorkspaceFolder),
+	}
+}
+
+// find finds the innermost folder containing path.
+func (wf *workspaceFolders) find(path string) (WorkspaceFolder, bool) {
+	wf.mu.RLock()
+	defer wf.mu.RUnlock()
+
+	var found *WorkspaceFolder
+	for dir, folder := range wf.folders {
+		if !inDir(dir, path) {
+			continue
+		}
+
+		if found == nil || len(dir) > len(found.Path) {
+			found = folder
+		}
+	}
+
+	if found == nil {
+		return WorkspaceFolder{}, false
+	}
+
+	return *found, true
+}
+
+// inDir returns true if path is dir or is contained in dir.
+func inDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// AddWorkspaceFolder adds a workspace folder. Adding a folder which
+// exists keeps its node cache and settings.
+func (c *Config) AddWorkspaceFolder(ctx context.Context, path, name string) {
+	path = filepath.Clean(path)
+
+	c.workspaceFolders.mu.Lock()
+	if _, ok := c.workspaceFolders.folders[path]; ok {
+		c.workspaceFolders.mu.Unlock()
+		return
+	}
+
+	c.workspaceFolders.folders[path] = &WorkspaceFolder{
+		Path:      path,
+		Name:      name,
+		nodeCache: token.NewNodeCache(),
+		settings:  newProject(path),
+	}
+	c.workspaceFolders.mu.Unlock()
+
+	c.dispatch(ctx, WorkspaceFolderUpdates, path)
+}
+
+// RemoveWorkspaceFolder removes a workspace folder and discards its node
+// cache. It returns false if the folder doesn't exist.
+func (c *Config) RemoveWorkspaceFolder(ctx context.Context, path string) bool {
+	path = filepath.Clean(path)
+
+	c.workspaceFolders.mu.Lock()
+	if _, ok := c.workspaceFolders.folders[path]; !ok {
+		c.workspaceFolders.mu.Unlock()
+		return false
+	}
+
+	delete(c.workspaceFolders.folders, path)
+	c.workspaceFolders.mu.Unlock()
+
+	c.dispatch(ctx, WorkspaceFolderUpdates, path)
+	return true
+}
+
+// WorkspaceFolders returns the workspace folders sorted by path.
+func (c *Config) WorkspaceFolders() []WorkspaceFolder {
+	c.workspaceFolders.mu.RLock()
+	defer c.workspaceFolders.mu.RUnlock()
+
+	var folders []WorkspaceFolder
+	for _, folder := range c.workspaceFolders.folders {
+		folders = append(folders, *folder)
+	}
+
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].Path < folders[j].Path
+	})
+
+	return folders
+}
+
+// UpdateWorkspaceFolderSettings replaces the settings of a workspace
+// folder with the jsonnet section of the client settings for the folder.
+// Only lib paths, external variables, top level arguments and lint
+// levels are scoped to folders; other settings in the section are
+// ignored. Relative paths are resolved from the folder.
+func (c *Config) UpdateWorkspaceFolderSettings(ctx context.Context, path string, section map[string]interface{}) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "updateWorkspaceFolderSettings")
+	defer span.Finish()
+
+	path = filepath.Clean(path)
+
+	settings := newProject(path)
+
+	scoped := make(map[string]interface{})
+	for k, v := range section {
+		if isProjectSetting(SettingsSection + "." + k) {
+			scoped[k] = v
+		}
+	}
+
+	if err := settings.update(span, scoped); err != nil {
+		return errors.Wrapf(err, "updating settings for workspace folder %q", path)
+	}
+
+	c.workspaceFolders.mu.Lock()
+	folder, ok := c.workspaceFolders.folders[path]
+	if !ok {
+		c.workspaceFolders.mu.Unlock()
+		return errors.Errorf("workspace folder %q does not exist", path)
+	}
+
+	folder.settings = settings
+	c.workspaceFolders.mu.Unlock()
+
+	c.dispatch(ctx, WorkspaceFolderUpdates, path)
+	return nil
+}
+
+// InWorkspace returns true if path is in the workspace root or a
+// workspace folder.
+func (c *Config) InWorkspace(path string) bool {
+	if c.rootPath != "" && inDir(c.rootPath, path) {
+		return true
+	}
+
+	_, ok := c.workspaceFolders.find(path)
+	return ok
+}
+
+// NodeCaches returns the node caches for the workspace and its folders.
+func (c *Config) NodeCaches() []*token.NodeCache {
+	caches := []*token.NodeCache{c.nodeCache}
+	for _, folder := range c.WorkspaceFolders() {
+		caches = append(caches, folder.nodeCache)
+	}
+
+	return caches
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_WorkspaceFolders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workspace")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeProjectFiles(t, dir, map[string]string{
+		"b/app/jsonnetfile.json": "{}",
+	})
+
+	c := New()
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+	err = c.UpdateClientConfiguration(ctx, map[string]interface{}{
+		JsonnetLibPaths: []string{"/client"},
+		JsonnetExtVars:  map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+
+	c.AddWorkspaceFolder(ctx, a, "a")
+	c.AddWorkspaceFolder(ctx, b+string(filepath.Separator), "b")
+
+	var names []string
+	for _, folder := range c.WorkspaceFolders() {
+		names = append(names, folder.Name)
+	}
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	err = c.UpdateWorkspaceFolderSettings(ctx, a, map[string]interface{}{
+		"libPaths":    []interface{}{"lib"},
+		"extVars":     map[string]interface{}{"env": "dev"},
+		"lint":        map[string]interface{}{"unused-local": "off"},
+		"evalTimeout": 10,
+	})
+	require.NoError(t, err)
+
+	pa, err := c.ForPath(filepath.Join(a, "main.jsonnet"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(a, "lib"), "/client"}, pa.JsonnetLibPaths())
+	assert.Equal(t, map[string]string{"env": "dev"}, pa.VMVariables(JsonnetExtVars))
+	assert.Equal(t, a, pa.RootPath())
+
+	_, enabled := c.DiagnosticSeverity(filepath.Join(a, "main.jsonnet"), static.UnusedLocal)
+	assert.False(t, enabled)
+
+	pb, err := c.ForPath(filepath.Join(b, "app", "main.jsonnet"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(b, "app", "vendor"), "/client"}, pb.JsonnetLibPaths())
+	assert.Equal(t, map[string]string{"env": "prod"}, pb.VMVariables(JsonnetExtVars))
+	assert.Equal(t, b, pb.RootPath())
+
+	// folders have their own node caches.
+	assert.True(t, pa.NodeCache() != pb.NodeCache())
+	assert.True(t, pa.NodeCache() != c.NodeCache())
+	assert.Len(t, c.NodeCaches(), 3)
+
+	outside, err := c.ForPath(filepath.Join(dir, "ab", "main.jsonnet"))
+	require.NoError(t, err)
+	assert.True(t, outside == c)
+	assert.False(t, c.InWorkspace(filepath.Join(dir, "ab", "main.jsonnet")))
+
+	assert.True(t, c.RemoveWorkspaceFolder(ctx, a))
+	assert.False(t, c.RemoveWorkspaceFolder(ctx, a))
+
+	err = c.UpdateWorkspaceFolderSettings(ctx, a, map[string]interface{}{})
+	require.Error(t, err)
+
+	pa, err = c.ForPath(filepath.Join(a, "main.jsonnet"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/client"}, pa.JsonnetLibPaths())
+}
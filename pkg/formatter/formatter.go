@@ -0,0 +1,537 @@
+// Package formatter formats Jsonnet source. It works on the token
+// stream, so comments are preserved and documents that don't parse can
+// still be formatted as long as they can be lexed.
+//
+// Line breaks in the source are kept as they are. The formatter
+// normalizes indentation and the whitespace between tokens on a line.
+// This means a line in the formatted output always corresponds to the
+// same line in the source.
+package formatter
+
+import (
+	"strings"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+)
+
+// StringStyle is the quote style used for strings.
+type StringStyle int
+
+const (
+	// StringStyleLeave leaves quotes as they are.
+	StringStyleLeave StringStyle = iota
+	// StringStyleSingle prefers single quotes.
+	StringStyleSingle
+	// StringStyleDouble prefers double quotes.
+	StringStyleDouble
+)
+
+// Options are formatting options.
+type Options struct {
+	// Indent is the number of spaces used for each level of indentation.
+	Indent int
+	// StringStyle is the preferred quote style for strings. Strings are
+	// only converted if that doesn't require adding escapes.
+	StringStyle StringStyle
+	// TrailingCommas adds commas after the last element of multi-line
+	// objects and arrays and removes them from single line ones.
+	TrailingCommas bool
+	// PadObjects adds spaces inside the braces of single line objects.
+	PadObjects bool
+	// SortImports sorts blocks of top level locals that import files by
+	// name.
+	SortImports bool
+}
+
+// DefaultOptions returns the default formatting options. They match
+// the defaults of jsonnetfmt.
+func DefaultOptions() Options {
+	return Options{
+		Indent:         2,
+		StringStyle:    StringStyleSingle,
+		TrailingCommas: true,
+		PadObjects:     true,
+		SortImports:    true,
+	}
+}
+
+// Format formats Jsonnet source.
+func Format(filename, source string, opts Options) (string, error) {
+	tokens, err := token.Lex(filename, source)
+	if err != nil {
+		return "", err
+	}
+
+	items := make([]item, len(tokens))
+	for i := range tokens {
+		items[i] = item{Token: tokens[i], fodder: tokens[i].Fodder()}
+	}
+
+	if opts.SortImports {
+		items = sortImports(items)
+	}
+
+	p := newPrinter(opts)
+	for i := range items {
+		p.print(&items[i])
+	}
+
+	return p.String(), nil
+}
+
+// item is a token and the fodder that precedes it. The fodder is kept
+// separately so tokens can be moved around.
+type item struct {
+	token.Token
+	fodder token.Fodder
+}
+
+// frame is an open brace, bracket or paren.
+type frame struct {
+	kind token.TokenKind
+	// lineIndent is the indent of the line the frame was opened on.
+	lineIndent int
+	// indent is the indent for lines inside of the frame.
+	indent int
+	// index is true if the frame is an index, e.g. `a[0]`.
+	index bool
+	// assert is true if an assert is being printed in the frame.
+	assert bool
+	// comprehension is true if the frame is an array or object
+	// comprehension.
+	comprehension bool
+	// ifs are the indents of the lines containing unfinished
+	// conditionals.
+	ifs []int
+	// stmts are the lines containing unfinished `local` and `assert`
+	// statements. The body after a statement is indented like the line
+	// the statement started on.
+	stmts []line
+	// afterStmt is the line the last finished statement started on.
+	afterStmt *line
+}
+
+// line is the indentation of a line.
+type line struct {
+	indent int
+	// cont is true if the line continues the previous line.
+	cont bool
+}
+
+type printer struct {
+	opts  Options
+	buf   []byte
+	stack []*frame
+
+	// atLineStart is true if nothing has been printed on the current line.
+	atLineStart bool
+	// lineIndent is the indent of the current line.
+	lineIndent int
+	// lineCont is true if the current line continues the previous line.
+	lineCont bool
+
+	prev       *item
+	beforePrev *item
+	prevUnary  bool
+	prevStart  int
+	prevEnd    int
+}
+
+func newPrinter(opts Options) *printer {
+	return &printer{
+		opts:        opts,
+		stack:       []*frame{{}},
+		atLineStart: true,
+	}
+}
+
+func (p *printer) String() string {
+	return string(p.buf)
+}
+
+func (p *printer) top() *frame {
+	return p.stack[len(p.stack)-1]
+}
+
+func (p *printer) write(s string) {
+	p.buf = append(p.buf, s...)
+	p.atLineStart = false
+}
+
+func (p *printer) newline() {
+	p.trimTrailingSpace()
+	p.buf = append(p.buf, '\n')
+	p.atLineStart = true
+}
+
+func (p *printer) trimTrailingSpace() {
+	p.buf = []byte(strings.TrimRight(string(p.buf), " \t"))
+}
+
+func (p *printer) space() {
+	p.write(" ")
+}
+
+func (p *printer) indent(n int, cont bool) {
+	p.lineIndent = n
+	p.lineCont = cont
+	p.write(strings.Repeat(" ", n))
+}
+
+// print prints an item.
+func (p *printer) print(it *item) {
+	afterComment := false
+	for _, f := range it.fodder {
+		if f.IsWhitespace() {
+			for i := 0; i < strings.Count(f.Data(), "\n"); i++ {
+				p.newline()
+			}
+			continue
+		}
+
+		if p.atLineStart {
+			p.indent(p.top().indent, false)
+		} else if len(p.buf) > 0 {
+			p.space()
+		}
+		p.write(strings.TrimRight(f.Comment(), " \t"))
+		afterComment = true
+	}
+
+	if it.Kind == token.TokenEndOfFile {
+		p.finish()
+		return
+	}
+
+	lineStart := p.atLineStart
+	p.updateTrailingComma(it, lineStart)
+
+	switch {
+	case lineStart:
+		p.lineStartIndent(it)
+	case afterComment:
+		p.space()
+	case p.prev != nil && p.spaced(p.prev, it):
+		p.space()
+	}
+
+	start := len(p.buf)
+	p.write(p.text(it))
+
+	p.beforePrev = p.prev
+	p.prev = it
+	p.prevUnary = isUnaryOperator(it) && !isValue(p.beforePrev)
+	p.prevStart, p.prevEnd = start, len(p.buf)
+
+	p.update(it)
+}
+
+// finish ends the output with a single newline.
+func (p *printer) finish() {
+	p.buf = []byte(strings.TrimRight(string(p.buf), " \t\n"))
+	if len(p.buf) > 0 {
+		p.buf = append(p.buf, '\n')
+	}
+}
+
+// lineStartIndent indents the line that starts with it.
+func (p *printer) lineStartIndent(it *item) {
+	f := p.top()
+
+	switch {
+	case isCloser(it.Kind) && len(p.stack) > 1:
+		p.indent(f.lineIndent, false)
+	case (it.Kind == token.TokenThen || it.Kind == token.TokenElse) && len(f.ifs) > 0:
+		p.indent(f.ifs[len(f.ifs)-1], false)
+	case p.continues(it):
+		if p.lineCont {
+			p.indent(p.lineIndent, true)
+		} else {
+			p.indent(p.lineIndent+p.opts.Indent, true)
+		}
+	case p.prev != nil && (p.prev.Kind == token.TokenThen || p.prev.Kind == token.TokenElse):
+		p.indent(p.lineIndent+p.opts.Indent, false)
+	case p.prev != nil && p.prev.Kind == token.TokenSemicolon && f.afterStmt != nil:
+		p.indent(f.afterStmt.indent, f.afterStmt.cont)
+	default:
+		p.indent(f.indent, false)
+	}
+}
+
+// continues returns true if a line starting with it continues the
+// expression on the previous line.
+func (p *printer) continues(it *item) bool {
+	prev := p.prev
+	if prev == nil {
+		return false
+	}
+
+	switch prev.Kind {
+	case token.TokenOperator:
+		return true
+	case token.TokenIf, token.TokenIn, token.TokenError, token.TokenAssert,
+		token.TokenImport, token.TokenImportStr:
+		return true
+	}
+
+	switch it.Kind {
+	case token.TokenDot, token.TokenThen, token.TokenElse:
+		return true
+	case token.TokenOperator:
+		return isValue(prev)
+	}
+
+	return false
+}
+
+// update updates the frame stack after it has been printed.
+func (p *printer) update(it *item) {
+	f := p.top()
+
+	switch it.Kind {
+	case token.TokenBraceL, token.TokenBracketL, token.TokenParenL:
+		p.stack = append(p.stack, &frame{
+			kind:       it.Kind,
+			lineIndent: p.lineIndent,
+			indent:     p.lineIndent + p.opts.Indent,
+			index:      it.Kind == token.TokenBracketL && isValue(p.beforePrev),
+		})
+	case token.TokenBraceR, token.TokenBracketR, token.TokenParenR:
+		if len(p.stack) > 1 {
+			p.stack = p.stack[:len(p.stack)-1]
+		}
+	case token.TokenLocal:
+		if !p.objectMember(f) {
+			f.stmts = append(f.stmts, line{indent: p.lineIndent, cont: p.lineCont})
+		}
+	case token.TokenAssert:
+		if !p.objectMember(f) {
+			f.stmts = append(f.stmts, line{indent: p.lineIndent, cont: p.lineCont})
+		}
+		f.assert = true
+	case token.TokenComma:
+		f.assert = false
+	case token.TokenSemicolon:
+		f.assert = false
+		f.afterStmt = nil
+		if n := len(f.stmts); n > 0 {
+			stmt := f.stmts[n-1]
+			f.afterStmt = &stmt
+			f.stmts = f.stmts[:n-1]
+		}
+	case token.TokenFor:
+		f.comprehension = true
+	case token.TokenIf:
+		if !f.comprehension {
+			f.ifs = append(f.ifs, p.lineIndent)
+		}
+	case token.TokenElse:
+		if len(f.ifs) > 0 {
+			f.ifs = f.ifs[:len(f.ifs)-1]
+		}
+	}
+}
+
+// objectMember returns true if the item printed last starts a member of
+// the object f, e.g. an object local, which ends with a comma rather
+// than a semicolon.
+func (p *printer) objectMember(f *frame) bool {
+	if f.kind != token.TokenBraceL {
+		return false
+	}
+
+	prev := p.beforePrev
+	return prev != nil && (prev.Kind == token.TokenBraceL || prev.Kind == token.TokenComma)
+}
+
+// updateTrailingComma adds or removes the trailing comma before it
+// closes an object or array.
+func (p *printer) updateTrailingComma(it *item, lineStart bool) {
+	if !p.opts.TrailingCommas || p.prev == nil || len(p.stack) < 2 {
+		return
+	}
+
+	f := p.top()
+	if f.comprehension || f.index {
+		return
+	}
+
+	switch {
+	case it.Kind == token.TokenBraceR && f.kind == token.TokenBraceL:
+	case it.Kind == token.TokenBracketR && f.kind == token.TokenBracketL:
+	default:
+		return
+	}
+
+	switch {
+	case lineStart && p.prev.Kind != token.TokenComma && !isOpener(p.prev.Kind):
+		p.insert(p.prevEnd, ",")
+	case !lineStart && p.prev.Kind == token.TokenComma:
+		rest := strings.TrimLeft(string(p.buf[p.prevEnd:]), " \t")
+		p.buf = append(p.buf[:p.prevStart], rest...)
+		p.prev, p.beforePrev = p.beforePrev, nil
+	}
+}
+
+func (p *printer) insert(at int, s string) {
+	buf := make([]byte, 0, len(p.buf)+len(s))
+	buf = append(buf, p.buf[:at]...)
+	buf = append(buf, s...)
+	buf = append(buf, p.buf[at:]...)
+	p.buf = buf
+}
+
+// spaced returns true if there should be a space between prev and cur
+// when they are on the same line.
+// nolint: gocyclo
+func (p *printer) spaced(prev, cur *item) bool {
+	f := p.top()
+
+	switch {
+	case isFieldOperator(cur):
+		return f.kind != token.TokenBracketL && f.assert
+	case isFieldOperator(prev):
+		return f.kind != token.TokenBracketL
+	}
+
+	if prev.Kind == token.TokenBraceL {
+		return p.opts.PadObjects && cur.Kind != token.TokenBraceR
+	}
+
+	switch cur.Kind {
+	case token.TokenComma, token.TokenSemicolon, token.TokenDot,
+		token.TokenParenR, token.TokenBracketR:
+		return false
+	case token.TokenBraceR:
+		return p.opts.PadObjects
+	case token.TokenParenL:
+		if prev.Kind == token.TokenFunction || p.prevUnary {
+			return false
+		}
+		return !isValue(prev) && !isOpener(prev.Kind)
+	case token.TokenBracketL:
+		if p.prevUnary {
+			return false
+		}
+		return !isValue(prev) && !isOpener(prev.Kind)
+	}
+
+	switch prev.Kind {
+	case token.TokenParenL, token.TokenBracketL, token.TokenDot:
+		return false
+	}
+
+	if f.kind == token.TokenParenL && (isOperator(prev, "=") || isOperator(cur, "=")) {
+		// named arguments and default parameters
+		return false
+	}
+
+	return !p.prevUnary
+}
+
+// text returns the text for it.
+func (p *printer) text(it *item) string {
+	switch it.Kind {
+	case token.TokenStringDouble, token.TokenStringSingle:
+		return quote(it.Kind, it.Data, p.opts.StringStyle)
+	case token.TokenVerbatimStringDouble:
+		return `@"` + strings.Replace(it.Data, `"`, `""`, -1) + `"`
+	case token.TokenVerbatimStringSingle:
+		return `@'` + strings.Replace(it.Data, `'`, `''`, -1) + `'`
+	case token.TokenStringBlock:
+		return p.textBlock(it.Data)
+	case token.TokenOperator, token.TokenIdentifier, token.TokenNumber:
+		return it.Data
+	default:
+		return strings.Trim(it.Kind.String(), `"`)
+	}
+}
+
+// textBlock returns a text block indented relative to the current line.
+func (p *printer) textBlock(data string) string {
+	indent := strings.Repeat(" ", p.lineIndent+p.opts.Indent)
+
+	var sb strings.Builder
+	sb.WriteString("|||\n")
+	for _, line := range strings.SplitAfter(data, "\n") {
+		switch line {
+		case "":
+			continue
+		case "\n":
+			sb.WriteString(line)
+		default:
+			sb.WriteString(indent + line)
+		}
+	}
+	sb.WriteString(strings.Repeat(" ", p.lineIndent) + "|||")
+
+	return sb.String()
+}
+
+func isOpener(kind token.TokenKind) bool {
+	switch kind {
+	case token.TokenBraceL, token.TokenBracketL, token.TokenParenL:
+		return true
+	}
+
+	return false
+}
+
+func isCloser(kind token.TokenKind) bool {
+	switch kind {
+	case token.TokenBraceR, token.TokenBracketR, token.TokenParenR:
+		return true
+	}
+
+	return false
+}
+
+// isValue returns true if it can end an expression.
+func isValue(it *item) bool {
+	if it == nil {
+		return false
+	}
+
+	switch it.Kind {
+	case token.TokenIdentifier, token.TokenNumber, token.TokenDollar,
+		token.TokenStringBlock, token.TokenStringDouble, token.TokenStringSingle,
+		token.TokenVerbatimStringDouble, token.TokenVerbatimStringSingle,
+		token.TokenSelf, token.TokenSuper, token.TokenTrue, token.TokenFalse,
+		token.TokenNullLit, token.TokenParenR, token.TokenBracketR, token.TokenBraceR:
+		return true
+	}
+
+	return false
+}
+
+func isOperator(it *item, op string) bool {
+	return it.Kind == token.TokenOperator && it.Data == op
+}
+
+func isUnaryOperator(it *item) bool {
+	if it.Kind != token.TokenOperator {
+		return false
+	}
+
+	switch it.Data {
+	case "-", "+", "!", "~":
+		return true
+	}
+
+	return false
+}
+
+// isFieldOperator returns true if it separates a field name from its
+// value.
+func isFieldOperator(it *item) bool {
+	if it.Kind != token.TokenOperator {
+		return false
+	}
+
+	switch it.Data {
+	case ":", "::", ":::", "+:", "+::", "+:::":
+		return true
+	}
+
+	return false
+}
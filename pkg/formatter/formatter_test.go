@@ -0,0 +1,199 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		opts     *Options
+		expected string
+		isErr    bool
+	}{
+		{
+			name:     "object",
+			source:   "{a:1,b::2,c+:3}",
+			expected: "{ a: 1, b:: 2, c+: 3 }\n",
+		},
+		{
+			name:     "object without padding",
+			source:   "{a:1}",
+			opts:     &Options{Indent: 2},
+			expected: "{a: 1}\n",
+		},
+		{
+			name:     "empty object",
+			source:   "{ }",
+			expected: "{}\n",
+		},
+		{
+			name:     "indentation",
+			source:   "{\na: {\n        b: 1,\n},\n}",
+			expected: "{\n  a: {\n    b: 1,\n  },\n}\n",
+		},
+		{
+			name:     "indent size",
+			source:   "{\na: 1,\n}",
+			opts:     &Options{Indent: 4, TrailingCommas: true},
+			expected: "{\n    a: 1,\n}\n",
+		},
+		{
+			name:     "add trailing comma",
+			source:   "[\n1,\n2\n]",
+			expected: "[\n  1,\n  2,\n]\n",
+		},
+		{
+			name:     "add trailing comma before comment",
+			source:   "{\na: 1 // a\n}",
+			expected: "{\n  a: 1, // a\n}\n",
+		},
+		{
+			name:     "remove trailing comma",
+			source:   "[1, 2,]",
+			expected: "[1, 2]\n",
+		},
+		{
+			name:     "comprehension",
+			source:   "[\nx\nfor x in [1,2]\n]",
+			expected: "[\n  x\n  for x in [1, 2]\n]\n",
+		},
+		{
+			name:     "single quotes",
+			source:   `{a: "a\"b", b: "it's"}`,
+			expected: `{ a: 'a"b', b: "it's" }` + "\n",
+		},
+		{
+			name:     "double quotes",
+			source:   `{a: 'a\'b', b: '"'}`,
+			opts:     &Options{StringStyle: StringStyleDouble, PadObjects: true},
+			expected: `{ a: "a'b", b: '"' }` + "\n",
+		},
+		{
+			name:     "leave quotes",
+			source:   `["a", 'b']`,
+			opts:     &Options{StringStyle: StringStyleLeave},
+			expected: `["a", 'b']` + "\n",
+		},
+		{
+			name:     "operators",
+			source:   "local x=-1; x+2*3 == 7 && !false",
+			expected: "local x = -1; x + 2 * 3 == 7 && !false\n",
+		},
+		{
+			name:     "functions",
+			source:   "local f = function (x, y = 1) x; f(1, y = 2)",
+			expected: "local f = function(x, y=1) x; f(1, y=2)\n",
+		},
+		{
+			name:     "index and slice",
+			source:   "local a = [1]; a [0] + a[1 : 2] + a[::2]",
+			expected: "local a = [1]; a[0] + a[1:2] + a[::2]\n",
+		},
+		{
+			name:     "assert",
+			source:   "{assert self.a==1:'msg', a: 1}",
+			expected: "{ assert self.a == 1 : 'msg', a: 1 }\n",
+		},
+		{
+			name:     "continuation",
+			source:   "local x =\n1 +\n2;\nx",
+			expected: "local x =\n  1 +\n  2;\nx\n",
+		},
+		{
+			name:     "local in continuation",
+			source:   "local f(x) =\n  local y = x;\n  y;\nf(1)",
+			expected: "local f(x) =\n  local y = x;\n  y;\nf(1)\n",
+		},
+		{
+			name:     "local in field body",
+			source:   "{\n  f(x)::\n    local y = x;\n    y + 1,\n}",
+			expected: "{\n  f(x)::\n    local y = x;\n    y + 1,\n}\n",
+		},
+		{
+			name:     "assert in continuation",
+			source:   "local f(x) =\nassert x > 0;\nx;\nf(1)",
+			expected: "local f(x) =\n  assert x > 0;\n  x;\nf(1)\n",
+		},
+		{
+			name:     "conditional",
+			source:   "{\na: if true then\n1\nelse\n2,\n}",
+			expected: "{\n  a: if true then\n    1\n  else\n    2,\n}\n",
+		},
+		{
+			name:     "comments",
+			source:   "// comment  \n{\n# hash\n  /* c */ a: 1,\n}",
+			expected: "// comment\n{\n  # hash\n  /* c */ a: 1,\n}\n",
+		},
+		{
+			name:     "text block",
+			source:   "{\na: |||\n      text\n        indented\n\n    |||,\n}",
+			expected: "{\n  a: |||\n    text\n      indented\n\n  |||,\n}\n",
+		},
+		{
+			name:     "verbatim string",
+			source:   "@'it''s'",
+			expected: "@'it''s'\n",
+		},
+		{
+			name:     "blank lines are kept",
+			source:   "local a = 1;\n\n\na\n\n",
+			expected: "local a = 1;\n\n\na\n",
+		},
+		{
+			name:     "sort imports",
+			source:   "local c = import 'c.libsonnet';\nlocal a = importstr 'a.txt';\nlocal b = import \"b.libsonnet\";\n\nlocal z = import 'z.libsonnet';\nlocal y = import 'y.libsonnet';\n{}",
+			expected: "local a = importstr 'a.txt';\nlocal b = import 'b.libsonnet';\nlocal c = import 'c.libsonnet';\n\nlocal y = import 'y.libsonnet';\nlocal z = import 'z.libsonnet';\n{}\n",
+		},
+		{
+			name:     "imports are not sorted",
+			source:   "local c = import 'c.libsonnet';\nlocal a = import 'a.libsonnet';\n{}",
+			opts:     &Options{StringStyle: StringStyleSingle},
+			expected: "local c = import 'c.libsonnet';\nlocal a = import 'a.libsonnet';\n{}\n",
+		},
+		{
+			name:     "comments end import blocks",
+			source:   "local c = import 'c.libsonnet';\n// a\nlocal a = import 'a.libsonnet';\n{}",
+			expected: "local c = import 'c.libsonnet';\n// a\nlocal a = import 'a.libsonnet';\n{}\n",
+		},
+		{
+			name:     "incomplete source",
+			source:   "{\na: {\nb: 1,",
+			expected: "{\n  a: {\n    b: 1,\n",
+		},
+		{
+			name:   "invalid source",
+			source: "'unterminated",
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if tc.opts != nil {
+				opts = *tc.opts
+				if opts.Indent == 0 {
+					opts.Indent = 2
+				}
+			}
+
+			got, err := Format("file.jsonnet", tc.source, opts)
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got)
+
+			again, err := Format("file.jsonnet", got, opts)
+			require.NoError(t, err)
+			assert.Equal(t, got, again, "formatting is not idempotent")
+		})
+	}
+}
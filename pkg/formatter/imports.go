@@ -0,0 +1,120 @@
+package formatter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+)
+
+// importLocalLen is the number of tokens in `local x = import 'x';`.
+const importLocalLen = 6
+
+// sortImports sorts blocks of top level import locals by their names.
+// A block is made of consecutive lines that each contain a single
+// import local. Blank lines and comments end a block.
+func sortImports(items []item) []item {
+	depth := 0
+	for i := 0; i < len(items); i++ {
+		if depth == 0 && isImportLocal(items, i) {
+			n := 1
+			for {
+				next := i + n*importLocalLen
+				if !isImportLocal(items, next) || !isSingleNewline(items[next].fodder) {
+					break
+				}
+				n++
+			}
+
+			sortImportBlock(items[i : i+n*importLocalLen])
+			i += n*importLocalLen - 1
+			continue
+		}
+
+		switch items[i].Kind {
+		case token.TokenBraceL, token.TokenBracketL, token.TokenParenL:
+			depth++
+		case token.TokenBraceR, token.TokenBracketR, token.TokenParenR:
+			depth--
+		}
+	}
+
+	return items
+}
+
+// sortImportBlock sorts a block of import locals in place. The fodder
+// before each local stays where it is.
+func sortImportBlock(block []item) {
+	var locals [][]item
+	var fodder []token.Fodder
+	for i := 0; i < len(block); i += importLocalLen {
+		local := make([]item, importLocalLen)
+		copy(local, block[i:i+importLocalLen])
+		locals = append(locals, local)
+		fodder = append(fodder, block[i].fodder)
+	}
+
+	sort.SliceStable(locals, func(i, j int) bool {
+		return locals[i][1].Data < locals[j][1].Data
+	})
+
+	for i, local := range locals {
+		local[0].fodder = fodder[i]
+		copy(block[i*importLocalLen:], local)
+	}
+}
+
+// isImportLocal returns true if the tokens starting at i are a local
+// importing a file on a single line.
+func isImportLocal(items []item, i int) bool {
+	if i < 0 || i+importLocalLen > len(items) {
+		return false
+	}
+
+	local := items[i : i+importLocalLen]
+
+	switch {
+	case local[0].Kind != token.TokenLocal,
+		local[1].Kind != token.TokenIdentifier,
+		!isOperator(&local[2], "="),
+		local[3].Kind != token.TokenImport && local[3].Kind != token.TokenImportStr,
+		!isString(local[4].Kind),
+		local[5].Kind != token.TokenSemicolon:
+		return false
+	}
+
+	for _, it := range local[1:] {
+		for _, f := range it.fodder {
+			if !f.IsWhitespace() || strings.Contains(f.Data(), "\n") {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isSingleNewline(fodder token.Fodder) bool {
+	for _, f := range fodder {
+		if !f.IsWhitespace() {
+			return false
+		}
+	}
+
+	var sb strings.Builder
+	for _, f := range fodder {
+		sb.WriteString(f.Data())
+	}
+
+	return strings.Count(sb.String(), "\n") == 1
+}
+
+func isString(kind token.TokenKind) bool {
+	switch kind {
+	case token.TokenStringDouble, token.TokenStringSingle,
+		token.TokenVerbatimStringDouble, token.TokenVerbatimStringSingle:
+		return true
+	}
+
+	return false
+}
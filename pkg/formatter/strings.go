@@ -0,0 +1,61 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+)
+
+// quote returns string data quoted using style. data is the escaped
+// content of a string token of kind.
+func quote(kind token.TokenKind, data string, style StringStyle) string {
+	from, to := byte('"'), byte('"')
+	if kind == token.TokenStringSingle {
+		from = '\''
+	}
+
+	switch style {
+	case StringStyleSingle:
+		to = '\''
+	case StringStyleDouble:
+		to = '"'
+	default:
+		to = from
+	}
+
+	if from != to {
+		if converted, ok := requote(data, from, to); ok {
+			return string(to) + converted + string(to)
+		}
+	}
+
+	return string(from) + data + string(from)
+}
+
+// requote converts escaped string data quoted with from so it can be
+// quoted with to. Escaped from quotes are no longer escaped. If data
+// contains an unescaped to quote, it can't be converted without adding
+// escapes, so it is left as is.
+func requote(data string, from, to byte) (string, bool) {
+	var sb strings.Builder
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			if data[i] == from {
+				sb.WriteByte(from)
+				continue
+			}
+			sb.WriteByte(c)
+			sb.WriteByte(data[i])
+		case c == to:
+			return "", false
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return sb.String(), true
+}
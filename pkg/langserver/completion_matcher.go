@@ -8,27 +8,31 @@ import (
 
 	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
 	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
-	"github.com/bryanl/jsonnet-language-server/pkg/tracing"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/text"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
 )
 
 // CompletionAction is an action performed on a completion match.
 type CompletionAction func(ctx context.Context, pos position.Position, path, source string) ([]lsp.CompletionItem, error)
 
+type completionMatch struct {
+	re *regexp.Regexp
+	fn CompletionAction
+}
+
 // CompletionMatcher can register multiple terms to complete against.
+// Terms are matched in the order they are registered.
 type CompletionMatcher struct {
-	store     map[*regexp.Regexp]CompletionAction
+	store     []completionMatch
 	mu        sync.Mutex
 	nodeCache *token.NodeCache
 }
 
 // NewCompletionMatcher creates an instance of CompletionMatchers.
 func NewCompletionMatcher() *CompletionMatcher {
-	return &CompletionMatcher{
-		store: make(map[*regexp.Regexp]CompletionAction),
-	}
+	return &CompletionMatcher{}
 }
 
 // Register registers terms for the matcher
@@ -42,15 +46,16 @@ func (cm *CompletionMatcher) Register(term string, fn CompletionAction) error {
 		return err
 	}
 
-	cm.store[re] = fn
+	cm.store = append(cm.store, completionMatch{re: re, fn: fn})
 
 	return nil
 }
 
 // Match matches at a point defined in the edit range.
 func (cm *CompletionMatcher) Match(ctx context.Context, pos position.Position, path, source string) ([]lsp.CompletionItem, error) {
-	span, ctx := tracing.ChildSpan(ctx, "completionMatcher")
-
+	// matches can be requested with a context without a span, so the
+	// span isn't started with tracing.ChildSpan.
+	span, ctx := opentracing.StartSpanFromContext(ctx, "completionMatcher")
 	defer span.Finish()
 
 	cm.mu.Lock()
@@ -61,14 +66,14 @@ func (cm *CompletionMatcher) Match(ctx context.Context, pos position.Position, p
 		return nil, err
 	}
 
-	for re, m := range cm.store {
+	for _, m := range cm.store {
 		span.LogFields(
 			log.String("match.text", matched),
-			log.String("match.regex", re.String()),
+			log.String("match.regex", m.re.String()),
 		)
-		match := re.FindStringSubmatch(matched)
+		match := m.re.FindStringSubmatch(matched)
 		if match != nil {
-			return m(ctx, pos, path, matched)
+			return m.fn(ctx, pos, path, matched)
 		}
 	}
 
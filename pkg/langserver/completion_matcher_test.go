@@ -114,3 +114,24 @@ func TestCompletionMatchers_proceeding_ender(t *testing.T) {
 
 	assert.Equal(t, resp, list)
 }
+
+func TestCompletionMatchers_registration_order(t *testing.T) {
+	pos := position.New(1, 18)
+
+	cm := NewCompletionMatcher()
+
+	newAction := func(label string) CompletionAction {
+		return func(ctx context.Context, p position.Position, path, source string) ([]lsp.CompletionItem, error) {
+			return []lsp.CompletionItem{{Label: label}}, nil
+		}
+	}
+
+	require.NoError(t, cm.Register(`import\s+"[^"]*`, newAction("import")))
+	require.NoError(t, cm.Register(`\w+\.`, newAction("index")))
+
+	ctx := context.Background()
+	list, err := cm.Match(ctx, pos, "file.jsonnet", `import "k8s/apps.`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []lsp.CompletionItem{{Label: "import"}}, list)
+}
@@ -2,8 +2,10 @@ package langserver
 
 import (
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 )
 
 // LibPaths manage jsonnet lib paths.
@@ -51,6 +53,98 @@ func (lp *LibPaths) Files() ([]string, error) {
 	return files, nil
 }
 
+// ImportCandidate is a file or directory which completes an import path.
+type ImportCandidate struct {
+	// Path is the import path. Paths of directories end with a slash.
+	Path  string
+	IsDir bool
+}
+
+// ImportCandidates returns the files and directories which complete the
+// import path prefix. Like Jsonnet, the prefix is resolved relative to
+// dir, the directory of the importing file, and then each lib path. Only
+// files which can be imported as Jsonnet are returned unless allFiles is
+// true, e.g. for importstr.
+func (lp *LibPaths) ImportCandidates(dir, prefix string, allFiles bool) ([]ImportCandidate, error) {
+	parent, name := "", prefix
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		parent, name = prefix[:i+1], prefix[i+1:]
+	}
+
+	roots := lp.paths
+	if dir != "" {
+		roots = append([]string{dir}, roots...)
+	}
+
+	if filepath.IsAbs(parent) {
+		roots = []string{""}
+	}
+
+	m := make(map[string]ImportCandidate)
+
+	for i, root := range roots {
+		relative := i == 0 && dir != ""
+
+		// only paths relative to the importing file can leave the root.
+		if !relative && strings.HasPrefix(parent, "../") {
+			continue
+		}
+
+		if relative && strings.HasPrefix(name, ".") && strings.HasPrefix("..", name) {
+			path := parent + "../"
+			m[path] = ImportCandidate{Path: path, IsDir: true}
+		}
+
+		fis, err := ioutil.ReadDir(filepath.Join(root, filepath.FromSlash(parent)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, fi := range fis {
+			if !strings.HasPrefix(fi.Name(), name) {
+				continue
+			}
+
+			// hidden files are only completed if they are asked for.
+			if strings.HasPrefix(fi.Name(), ".") && !strings.HasPrefix(name, ".") {
+				continue
+			}
+
+			if fi.IsDir() {
+				path := parent + fi.Name() + "/"
+				m[path] = ImportCandidate{Path: path, IsDir: true}
+				continue
+			}
+
+			if !allFiles && !isImportableFile(fi.Name()) {
+				continue
+			}
+
+			path := parent + fi.Name()
+			m[path] = ImportCandidate{Path: path}
+		}
+	}
+
+	var candidates []ImportCandidate
+	for _, c := range m {
+		candidates = append(candidates, c)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Path < candidates[j].Path
+	})
+
+	return candidates, nil
+}
+
+// isImportableFile returns true if the file can be imported as Jsonnet.
+func isImportableFile(name string) bool {
+	return isJsonnetFile(name) || filepath.Ext(name) == ".json"
+}
+
 func isJsonnetFile(name string) bool {
 	if ext := filepath.Ext(name); ext == ".jsonnet" || ext == ".libsonnet" {
 		return true
@@ -49,3 +49,125 @@ func createFile(t *testing.T, base, path string) {
 	err = ioutil.WriteFile(file, []byte(""), 0600)
 	require.NoError(t, err)
 }
+
+func Test_LibPaths_ImportCandidates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	paths := []string{
+		"app/components/main.jsonnet",
+		"app/components/config.yaml",
+		"app/lib/util.libsonnet",
+		"app/lib/.hidden.libsonnet",
+		"vendor/k8s/apps/v1/deployment.libsonnet",
+		"vendor/k8s/core/v1/service.libsonnet",
+		"vendor/k8s/k8s.libsonnet",
+		"vendor/data.json",
+	}
+
+	for _, path := range paths {
+		createFile(t, dir, path)
+	}
+
+	lp := NewLibPaths([]string{
+		filepath.Join(dir, "app", "lib"),
+		filepath.Join(dir, "vendor"),
+	})
+
+	fileDir := filepath.Join(dir, "app", "components")
+
+	cases := []struct {
+		name     string
+		dir      string
+		prefix   string
+		allFiles bool
+		expected []ImportCandidate
+	}{
+		{
+			name:   "top level",
+			dir:    fileDir,
+			prefix: "",
+			expected: []ImportCandidate{
+				{Path: "data.json"},
+				{Path: "k8s/", IsDir: true},
+				{Path: "main.jsonnet"},
+				{Path: "util.libsonnet"},
+			},
+		},
+		{
+			name:   "partial name",
+			dir:    fileDir,
+			prefix: "k",
+			expected: []ImportCandidate{
+				{Path: "k8s/", IsDir: true},
+			},
+		},
+		{
+			name:   "nested directory",
+			dir:    fileDir,
+			prefix: "k8s/",
+			expected: []ImportCandidate{
+				{Path: "k8s/apps/", IsDir: true},
+				{Path: "k8s/core/", IsDir: true},
+				{Path: "k8s/k8s.libsonnet"},
+			},
+		},
+		{
+			name:   "nested file",
+			dir:    fileDir,
+			prefix: "k8s/apps/v1/d",
+			expected: []ImportCandidate{
+				{Path: "k8s/apps/v1/deployment.libsonnet"},
+			},
+		},
+		{
+			name:   "parent directory",
+			dir:    fileDir,
+			prefix: "..",
+			expected: []ImportCandidate{
+				{Path: "../", IsDir: true},
+			},
+		},
+		{
+			name:   "relative",
+			dir:    fileDir,
+			prefix: "../lib/",
+			expected: []ImportCandidate{
+				{Path: "../lib/util.libsonnet"},
+			},
+		},
+		{
+			name:   "hidden",
+			dir:    fileDir,
+			prefix: "../lib/.",
+			expected: []ImportCandidate{
+				{Path: "../lib/../", IsDir: true},
+				{Path: "../lib/.hidden.libsonnet"},
+			},
+		},
+		{
+			name:     "all files",
+			dir:      fileDir,
+			prefix:   "c",
+			allFiles: true,
+			expected: []ImportCandidate{
+				{Path: "config.yaml"},
+			},
+		},
+		{
+			name:   "no matches",
+			dir:    fileDir,
+			prefix: "missing/",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := lp.ImportCandidates(tc.dir, tc.prefix, tc.allFiles)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package langserver
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
+)
+
+// Snippet is a template inserted by completion. The body uses the LSP
+// snippet syntax, e.g. `local ${1:name} = ${2:value};`.
+type Snippet struct {
+	// Prefix is the text completed by the snippet.
+	Prefix      string
+	Body        string
+	Description string
+}
+
+// DefaultSnippets are the snippets for common Jsonnet constructs.
+var DefaultSnippets = []Snippet{
+	{
+		Prefix:      "local",
+		Body:        "local ${1:name} = ${2:value};\n$0",
+		Description: "local variable",
+	},
+	{
+		Prefix:      "localf",
+		Body:        "local ${1:name}(${2:x}) = ${3:x};\n$0",
+		Description: "local function",
+	},
+	{
+		Prefix:      "function",
+		Body:        "function(${1:x}) ${2:x}",
+		Description: "anonymous function",
+	},
+	{
+		Prefix:      "if",
+		Body:        "if ${1:condition} then ${2:a} else ${3:b}",
+		Description: "conditional",
+	},
+	{
+		Prefix:      "arraycomp",
+		Body:        "[${3:x} for ${1:x} in ${2:arr}]",
+		Description: "array comprehension",
+	},
+	{
+		Prefix:      "objectcomp",
+		Body:        "{\n\t[${1:k}]: ${3:obj[${1:k}]}\n\tfor ${1:k} in std.objectFields(${2:obj})\n}",
+		Description: "object comprehension",
+	},
+	{
+		Prefix:      "mapWithKey",
+		Body:        "std.mapWithKey(function(${1:k}, ${2:v}) ${3:v}, ${4:obj})",
+		Description: "std.mapWithKey",
+	},
+	{
+		Prefix:      "assert",
+		Body:        "assert ${1:condition} : ${2:'message'};\n$0",
+		Description: "assertion",
+	},
+}
+
+// SnippetCompletionItems creates completion items for snippets sorted by
+// prefix. The sort text of the items starts with sortPrefix. Snippets
+// later in the list replace earlier snippets with the same prefix, so
+// user snippets can replace the default snippets.
+func SnippetCompletionItems(snippets []Snippet, r position.Range, sortPrefix string) []lsp.CompletionItem {
+	byPrefix := make(map[string]Snippet)
+	for _, snippet := range snippets {
+		byPrefix[snippet.Prefix] = snippet
+	}
+
+	var prefixes []string
+	for prefix := range byPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var items []lsp.CompletionItem
+	for _, prefix := range prefixes {
+		snippet := byPrefix[prefix]
+
+		items = append(items, lsp.CompletionItem{
+			Label:            snippet.Prefix,
+			Kind:             lsp.CIKSnippet,
+			Detail:           snippet.Description,
+			SortText:         fmt.Sprintf("%s_%s", sortPrefix, snippet.Prefix),
+			InsertTextFormat: lsp.ITFSnippet,
+			TextEdit: lsp.TextEdit{
+				Range:   r.ToLSP(),
+				NewText: snippet.Body,
+			},
+		})
+	}
+
+	return items
+}
@@ -0,0 +1,49 @@
+package langserver
+
+import (
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnippetCompletionItems(t *testing.T) {
+	pos := position.New(1, 1)
+	r := position.NewRange(pos, pos)
+
+	snippets := []Snippet{
+		{Prefix: "if", Body: "if ${1:c} then ${2:a} else ${3:b}", Description: "conditional"},
+		{Prefix: "assert", Body: "assert ${1:c};", Description: "assertion"},
+		{Prefix: "if", Body: "if ${1:c} then ${2:a}", Description: "user conditional"},
+	}
+
+	got := SnippetCompletionItems(snippets, r, "2")
+
+	expected := []lsp.CompletionItem{
+		{
+			Label:            "assert",
+			Kind:             lsp.CIKSnippet,
+			Detail:           "assertion",
+			SortText:         "2_assert",
+			InsertTextFormat: lsp.ITFSnippet,
+			TextEdit: lsp.TextEdit{
+				Range:   r.ToLSP(),
+				NewText: "assert ${1:c};",
+			},
+		},
+		{
+			Label:            "if",
+			Kind:             lsp.CIKSnippet,
+			Detail:           "user conditional",
+			SortText:         "2_if",
+			InsertTextFormat: lsp.ITFSnippet,
+			TextEdit: lsp.TextEdit{
+				Range:   r.ToLSP(),
+				NewText: "if ${1:c} then ${2:a}",
+			},
+		},
+	}
+
+	assert.Equal(t, expected, got)
+}
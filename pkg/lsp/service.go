@@ -7,6 +7,12 @@ type InitializeParams struct {
 	RootPath              string             `json:"rootPath,omitempty"`
 	InitializationOptions interface{}        `json:"initializationOptions,omitempty"`
 	Capabilities          ClientCapabilities `json:"capabilities"`
+	WorkspaceFolders      []WorkspaceFolder  `json:"workspaceFolders,omitempty"`
+}
+
+type WorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
 }
 
 type ClientCapabilities struct {
@@ -20,6 +26,32 @@ type ClientCapabilities struct {
 	// XContentProvider indicates the client provides support for
 	// textDocument/xcontent. This is a Sourcegraph extension.
 	XContentProvider bool `json:"xcontentProvider,omitempty"`
+
+	Workspace WorkspaceClientCapabilities `json:"workspace,omitempty"`
+
+	TextDocument TextDocumentClientCapabilities `json:"textDocument,omitempty"`
+}
+
+type WorkspaceClientCapabilities struct {
+	Configuration          bool                                `json:"configuration,omitempty"`
+	WorkspaceFolders       bool                                `json:"workspaceFolders,omitempty"`
+	DidChangeConfiguration *DidChangeConfigurationCapabilities `json:"didChangeConfiguration,omitempty"`
+}
+
+type DidChangeConfigurationCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+type TextDocumentClientCapabilities struct {
+	Completion *CompletionClientCapabilities `json:"completion,omitempty"`
+}
+
+type CompletionClientCapabilities struct {
+	CompletionItem *CompletionItemClientCapabilities `json:"completionItem,omitempty"`
+}
+
+type CompletionItemClientCapabilities struct {
+	SnippetSupport bool `json:"snippetSupport,omitempty"`
 }
 
 type InitializeResult struct {
@@ -53,7 +85,22 @@ type ServerCapabilities struct {
 	DocumentFormattingProvider       bool                             `json:"documentFormattingProvider,omitempty"`
 	DocumentRangeFormattingProvider  bool                             `json:"documentRangeFormattingProvider,omitempty"`
 	DocumentOnTypeFormattingProvider *DocumentOnTypeFormattingOptions `json:"documentOnTypeFormattingProvider,omitempty"`
-	RenameProvider                   bool                             `json:"renameProvider,omitempty"`
+	RenameProvider                   *RenameOptions                   `json:"renameProvider,omitempty"`
+	InlayHintProvider                bool                             `json:"inlayHintProvider,omitempty"`
+	Workspace                        *ServerWorkspaceCapabilities     `json:"workspace,omitempty"`
+}
+
+type ServerWorkspaceCapabilities struct {
+	WorkspaceFolders *WorkspaceFoldersServerCapabilities `json:"workspaceFolders,omitempty"`
+}
+
+type WorkspaceFoldersServerCapabilities struct {
+	Supported           bool `json:"supported,omitempty"`
+	ChangeNotifications bool `json:"changeNotifications,omitempty"`
+}
+
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider,omitempty"`
 }
 
 type CompletionOptions struct {
@@ -71,7 +118,8 @@ type CodeLensOptions struct {
 }
 
 type SignatureHelpOptions struct {
-	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+	TriggerCharacters   []string `json:"triggerCharacters,omitempty"`
+	RetriggerCharacters []string `json:"retriggerCharacters,omitempty"`
 }
 
 type CompletionItemKind int
@@ -95,18 +143,28 @@ const (
 	CIKColor                          = 16
 	CIKFile                           = 17
 	CIKReference                      = 18
+	CIKFolder                         = 19
+)
+
+type InsertTextFormat int
+
+const (
+	ITFPlainText InsertTextFormat = 1
+	ITFSnippet                    = 2
 )
 
 type CompletionItem struct {
-	Label         string      `json:"label"`
-	Kind          int         `json:"kind,omitempty"`
-	Detail        string      `json:"detail,omitempty"`
-	Documentation string      `json:"documentation,omitempty"`
-	SortText      string      `json:"sortText,omitempty"`
-	FilterText    string      `json:"filterText,omitempty"`
-	InsertText    string      `json:"insertText,omitempty"`
-	TextEdit      TextEdit    `json:"textEdit,omitempty"`
-	Data          interface{} `json:"data,omitempty"`
+	Label            string      `json:"label"`
+	Kind             int         `json:"kind,omitempty"`
+	Detail           string      `json:"detail,omitempty"`
+	Documentation    string      `json:"documentation,omitempty"`
+	Deprecated       bool        `json:"deprecated,omitempty"`
+	SortText         string      `json:"sortText,omitempty"`
+	FilterText       string      `json:"filterText,omitempty"`
+	InsertText       string      `json:"insertText,omitempty"`
+	InsertTextFormat int         `json:"insertTextFormat,omitempty"`
+	TextEdit         TextEdit    `json:"textEdit,omitempty"`
+	Data             interface{} `json:"data,omitempty"`
 }
 
 type CompletionList struct {
@@ -115,8 +173,21 @@ type CompletionList struct {
 }
 
 type Hover struct {
-	Contents []MarkedString `json:"contents,omitempty"`
-	Range    Range          `json:"range"`
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+// MarkupKind is the format of MarkupContent.
+type MarkupKind string
+
+const (
+	MKPlainText MarkupKind = "plaintext"
+	MKMarkdown  MarkupKind = "markdown"
+)
+
+type MarkupContent struct {
+	Kind  MarkupKind `json:"kind"`
+	Value string     `json:"value"`
 }
 
 type MarkedString struct {
@@ -225,6 +296,21 @@ type CodeActionParams struct {
 	Context      CodeActionContext      `json:"context"`
 }
 
+type CodeActionKind string
+
+const (
+	QuickFix        CodeActionKind = "quickfix"
+	RefactorRewrite CodeActionKind = "refactor.rewrite"
+)
+
+type CodeAction struct {
+	Title       string         `json:"title"`
+	Kind        CodeActionKind `json:"kind,omitempty"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit,omitempty"`
+	Command     *Command       `json:"command,omitempty"`
+}
+
 type CodeLensParams struct {
 	TextDocument TextDocumentIdentifier `json:"textDocument"`
 }
@@ -235,6 +321,26 @@ type CodeLens struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+type InlayHintKind int
+
+const (
+	IHKType      InlayHintKind = 1
+	IHKParameter InlayHintKind = 2
+)
+
+type InlayHint struct {
+	Position     Position      `json:"position"`
+	Label        string        `json:"label"`
+	Kind         InlayHintKind `json:"kind,omitempty"`
+	PaddingLeft  bool          `json:"paddingLeft,omitempty"`
+	PaddingRight bool          `json:"paddingRight,omitempty"`
+}
+
 type DocumentFormattingParams struct {
 	TextDocument TextDocumentIdentifier `json:"textDocument"`
 	Options      FormattingOptions      `json:"options"`
@@ -246,6 +352,11 @@ type FormattingOptions struct {
 	Key          string `json:"key"`
 }
 
+type PrepareRenameResult struct {
+	Range       Range  `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
 type RenameParams struct {
 	TextDocument TextDocumentIdentifier `json:"textDocument"`
 	Position     Position               `json:"position"`
@@ -308,6 +419,24 @@ type DidChangeConfigurationParams struct {
 	Settings interface{} `json:"settings"`
 }
 
+type ConfigurationItem struct {
+	ScopeURI string `json:"scopeUri,omitempty"`
+	Section  string `json:"section,omitempty"`
+}
+
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+type DidChangeWorkspaceFoldersParams struct {
+	Event WorkspaceFoldersChangeEvent `json:"event"`
+}
+
+type WorkspaceFoldersChangeEvent struct {
+	Added   []WorkspaceFolder `json:"added"`
+	Removed []WorkspaceFolder `json:"removed"`
+}
+
 type FileChangeType int
 
 const (
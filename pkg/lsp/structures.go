@@ -56,6 +56,27 @@ type Diagnostic struct {
 	 * The diagnostic's message.
 	 */
 	Message string `json:"message"`
+
+	/**
+	 * An array of related diagnostic information, e.g. when symbol-names within
+	 * a scope collide all definitions can be marked via this property.
+	 */
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+/**
+ * Represents a related message and source code location for a diagnostic.
+ */
+type DiagnosticRelatedInformation struct {
+	/**
+	 * The location of this related diagnostic information.
+	 */
+	Location Location `json:"location"`
+
+	/**
+	 * The message of this related diagnostic information.
+	 */
+	Message string `json:"message"`
 }
 
 type DiagnosticSeverity int
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/static"
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+var codeActions = token.NewDefaultCodeActionRegistry()
+
+func textDocumentCodeAction(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.CodeActionParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	doc, err := c.Text(ctx, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := uri.ToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, err := c.ForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// the lsp diagnostics are kept so actions can return them as sent.
+	diagnostics := make(map[token.CodeActionDiagnostic]lsp.Diagnostic)
+
+	req := token.CodeActionRequest{
+		Filename: path,
+		Source:   doc.String(),
+		Range:    rangeFromLSP(params.Range),
+		LibPaths: pc.JsonnetLibPaths(),
+	}
+
+	for _, d := range params.Context.Diagnostics {
+		cad := token.CodeActionDiagnostic{
+			Code:    static.DiagnosticCode(d.Code),
+			Message: d.Message,
+			Range:   rangeFromLSP(d.Range),
+		}
+		diagnostics[cad] = d
+		req.Diagnostics = append(req.Diagnostics, cad)
+	}
+
+	actions, err := codeActions.CodeActions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]lsp.CodeAction, 0, len(actions))
+	for _, action := range actions {
+		edit := &lsp.WorkspaceEdit{
+			Changes: make(map[string][]lsp.TextEdit),
+		}
+
+		for _, e := range action.Edits {
+			edit.Changes[params.TextDocument.URI] = append(edit.Changes[params.TextDocument.URI], lsp.TextEdit{
+				Range:   e.Range.ToLSP(),
+				NewText: e.NewText,
+			})
+		}
+
+		ca := lsp.CodeAction{
+			Title: action.Title,
+			Kind:  action.Kind,
+			Edit:  edit,
+		}
+
+		for _, d := range action.Diagnostics {
+			ca.Diagnostics = append(ca.Diagnostics, diagnostics[d])
+		}
+
+		response = append(response, ca)
+	}
+
+	return response, nil
+}
+
+func rangeFromLSP(r lsp.Range) jpos.Range {
+	return jpos.NewRange(jpos.FromLSPPosition(r.Start), jpos.FromLSPPosition(r.End))
+}
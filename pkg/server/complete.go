@@ -51,7 +51,7 @@ func newComplete(rp lsp.ReferenceParams, cfg *config.Config) (*complete, error)
 	}
 
 	jpm := newJsonnetPathManager(cfg)
-	mh := newMatchHandler(jpm, cfg.NodeCache())
+	mh := newMatchHandler(jpm, cfg.NodeCache(), cfg.SnippetSupport())
 	if err := mh.register(c.completionMatcher); err != nil {
 		return nil, err
 	}
@@ -127,6 +127,7 @@ func (c *complete) handle(ctx context.Context) (interface{}, error) {
 				Kind:          lsp.CIKVariable,
 				Detail:        e.Detail,
 				Documentation: e.Documentation,
+				Deprecated:    e.Doc.Deprecated,
 				SortText:      fmt.Sprintf("0_%s", k),
 				TextEdit: lsp.TextEdit{
 					Range:   editRange.ToLSP(),
@@ -152,6 +153,15 @@ func (c *complete) handle(ctx context.Context) (interface{}, error) {
 		list.Items = append(list.Items, ci)
 	}
 
+	// clients without snippet support would insert the snippet syntax.
+	if c.config.SnippetSupport() {
+		snippets := append([]langserver.Snippet{}, langserver.DefaultSnippets...)
+		for _, snippet := range c.config.Snippets() {
+			snippets = append(snippets, langserver.Snippet(snippet))
+		}
+		list.Items = append(list.Items, langserver.SnippetCompletionItems(snippets, editRange, "2")...)
+	}
+
 	return list, nil
 
 }
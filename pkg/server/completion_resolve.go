@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/text"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	completionDataField = "field"
+	completionDataStd   = "std"
+)
+
+// completionData is carried by completion items so their detail and
+// documentation can be computed when the item is resolved.
+type completionData struct {
+	Kind string `json:"kind"`
+	// Path and Position locate the index being completed.
+	Path     string       `json:"path,omitempty"`
+	Position lsp.Position `json:"position"`
+	Name     string       `json:"name"`
+	// File and Declaration locate the name of a field in the file
+	// declaring it. Fields found through the inferred type of an object
+	// don't have a declaration.
+	File        string       `json:"file,omitempty"`
+	Declaration lsp.Position `json:"declaration"`
+	// Hidden is true if the field is hidden. Visibility is inherited, so
+	// it can't be found from the declaration.
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+func completionItemResolve(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	var ci lsp.CompletionItem
+	if err := r.Decode(&ci); err != nil {
+		return nil, err
+	}
+
+	return resolveCompletionItem(ctx, ci, c)
+}
+
+func resolveCompletionItem(ctx context.Context, ci lsp.CompletionItem, c *config.Config) (lsp.CompletionItem, error) {
+	if ci.Data == nil {
+		return ci, nil
+	}
+
+	data, err := decodeCompletionData(ci.Data)
+	if err != nil {
+		return ci, err
+	}
+
+	switch data.Kind {
+	case completionDataStd:
+		return resolveStdItem(ci, data)
+	case completionDataField:
+		return resolveFieldItem(ctx, ci, data, c)
+	default:
+		return ci, errors.Errorf("unknown completion item kind %q", data.Kind)
+	}
+}
+
+func decodeCompletionData(v interface{}) (completionData, error) {
+	var data completionData
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return data, errors.Wrap(err, "encoding completion item data")
+	}
+
+	if err := json.Unmarshal(b, &data); err != nil {
+		return data, errors.Wrap(err, "decoding completion item data")
+	}
+
+	return data, nil
+}
+
+func resolveStdItem(ci lsp.CompletionItem, data completionData) (lsp.CompletionItem, error) {
+	stdlib, err := token.LoadStdlib()
+	if err != nil {
+		return ci, err
+	}
+
+	f, ok := stdlib.Function(data.Name)
+	if !ok {
+		return ci, errors.Errorf("std.%s does not exist", data.Name)
+	}
+
+	ci.Detail = f.Signature()
+	ci.Documentation = f.Documentation
+
+	return ci, nil
+}
+
+// resolveFieldItem finds the object field at its declaration. The detail
+// describes the inferred type of the field in the file declaring it, and
+// the documentation is the comment before the field name in that file.
+// Fields without a declaration were described when they were completed.
+func resolveFieldItem(ctx context.Context, ci lsp.CompletionItem, data completionData, c *config.Config) (lsp.CompletionItem, error) {
+	span := opentracing.SpanFromContext(ctx)
+
+	if data.File == "" {
+		return ci, nil
+	}
+
+	pc, err := c.ForPath(data.Path)
+	if err != nil {
+		return ci, err
+	}
+
+	declared, err := pc.Text(ctx, uri.FromPath(data.File))
+	if err != nil {
+		return ci, errors.Wrap(err, "loading declaring text")
+	}
+
+	// fields in the document being edited are found in the source before
+	// the index, which parses even if the document doesn't.
+	source := declared.String()
+	if data.File == data.Path {
+		source, err = text.Truncate(source, position.FromLSPPosition(data.Position))
+		if err != nil {
+			return ci, err
+		}
+	}
+
+	declPos := position.FromLSPPosition(data.Declaration)
+	loc := declPos.ToJsonnet()
+
+	field, err := token.DeclaredField(ctx, data.File, source, loc, pc.JsonnetLibPaths(), pc.NodeCache())
+	if err != nil {
+		return ci, errors.Wrapf(err, "unable to find field %q", data.Name)
+	}
+
+	field.Hidden = data.Hidden
+	ci.Detail = fieldDetail(*field)
+
+	comment, err := token.DocComment(data.File, declared.String(), loc)
+	if err != nil {
+		span.LogFields(log.Error(err))
+		return ci, nil
+	}
+
+	doc := token.ParseDoc(comment)
+	ci.Documentation = doc.Text()
+	ci.Deprecated = doc.Deprecated
+
+	return ci, nil
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_resolveCompletionItem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resolve")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	lib := "{\n  // Creates a deployment.\n  deployment(name):: {},\n  replicas: 1,\n  port: 80 + 1,\n  // @deprecated use replicas\n  count: 1,\n}"
+	libPath := filepath.Join(dir, "lib.libsonnet")
+	require.NoError(t, ioutil.WriteFile(libPath, []byte(lib), 0644))
+
+	path := filepath.Join(dir, "file.jsonnet")
+	source := "local o = {\n  // The name.\n  name: 'app',\n};\no."
+
+	importPath := filepath.Join(dir, "import.jsonnet")
+	importSource := "local lib = import 'lib.libsonnet';\nlib."
+
+	c := config.New()
+	for p, s := range map[string]string{path: source, importPath: importSource} {
+		td := config.NewTextDocument(uri.FromPath(p), s)
+		require.NoError(t, c.StoreTextDocumentItem(testContext(), td))
+	}
+
+	cases := []struct {
+		name     string
+		data     completionData
+		expected lsp.CompletionItem
+		isErr    bool
+	}{
+		{
+			name: "field in same file",
+			data: completionData{
+				Kind:        completionDataField,
+				Path:        path,
+				Position:    lsp.Position{Line: 4, Character: 2},
+				Name:        "name",
+				File:        path,
+				Declaration: lsp.Position{Line: 2, Character: 2},
+			},
+			expected: lsp.CompletionItem{
+				Detail:        `(string) "app"`,
+				Documentation: "The name.",
+			},
+		},
+		{
+			name: "imported method",
+			data: completionData{
+				Kind:        completionDataField,
+				Path:        importPath,
+				Position:    lsp.Position{Line: 1, Character: 4},
+				Name:        "deployment",
+				File:        libPath,
+				Declaration: lsp.Position{Line: 2, Character: 2},
+				Hidden:      true,
+			},
+			expected: lsp.CompletionItem{
+				Detail:        "(hidden) deployment(name)",
+				Documentation: "Creates a deployment.",
+			},
+		},
+		{
+			name: "imported field without comment",
+			data: completionData{
+				Kind:        completionDataField,
+				Path:        importPath,
+				Position:    lsp.Position{Line: 1, Character: 4},
+				Name:        "replicas",
+				File:        libPath,
+				Declaration: lsp.Position{Line: 3, Character: 2},
+			},
+			expected: lsp.CompletionItem{
+				Detail: "(number) 1",
+			},
+		},
+		{
+			name: "field with inferred type",
+			data: completionData{
+				Kind:        completionDataField,
+				Path:        importPath,
+				Position:    lsp.Position{Line: 1, Character: 4},
+				Name:        "port",
+				File:        libPath,
+				Declaration: lsp.Position{Line: 4, Character: 2},
+			},
+			expected: lsp.CompletionItem{
+				Detail: "(number)",
+			},
+		},
+		{
+			name: "deprecated field",
+			data: completionData{
+				Kind:        completionDataField,
+				Path:        importPath,
+				Position:    lsp.Position{Line: 1, Character: 4},
+				Name:        "count",
+				File:        libPath,
+				Declaration: lsp.Position{Line: 6, Character: 2},
+			},
+			expected: lsp.CompletionItem{
+				Detail:        "(number) 1",
+				Documentation: "Deprecated: use replicas",
+				Deprecated:    true,
+			},
+		},
+		{
+			// fields found through the inferred type are described when
+			// they are completed.
+			name: "field without declaration",
+			data: completionData{
+				Kind:     completionDataField,
+				Path:     path,
+				Position: lsp.Position{Line: 4, Character: 2},
+				Name:     "name",
+			},
+			expected: lsp.CompletionItem{},
+		},
+		{
+			name: "std function",
+			data: completionData{
+				Kind: completionDataStd,
+				Name: "length",
+			},
+			expected: lsp.CompletionItem{
+				Detail: "std.length(x)",
+			},
+		},
+		{
+			name: "missing field",
+			data: completionData{
+				Kind:        completionDataField,
+				Path:        path,
+				Position:    lsp.Position{Line: 4, Character: 2},
+				Name:        "missing",
+				File:        path,
+				Declaration: lsp.Position{Line: 3, Character: 2},
+			},
+			isErr: true,
+		},
+		{
+			name:  "unknown kind",
+			data:  completionData{Kind: "unknown"},
+			isErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ci := completionItemWithData(t, tc.data)
+
+			got, err := resolveCompletionItem(testContext(), ci, c)
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected.Detail, got.Detail)
+			assert.Equal(t, tc.expected.Deprecated, got.Deprecated)
+			if tc.data.Kind == completionDataStd {
+				assert.NotEmpty(t, got.Documentation)
+			} else {
+				assert.Equal(t, tc.expected.Documentation, got.Documentation)
+			}
+		})
+	}
+}
+
+// completionItemWithData creates a completion item with data the way it
+// is decoded from a client request.
+func completionItemWithData(t *testing.T, data completionData) lsp.CompletionItem {
+	pos := position.New(1, 1)
+	r := position.NewRange(pos, pos)
+
+	ci := createCompletionItem("label", "text", lsp.CIKField, r, nil)
+	ci.Data = data
+
+	b, err := json.Marshal(ci)
+	require.NoError(t, err)
+
+	var decoded lsp.CompletionItem
+	require.NoError(t, json.Unmarshal(b, &decoded))
+
+	return decoded
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+func initialized(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	capabilities := c.ClientCapabilities().Workspace
+
+	// requests to the client can't be made until this notification has
+	// been handled.
+	go func() {
+		if dcc := capabilities.DidChangeConfiguration; dcc != nil && dcc.DynamicRegistration {
+			if _, err := r.RegisterCapability(ctx, "workspace/didChangeConfiguration", nil); err != nil {
+				span.LogFields(log.Error(err))
+			}
+		}
+
+		if capabilities.Configuration {
+			reportConfigurationError(ctx, r, pullConfiguration(ctx, r, c))
+		}
+	}()
+
+	return nil, nil
+}
+
+func workspaceDidChangeConfiguration(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.DidChangeConfigurationParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	// clients using the pull model don't send the settings with the
+	// notification.
+	section, ok := settingsSection(params.Settings)
+	if !ok {
+		if c.ClientCapabilities().Workspace.Configuration {
+			go func() {
+				reportConfigurationError(ctx, r, pullConfiguration(ctx, r, c))
+			}()
+		}
+
+		return nil, nil
+	}
+
+	if err := c.UpdateSettings(ctx, section); err != nil {
+		reportConfigurationError(ctx, r, err)
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// pullConfiguration requests the jsonnet settings for the workspace and
+// each workspace folder from the client and updates the configuration
+// with them.
+func pullConfiguration(ctx context.Context, r *request, c *config.Config) error {
+	folders := c.WorkspaceFolders()
+
+	params := &lsp.ConfigurationParams{
+		Items: []lsp.ConfigurationItem{
+			{Section: config.SettingsSection},
+		},
+	}
+
+	for _, folder := range folders {
+		params.Items = append(params.Items, lsp.ConfigurationItem{
+			ScopeURI: uri.FromPath(folder.Path),
+			Section:  config.SettingsSection,
+		})
+	}
+
+	var result []interface{}
+	if err := r.conn.Call(ctx, "workspace/configuration", params, &result); err != nil {
+		return errors.Wrap(err, "requesting configuration")
+	}
+
+	for i, v := range result {
+		if v == nil || i > len(folders) {
+			continue
+		}
+
+		section, ok := v.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("configuration section %q is %T, not an object", config.SettingsSection, v)
+		}
+
+		if i == 0 {
+			if err := c.UpdateSettings(ctx, section); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := c.UpdateWorkspaceFolderSettings(ctx, folders[i-1].Path, section); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// settingsSection returns the jsonnet section from client settings.
+func settingsSection(settings interface{}) (map[string]interface{}, bool) {
+	m, ok := settings.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	section, ok := m[config.SettingsSection].(map[string]interface{})
+	return section, ok
+}
+
+func reportConfigurationError(ctx context.Context, r *request, err error) {
+	if err == nil {
+		return
+	}
+
+	span := opentracing.SpanFromContext(ctx)
+	span.LogFields(log.Error(err))
+
+	if msgErr := showMessage(ctx, r, lsp.MTError, err.Error()); msgErr != nil {
+		span.LogFields(log.Error(msgErr))
+	}
+}
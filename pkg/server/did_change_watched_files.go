@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+func workspaceDidChangeWatchedFiles(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.DidChangeWatchedFilesParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	for _, change := range params.Changes {
+		span.LogFields(
+			log.String("uri", change.URI),
+			log.Int("type", change.Type),
+		)
+
+		path, err := uri.ToPath(change.URI)
+		if err != nil {
+			span.LogFields(log.Error(err))
+			continue
+		}
+
+		if c.ReloadProject(path) {
+			if err := refreshTextDocuments(c)(ctx, path); err != nil {
+				span.LogFields(log.Error(err))
+			}
+			continue
+		}
+
+		if !token.IsJsonnetFile(path) {
+			continue
+		}
+
+		switch lsp.FileChangeType(change.Type) {
+		case lsp.Deleted:
+			c.SymbolIndex().Remove(path)
+		default:
+			if err := c.SymbolIndex().IndexFile(ctx, path); err != nil {
+				span.LogFields(log.Error(err))
+			}
+		}
+
+		if err := invalidateNodeCache(ctx, c, path); err != nil {
+			span.LogFields(log.Error(err))
+		}
+	}
+
+	return nil, nil
+}
+
+// invalidateNodeCache evicts the node cache entries for path and the
+// entries which depend on it. Open documents importing any of the
+// evicted files are refreshed so their diagnostics are published again.
+func invalidateNodeCache(ctx context.Context, c *config.Config, path string) error {
+	scopes, err := nodeCacheScopes(c, path)
+	if err != nil {
+		return err
+	}
+
+	// files can be imported from any workspace folder.
+	affected := map[string]bool{path: true}
+	for _, scope := range scopes {
+		for _, name := range token.ImportNames(path, scope.libPaths) {
+			keys, err := scope.nodeCache.Invalidate(ctx, name)
+			if err != nil {
+				return err
+			}
+
+			for _, key := range keys {
+				keyPath, err := token.ImportPath(key, scope.libPaths)
+				if err != nil {
+					continue
+				}
+				affected[keyPath] = true
+			}
+		}
+	}
+
+	for _, td := range c.TextDocuments() {
+		filename, err := td.Filename()
+		if err != nil || filename == path {
+			continue
+		}
+
+		tdc, err := c.ForPath(filename)
+		if err != nil {
+			continue
+		}
+
+		imports, err := token.SourceImports(filename, td.String(), tdc.JsonnetLibPaths())
+		if err != nil {
+			continue
+		}
+
+		for _, imported := range imports {
+			if !affected[imported] {
+				continue
+			}
+
+			if err := c.RefreshTextDocument(ctx, td.URI()); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// nodeCacheScope is a node cache and the lib paths its entries were
+// imported with.
+type nodeCacheScope struct {
+	nodeCache *token.NodeCache
+	libPaths  []string
+}
+
+// nodeCacheScopes returns the node caches of the workspace and its
+// folders with the lib paths of their documents. The cache path belongs
+// to uses the lib paths of path, which include its project's lib paths.
+func nodeCacheScopes(c *config.Config, path string) ([]nodeCacheScope, error) {
+	pc, err := c.ForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := []*config.Config{c}
+	for _, folder := range c.WorkspaceFolders() {
+		fc, err := c.ForPath(folder.Path)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, fc)
+	}
+
+	var scopes []nodeCacheScope
+	for _, sc := range configs {
+		if sc.NodeCache() == pc.NodeCache() {
+			sc = pc
+		}
+
+		scopes = append(scopes, nodeCacheScope{
+			nodeCache: sc.NodeCache(),
+			libPaths:  sc.JsonnetLibPaths(),
+		})
+	}
+
+	return scopes, nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_nodeCacheScopes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watched")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	app := filepath.Join(dir, "b", "app")
+	require.NoError(t, os.MkdirAll(app, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(app, config.Jsonnetfile), []byte("{}"), 0644))
+
+	c := config.New()
+	ctx := opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+	err = c.UpdateClientConfiguration(ctx, map[string]interface{}{
+		config.JsonnetLibPaths: []string{"/client"},
+	})
+	require.NoError(t, err)
+
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	c.AddWorkspaceFolder(ctx, a, "a")
+	c.AddWorkspaceFolder(ctx, b, "b")
+
+	err = c.UpdateWorkspaceFolderSettings(ctx, a, map[string]interface{}{
+		"libPaths": []interface{}{"lib"},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(app, "main.jsonnet")
+	scopes, err := nodeCacheScopes(c, path)
+	require.NoError(t, err)
+
+	pa, err := c.ForPath(filepath.Join(a, "main.jsonnet"))
+	require.NoError(t, err)
+	pb, err := c.ForPath(path)
+	require.NoError(t, err)
+
+	expected := []nodeCacheScope{
+		{nodeCache: c.NodeCache(), libPaths: []string{"/client"}},
+		{nodeCache: pa.NodeCache(), libPaths: []string{filepath.Join(a, "lib"), "/client"}},
+		{nodeCache: pb.NodeCache(), libPaths: []string{filepath.Join(app, "vendor"), "/client"}},
+	}
+
+	require.Len(t, scopes, len(expected))
+	for i := range expected {
+		assert.True(t, expected[i].nodeCache == scopes[i].nodeCache, "node cache %d", i)
+		assert.Equal(t, expected[i].libPaths, scopes[i].libPaths)
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+)
+
+// watchEvalDiagnostics refreshes the open documents when evaluation
+// diagnostics are enabled or disabled, so their evaluation diagnostics
+// are published or cleared.
+func watchEvalDiagnostics(c *config.Config) {
+	c.Watch(config.JsonnetEvalDiagnostics, refreshTextDocuments(c))
+}
+
+// watchLint refreshes the open documents when diagnostic levels change,
+// so their diagnostics are published again.
+func watchLint(c *config.Config) {
+	c.Watch(config.JsonnetLint, refreshTextDocuments(c))
+}
+
+// watchVMVariables refreshes the open documents when external variables
+// or top level arguments change, so they are analyzed with the new
+// values.
+func watchVMVariables(c *config.Config) {
+	for _, k := range config.VMVariableSettings() {
+		c.Watch(k, refreshTextDocuments(c))
+	}
+}
+
+// refreshTextDocuments creates a dispatch function which refreshes the
+// open documents.
+func refreshTextDocuments(c *config.Config) config.DispatchFn {
+	return func(ctx context.Context, v interface{}) error {
+		for _, td := range c.TextDocuments() {
+			if err := c.RefreshTextDocument(ctx, td.URI()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
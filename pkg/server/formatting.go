@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/formatter"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+func textDocumentFormatting(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.DocumentFormattingParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	opts := formatterOptions(c, params.Options)
+
+	return formatDocument(ctx, c, params.TextDocument.URI, opts, 0, -1)
+}
+
+func textDocumentRangeFormatting(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.DocumentRangeFormattingParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	// sorting imports moves lines, so it only happens when the whole
+	// document is formatted.
+	opts := formatterOptions(c, params.Options)
+	opts.SortImports = false
+
+	start, end := params.Range.Start.Line, params.Range.End.Line
+	if end > start && params.Range.End.Character == 0 {
+		end--
+	}
+
+	return formatDocument(ctx, c, params.TextDocument.URI, opts, start, end)
+}
+
+func textDocumentOnTypeFormatting(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.DocumentOnTypeFormattingParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	opts := formatterOptions(c, params.Options)
+	opts.SortImports = false
+
+	// a newline finishes the previous line. The new line is left for the
+	// editor to indent.
+	line := params.Position.Line
+	if params.Ch == "\n" {
+		line--
+	}
+
+	if line < 0 {
+		return []lsp.TextEdit{}, nil
+	}
+
+	return formatDocument(ctx, c, params.TextDocument.URI, opts, line, line)
+}
+
+// formatDocument formats a document and returns edits for the lines
+// from start to end. If end is negative, edits for the whole document
+// are returned.
+func formatDocument(ctx context.Context, c *config.Config, uriStr string, opts formatter.Options, start, end int) ([]lsp.TextEdit, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "formatDocument")
+	defer span.Finish()
+
+	doc, err := c.Text(ctx, uriStr)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := uri.ToPath(uriStr)
+	if err != nil {
+		return nil, err
+	}
+
+	source := doc.String()
+
+	formatted, err := formatter.Format(path, source, opts)
+	if err != nil {
+		// source that can't be lexed can't be formatted.
+		span.LogFields(log.Error(err))
+		return []lsp.TextEdit{}, nil
+	}
+
+	if end < 0 {
+		end = strings.Count(source, "\n")
+	}
+
+	return formattingEdits(source, formatted, start, end), nil
+}
+
+// formatterOptions merges the formatting options of a request into the
+// formatting settings.
+func formatterOptions(c *config.Config, fo lsp.FormattingOptions) formatter.Options {
+	opts := c.FormatOptions()
+	if fo.TabSize > 0 {
+		opts.Indent = fo.TabSize
+	}
+
+	return opts
+}
+
+// formattingEdits creates edits that change the lines from start to end
+// in source to their formatted versions. Formatting keeps line breaks, so
+// only the end of the document can have a different number of lines.
+func formattingEdits(source, formatted string, start, end int) []lsp.TextEdit {
+	src := strings.Split(source, "\n")
+	out := strings.Split(formatted, "\n")
+
+	n := len(src)
+	if len(out) < n {
+		n = len(out)
+	}
+
+	edits := []lsp.TextEdit{}
+
+	for i := start; i <= end && i < n-1; i++ {
+		if src[i] == out[i] {
+			continue
+		}
+
+		edits = append(edits, lsp.TextEdit{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: i},
+				End:   lsp.Position{Line: i, Character: utf16Len(src[i])},
+			},
+			NewText: out[i],
+		})
+	}
+
+	if end < n-1 {
+		return edits
+	}
+
+	last := len(src) - 1
+	text := strings.Join(out[n-1:], "\n")
+	if strings.Join(src[n-1:], "\n") != text {
+		edits = append(edits, lsp.TextEdit{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: n - 1},
+				End:   lsp.Position{Line: last, Character: utf16Len(src[last])},
+			},
+			NewText: text,
+		})
+	}
+
+	return edits
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
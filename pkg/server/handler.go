@@ -27,19 +27,32 @@ import (
 type operation func(context.Context, *request, *config.Config) (interface{}, error)
 
 var operations = map[string]operation{
-	"completionItem/resolve":         completionItemResolve,
-	"initialize":                     initialize,
-	"textDocument/completion":        textDocumentCompletion,
-	"textDocument/didChange":         textDocumentDidChange,
-	"textDocument/didClose":          textDocumentDidClose,
-	"textDocument/didOpen":           textDocumentDidOpen,
-	"textDocument/didSave":           textDocumentDidSave,
-	"textDocument/documentHighlight": textDocumentHighlight,
-	"textDocument/documentSymbol":    textDocumentSymbol,
-	"textDocument/hover":             textDocumentHover,
-	"textDocument/references":        textDocumentReferences,
-	"textDocument/signatureHelp":     textDocumentSignatureHelper,
-	"updateClientConfiguration":      updateClientConfiguration,
+	"completionItem/resolve":              completionItemResolve,
+	"initialize":                          initialize,
+	"initialized":                         initialized,
+	"textDocument/codeAction":             textDocumentCodeAction,
+	"textDocument/completion":             textDocumentCompletion,
+	"textDocument/definition":             textDocumentDefinition,
+	"textDocument/didChange":              textDocumentDidChange,
+	"textDocument/didClose":               textDocumentDidClose,
+	"textDocument/didOpen":                textDocumentDidOpen,
+	"textDocument/didSave":                textDocumentDidSave,
+	"textDocument/documentHighlight":      textDocumentHighlight,
+	"textDocument/documentSymbol":         textDocumentSymbol,
+	"textDocument/formatting":             textDocumentFormatting,
+	"textDocument/hover":                  textDocumentHover,
+	"textDocument/inlayHint":              textDocumentInlayHint,
+	"textDocument/onTypeFormatting":       textDocumentOnTypeFormatting,
+	"textDocument/prepareRename":          textDocumentPrepareRename,
+	"textDocument/rangeFormatting":        textDocumentRangeFormatting,
+	"textDocument/references":             textDocumentReferences,
+	"textDocument/rename":                 textDocumentRename,
+	"textDocument/signatureHelp":          textDocumentSignatureHelper,
+	"updateClientConfiguration":           updateClientConfiguration,
+	"workspace/didChangeConfiguration":    workspaceDidChangeConfiguration,
+	"workspace/didChangeWatchedFiles":     workspaceDidChangeWatchedFiles,
+	"workspace/didChangeWorkspaceFolders": workspaceDidChangeWorkspaceFolders,
+	"workspace/symbol":                    workspaceSymbol,
 }
 
 // Handler is a JSON RPC Handler
@@ -63,7 +76,12 @@ func NewHandler(zLogger *zap.Logger) *Handler {
 
 	zapLogger := zLogger.With(zap.String("component", "handler"))
 
-	tdw := lexical.NewTextDocumentWatcher(c, lexical.NewPerformDiagnostics())
+	publisher := lexical.NewDiagnosticPublisher()
+	dp := lexical.DocumentProcessors{
+		lexical.NewPerformDiagnostics(c, publisher),
+		lexical.NewPerformEvaluation(c, publisher),
+	}
+	tdw := lexical.NewTextDocumentWatcher(c, dp)
 
 	tracer, tracerCloser := initTracing("jsonnet-langauge-server", zapLogger)
 
@@ -155,7 +173,7 @@ func (lh *Handler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc
 		return
 	}
 
-	response, err := fn(ctx, r, lh.config)
+	response, err := fn(ctx, r, requestConfig(ctx, r, lh.config))
 	if err != nil {
 		span.LogFields(
 			log.Error(err),
@@ -180,17 +198,6 @@ func (lh *Handler) Handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc
 	}
 }
 
-func completionItemResolve(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
-	var ci lsp.CompletionItem
-	if err := r.Decode(&ci); err != nil {
-		return nil, err
-	}
-
-	// TODO figure out what do do here. This might not be needed and
-	// it can drop.
-	return nil, nil
-}
-
 func updateNodeCache(ctx context.Context, r *request, c *config.Config, uriStr string) {
 	span, ctx := tracing.ChildSpan(ctx, "updateNodeCache")
 	defer span.Finish()
@@ -203,6 +210,14 @@ func updateNodeCache(ctx context.Context, r *request, c *config.Config, uriStr s
 		return
 	}
 
+	pc, err := c.ForPath(path)
+	if err != nil {
+		span.LogFields(
+			log.Error(err),
+		)
+		return
+	}
+
 	// do notification stuff here
 
 	done := make(chan bool, 1)
@@ -212,7 +227,7 @@ func updateNodeCache(ctx context.Context, r *request, c *config.Config, uriStr s
 	defer timer.Stop()
 
 	go func() {
-		err := token.UpdateNodeCache(ctx, path, c.JsonnetLibPaths(), c.NodeCache())
+		err := token.UpdateNodeCache(ctx, path, pc.JsonnetLibPaths(), c.NodeCache())
 		if err != nil {
 			errCh <- err
 			return
@@ -271,6 +286,13 @@ func closeFile(ctx context.Context, c *config.Config, uriStr string) {
 			log.Error(err),
 		)
 	}
+
+	// changes that weren't saved are no longer part of the workspace.
+	if err := c.SymbolIndex().IndexFile(ctx, path); err != nil {
+		span.LogFields(
+			log.Error(err),
+		)
+	}
 }
 
 func textDocumentDidOpen(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
@@ -2,20 +2,21 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/text"
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/config"
 	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
 )
 
-var (
-	emptyHover = &lsp.Hover{}
-)
-
 func textDocumentHover(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
 	span := opentracing.SpanFromContext(ctx)
 	ctx = opentracing.ContextWithSpan(ctx, span)
@@ -63,29 +64,87 @@ func (h *hover) handle(ctx context.Context) (interface{}, error) {
 
 	pos := position.FromLSPPosition(h.params.Position)
 
-	ic, err := token.NewIdentifyConfig(h.path, h.config.JsonnetLibPaths()...)
+	d, err := token.Describe(ctx, h.path, text.String(), pos, h.config.JsonnetLibPaths(), h.config.NodeCache())
+	if err != nil {
+		// items which can't be found statically, e.g. the results of
+		// ext vars, are identified by evaluating the source.
+		span.LogFields(log.Error(err))
+		return h.identify(text.String(), pos)
+	}
+
+	return markdownHover(hoverMarkdown(d, h.config.RootPath())), nil
+}
+
+func (h *hover) identify(source string, pos position.Position) (interface{}, error) {
+	ic, err := h.config.IdentifyConfig(h.path)
 	if err != nil {
 		return nil, err
 	}
 
-	item, err := token.Identify(text.String(), pos, h.config.NodeCache(), ic)
+	item, err := token.Identify(source, pos, h.config.NodeCache(), ic)
 	if err != nil {
 		return nil, err
 	}
 
 	value := item.String()
 	if value == "" {
-		return emptyHover, nil
+		return nil, nil
 	}
 
-	response := &lsp.Hover{
-		Contents: []lsp.MarkedString{
-			{
-				Language: "jsonnet",
-				Value:    value,
-			},
-		},
+	sections := []string{codeBlock("jsonnet", value)}
+	if sig := item.Signature(); sig != nil && sig.Documentation() != "" {
+		sections = append(sections, sig.Documentation())
+	}
+
+	return markdownHover(strings.Join(sections, "\n\n")), nil
+}
+
+const (
+	hoverPreviewLines = 20
+	hoverPreviewWidth = 80
+)
+
+// hoverMarkdown renders a description with its signature or type, a
+// preview of its value, its documentation and where it is declared.
+// Declarations in rootPath are shown relative to rootPath.
+func hoverMarkdown(d *token.Description, rootPath string) string {
+	header := d.Name
+	switch {
+	case d.Signature != "":
+		header = d.Signature
+	case d.Type != "":
+		header = fmt.Sprintf("(%s) %s", d.Type, d.Name)
 	}
 
-	return response, nil
+	sections := []string{codeBlock("jsonnet", header)}
+
+	if d.Value != "" {
+		sections = append(sections, codeBlock("json", text.Preview(d.Value, hoverPreviewLines, hoverPreviewWidth)))
+	}
+
+	if d.Documentation != "" {
+		sections = append(sections, d.Documentation)
+	}
+
+	if path := d.Declaration.FileName; path != "" {
+		if rel, err := filepath.Rel(rootPath, path); rootPath != "" && err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		sections = append(sections, fmt.Sprintf("Declared in `%s`", path))
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+func codeBlock(language, code string) string {
+	return fmt.Sprintf("```%s\n%s\n```", language, code)
+}
+
+func markdownHover(value string) *lsp.Hover {
+	return &lsp.Hover{
+		Contents: lsp.MarkupContent{
+			Kind:  lsp.MKMarkdown,
+			Value: value,
+		},
+	}
 }
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_hover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hover")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file.jsonnet")
+	source := "// The labels.\nlocal labels = {app: 'web'};\n// Creates a service.\nlocal service(name, port=80) = {};\nservice(labels.app)"
+
+	c := config.New()
+	c.SetRootPath(dir)
+	td := config.NewTextDocument(uri.FromPath(path), source)
+	require.NoError(t, c.StoreTextDocumentItem(testContext(), td))
+
+	cases := []struct {
+		name     string
+		pos      lsp.Position
+		expected string
+	}{
+		{
+			name:     "constant object",
+			pos:      lsp.Position{Line: 4, Character: 9},
+			expected: "```jsonnet\n(object) labels\n```\n\n```json\n{\n  \"app\": \"web\"\n}\n```\n\nThe labels.\n\nDeclared in `file.jsonnet`",
+		},
+		{
+			name:     "function",
+			pos:      lsp.Position{Line: 4, Character: 2},
+			expected: "```jsonnet\nservice(name, port=80)\n```\n\nCreates a service.\n\nDeclared in `file.jsonnet`",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := lsp.TextDocumentPositionParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: uri.FromPath(path)},
+				Position:     tc.pos,
+			}
+
+			h, err := newHover(params, c)
+			require.NoError(t, err)
+
+			got, err := h.handle(testContext())
+			require.NoError(t, err)
+
+			expected := &lsp.Hover{
+				Contents: lsp.MarkupContent{
+					Kind:  lsp.MKMarkdown,
+					Value: tc.expected,
+				},
+			}
+			assert.Equal(t, expected, got)
+		})
+	}
+}
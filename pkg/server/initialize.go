@@ -20,25 +20,45 @@ func initialize(ctx context.Context, r *request, c *config.Config) (interface{},
 		return nil, err
 	}
 
+	// When lib paths or workspace folders are updated, tell the client
+	// to send watch updates for all the roots and lib paths.
 	fn := func(ctx context.Context, v interface{}) error {
 		span := opentracing.SpanFromContext(ctx)
 		ctx = opentracing.ContextWithSpan(ctx, span)
 
-		// When lib paths are updated, tell the client to send
-		// watch updates for all the lib paths.
+		options := &lsp.DidChangeWatchedFilesRegistrationOptions{
+			Watchers: make([]lsp.FileSystemWatcher, 0),
+		}
 
-		paths, ok := v.([]string)
-		if !ok {
-			span.LogFields(
-				log.Error(errors.New("lib paths are not []string")),
-			)
+		var roots []string
+		if root := c.RootPath(); root != "" {
+			roots = append(roots, root)
+		}
+		for _, folder := range c.WorkspaceFolders() {
+			roots = append(roots, folder.Path)
 		}
 
-		options := &lsp.DidChangeWatchedFilesRegistrationOptions{
-			Watchers: make([]lsp.FileSystemWatcher, 0),
+		for _, root := range roots {
+			for _, ext := range []string{"libsonnet", "jsonnet"} {
+				watcher := lsp.FileSystemWatcher{
+					GlobPattern: filepath.Join(filepath.Clean(root), "**", "*."+ext),
+					Kind:        lsp.WatchKindChange + lsp.WatchKindCreate + lsp.WatchKindDelete,
+				}
+
+				options.Watchers = append(options.Watchers, watcher)
+			}
+
+			for _, name := range config.ProjectFiles {
+				watcher := lsp.FileSystemWatcher{
+					GlobPattern: filepath.Join(filepath.Clean(root), "**", name),
+					Kind:        lsp.WatchKindChange + lsp.WatchKindCreate + lsp.WatchKindDelete,
+				}
+
+				options.Watchers = append(options.Watchers, watcher)
+			}
 		}
 
-		for _, path := range paths {
+		for _, path := range c.JsonnetLibPaths() {
 			path = filepath.Clean(path)
 			for _, ext := range []string{"libsonnet", "jsonnet"} {
 				watcher := lsp.FileSystemWatcher{
@@ -50,24 +70,48 @@ func initialize(ctx context.Context, r *request, c *config.Config) (interface{},
 			}
 		}
 
-		if _, err := r.RegisterCapability(ctx, "workspace/didChangeWatchedFiles", options); err != nil {
-			span.LogFields(log.Error(err))
-		}
+		// registering capabilities calls the client, so it can't block
+		// the request which changed the configuration.
+		go func() {
+			if _, err := r.RegisterCapability(ctx, "workspace/didChangeWatchedFiles", options); err != nil {
+				span.LogFields(log.Error(err))
+			}
+		}()
 
 		return nil
 	}
 
-	c.Watch(config.JsonnetLibPaths, fn)
+	c.SetRootPath(ip.RootPath)
+	c.SetClientCapabilities(ip.Capabilities)
 
-	update, ok := ip.InitializationOptions.(map[string]interface{})
-	if !ok {
-		return nil, errors.New("initialization options are incorrect type")
+	if err := addWorkspaceFolders(ctx, c, ip.WorkspaceFolders); err != nil {
+		return nil, err
 	}
 
-	if err := c.UpdateClientConfiguration(ctx, update); err != nil {
-		return nil, err
+	c.Watch(config.JsonnetLibPaths, fn)
+	c.Watch(config.WorkspaceFolderUpdates, fn)
+	watchWorkspaceFolders(c)
+	watchSymbolIndex(c)
+	watchEvalDiagnostics(c)
+	watchVMVariables(c)
+	watchLint(c)
+
+	// generic clients send their configuration with
+	// workspace/didChangeConfiguration or workspace/configuration
+	// instead of initialization options.
+	if ip.InitializationOptions != nil {
+		update, ok := ip.InitializationOptions.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("initialization options are incorrect type")
+		}
+
+		if err := c.UpdateClientConfiguration(ctx, update); err != nil {
+			return nil, err
+		}
 	}
 
+	go indexWorkspace(ctx, c)
+
 	span.LogFields(
 		log.String("workspace", ip.RootPath),
 		log.String("config", c.String()),
@@ -75,17 +119,38 @@ func initialize(ctx context.Context, r *request, c *config.Config) (interface{},
 
 	response := &lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
+			CodeActionProvider: true,
 			CompletionProvider: &lsp.CompletionOptions{
-				ResolveProvider: true,
+				ResolveProvider:   true,
+				TriggerCharacters: []string{".", `"`, "'", "/"},
+			},
+			DefinitionProvider:              true,
+			DocumentSymbolProvider:          true,
+			DocumentHighlightProvider:       true,
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			DocumentOnTypeFormattingProvider: &lsp.DocumentOnTypeFormattingOptions{
+				FirstTriggerCharacter: "}",
+				MoreTriggerCharacter:  []string{"]", ")", "\n"},
+			},
+			HoverProvider:      true,
+			InlayHintProvider:  true,
+			ReferencesProvider: true,
+			RenameProvider: &lsp.RenameOptions{
+				PrepareProvider: true,
 			},
-			DocumentSymbolProvider:    true,
-			DocumentHighlightProvider: true,
-			HoverProvider:             true,
-			ReferencesProvider:        true,
 			SignatureHelpProvider: &lsp.SignatureHelpOptions{
-				TriggerCharacters: []string{"("},
+				TriggerCharacters:   []string{"("},
+				RetriggerCharacters: []string{","},
+			},
+			TextDocumentSync: lsp.TDSKIncremental,
+			Workspace: &lsp.ServerWorkspaceCapabilities{
+				WorkspaceFolders: &lsp.WorkspaceFoldersServerCapabilities{
+					Supported:           true,
+					ChangeNotifications: true,
+				},
 			},
-			TextDocumentSync: lsp.TDSKFull,
+			WorkspaceSymbolProvider: true,
 		},
 	}
 
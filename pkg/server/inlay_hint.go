@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+func textDocumentInlayHint(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.InlayHintParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	return inlayHints(ctx, c, params)
+}
+
+func inlayHints(ctx context.Context, c *config.Config, params lsp.InlayHintParams) ([]lsp.InlayHint, error) {
+	text, err := c.Text(ctx, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := uri.ToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	ic, err := c.IdentifyConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rng := jpos.NewRange(jpos.FromLSPPosition(params.Range.Start), jpos.FromLSPPosition(params.Range.End))
+
+	hints, err := token.InlayHints(ctx, path, text.String(), rng, ic, c.NodeCache(), c.InlayHints())
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]lsp.InlayHint, 0, len(hints))
+	for _, hint := range hints {
+		ih := lsp.InlayHint{
+			Position: hint.Position.ToLSP(),
+			Label:    hint.Label,
+		}
+
+		switch hint.Kind {
+		case token.ParameterHint:
+			ih.Kind = lsp.IHKParameter
+			ih.PaddingRight = true
+		case token.ValueHint:
+			ih.PaddingLeft = true
+		}
+
+		out = append(out, ih)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_inlayHints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inlay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file.jsonnet")
+	source := "local container(name, image) = name;\nlocal env = std.extVar('env');\ncontainer('app', env)"
+
+	cases := []struct {
+		name     string
+		settings map[string]interface{}
+		expected []lsp.InlayHint
+	}{
+		{
+			name: "defaults",
+			expected: []lsp.InlayHint{
+				{Position: lsp.Position{Line: 2, Character: 10}, Label: "name:", Kind: lsp.IHKParameter, PaddingRight: true},
+				{Position: lsp.Position{Line: 2, Character: 17}, Label: "image:", Kind: lsp.IHKParameter, PaddingRight: true},
+			},
+		},
+		{
+			name: "values",
+			settings: map[string]interface{}{
+				"jsonnet.extVars":    map[string]interface{}{"env": "dev"},
+				"jsonnet.inlayHints": map[string]interface{}{"parameterNames": false, "values": true},
+			},
+			expected: []lsp.InlayHint{
+				{Position: lsp.Position{Line: 1, Character: 29}, Label: `= "dev"`, PaddingLeft: true},
+			},
+		},
+		{
+			name: "disabled",
+			settings: map[string]interface{}{
+				"jsonnet.inlayHints": map[string]interface{}{"parameterNames": false},
+			},
+			expected: []lsp.InlayHint{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := config.New()
+			ctx := testContext()
+
+			td := config.NewTextDocument(uri.FromPath(path), source)
+			require.NoError(t, c.StoreTextDocumentItem(ctx, td))
+			require.NoError(t, c.UpdateClientConfiguration(ctx, tc.settings))
+
+			params := lsp.InlayHintParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: uri.FromPath(path)},
+				Range: lsp.Range{
+					Start: lsp.Position{Line: 0, Character: 0},
+					End:   lsp.Position{Line: 3, Character: 0},
+				},
+			}
+
+			got, err := inlayHints(ctx, c, params)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
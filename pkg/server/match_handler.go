@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -15,13 +16,13 @@ import (
 	"github.com/bryanl/jsonnet-language-server/pkg/langserver"
 	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
-	"github.com/bryanl/jsonnet-language-server/pkg/util/text"
 	"github.com/google/go-jsonnet/ast"
 	"github.com/pkg/errors"
 )
 
 type jsonnetPathManager interface {
-	Files() ([]string, error)
+	ImportCandidates(dir, prefix string, allFiles bool) ([]langserver.ImportCandidate, error)
+	LibPaths() []string
 }
 
 type defaultJsonnetPathManager struct {
@@ -34,60 +35,101 @@ func newJsonnetPathManager(c *config.Config) *defaultJsonnetPathManager {
 	}
 }
 
-func (jpm *defaultJsonnetPathManager) Files() ([]string, error) {
+func (jpm *defaultJsonnetPathManager) ImportCandidates(dir, prefix string, allFiles bool) ([]langserver.ImportCandidate, error) {
 	lp := langserver.NewLibPaths(jpm.config.JsonnetLibPaths())
-	return lp.Files()
+	return lp.ImportCandidates(dir, prefix, allFiles)
+}
+
+func (jpm *defaultJsonnetPathManager) LibPaths() []string {
+	return jpm.config.JsonnetLibPaths()
 }
 
 type matchHandler struct {
 	jsonnetPathManager jsonnetPathManager
 	nodeCache          *token.NodeCache
+	// snippets is true if the client supports snippets in completion
+	// items.
+	snippets bool
 }
 
-func newMatchHandler(jpm jsonnetPathManager, nc *token.NodeCache) *matchHandler {
+func newMatchHandler(jpm jsonnetPathManager, nc *token.NodeCache, snippets bool) *matchHandler {
 	mh := &matchHandler{
 		jsonnetPathManager: jpm,
 		nodeCache:          nc,
+		snippets:           snippets,
 	}
 
 	return mh
 }
 
 func (mh *matchHandler) register(cm *langserver.CompletionMatcher) error {
-	m := map[string]langserver.CompletionAction{
-		`import\s`:    mh.handleImport,
-		`importstr\s`: mh.handleImport,
-		`\w+\.`:       mh.handleIndex,
+	// import paths are matched first so paths containing dots aren't
+	// completed as indexes.
+	matchers := []struct {
+		term string
+		fn   langserver.CompletionAction
+	}{
+		{term: `import(str)?\s+`, fn: mh.handleImport},
+		{term: `import(str)?\s+["'][^"'\n]*`, fn: mh.handleImport},
+		{term: `(\w+|\$|\))\.`, fn: mh.handleIndex},
 	}
 
-	for term, fn := range m {
-		if err := cm.Register(term, fn); err != nil {
-			return errors.Wrapf(err, "registering completion matcher %q", term)
+	for _, m := range matchers {
+		if err := cm.Register(m.term, m.fn); err != nil {
+			return errors.Wrapf(err, "registering completion matcher %q", m.term)
 		}
 	}
 
 	return nil
 }
 
+var (
+	reImport = regexp.MustCompile(`(import|importstr)\s+(["']?)([^"'\n]*)$`)
+)
+
+// handleImport completes import paths. Inside an import string, the path
+// typed so far is replaced one directory segment at a time. Without a
+// string, a quoted path is inserted.
 func (mh *matchHandler) handleImport(ctx context.Context, pos position.Position, path, source string) ([]lsp.CompletionItem, error) {
 	span := opentracing.SpanFromContext(ctx)
 	span.LogFields(
 		log.String("match.type", "import"),
 	)
 
-	editRange := position.NewRange(pos, pos)
-	var items []lsp.CompletionItem
+	match := reImport.FindStringSubmatch(source)
+	if match == nil {
+		return nil, errors.Errorf("%q does not end with an import", source)
+	}
+	keyword, quote, prefix := match[1], match[2], match[3]
 
-	files, err := mh.jsonnetPathManager.Files()
+	candidates, err := mh.jsonnetPathManager.ImportCandidates(filepath.Dir(path), prefix, keyword == "importstr")
 	if err != nil {
 		return nil, err
 	}
 
-	for _, file := range files {
-		text := fmt.Sprintf(`"%s"`, file)
-		ci := createCompletionItem(file, text, lsp.CIKFile, editRange, nil)
-		items = append(items, ci)
+	start := position.New(pos.Line(), pos.Column()-len(prefix))
+	editRange := position.NewRange(start, pos)
 
+	var items []lsp.CompletionItem
+	for _, candidate := range candidates {
+		kind := lsp.CIKFile
+		if candidate.IsDir {
+			kind = lsp.CIKFolder
+		}
+
+		text := candidate.Path
+		if quote == "" {
+			text = `"` + text
+			if !candidate.IsDir {
+				text += `"`
+			}
+		}
+
+		label := strings.TrimPrefix(candidate.Path, prefix[:strings.LastIndex(prefix, "/")+1])
+
+		ci := createCompletionItem(label, text, kind, editRange, nil)
+		ci.FilterText = candidate.Path
+		items = append(items, ci)
 	}
 
 	return items, nil
@@ -99,71 +141,122 @@ func (mh *matchHandler) handleIndex(ctx context.Context, pos position.Position,
 		log.String("match.type", "index"),
 	)
 
-	var items []lsp.CompletionItem
+	editRange := position.NewRange(pos, pos)
 
-	scope, err := token.LocationScope(filePath, source, pos, mh.nodeCache)
-	if err != nil {
-		return nil, err
+	path, err := resolveIndex(source)
+	if err == nil && len(path) == 1 && path[0] == "std" {
+		return stdCompletionItems(editRange)
 	}
 
-	truncated, err := text.Truncate(source, pos)
+	fields, err := token.IndexFields(ctx, filePath, source, mh.jsonnetPathManager.LibPaths(), mh.nodeCache)
 	if err != nil {
 		return nil, err
 	}
 
-	path, err := resolveIndex(truncated)
-	if err != nil {
-		return nil, err
+	var items []lsp.CompletionItem
+	for i, field := range fields {
+		ci := fieldCompletionItem(field, i, editRange, mh.snippets)
+
+		data := completionData{
+			Kind:     completionDataField,
+			Path:     filePath,
+			Position: pos.ToLSP(),
+			Name:     field.Name,
+			Hidden:   field.Hidden,
+		}
+
+		if field.Loc.FileName == "" {
+			// fields found through the inferred type can't be found again
+			// when they are resolved.
+			ci.Detail = fieldDetail(field)
+		} else {
+			declPos := position.FromJsonnetLocation(field.Loc.Begin)
+			data.File = field.Loc.FileName
+			data.Declaration = declPos.ToLSP()
+		}
+
+		ci.Data = data
+		items = append(items, ci)
 	}
 
-	se, err := scope.GetInPath(path)
-	if err != nil {
-		return nil, err
+	return items, nil
+}
+
+// fieldCompletionItem creates a completion item for an object field.
+// Items are sorted in inheritance order with hidden fields after visible
+// fields. Methods insert a snippet with their parameters, or only the
+// opening parenthesis if the client doesn't support snippets. The detail
+// and documentation are filled in when the item is resolved.
+func fieldCompletionItem(field token.ObjectField, i int, r position.Range, snippets bool) lsp.CompletionItem {
+	kind := lsp.CIKField
+	text := field.Name
+	if field.Method {
+		kind = lsp.CIKMethod
+		text = field.Name + "("
 	}
 
-	editRange := position.NewRange(pos, pos)
+	if field.Method && snippets {
+		var params []string
+		for j, param := range field.Parameters {
+			params = append(params, fmt.Sprintf("${%d:%s}", j+1, param))
+		}
+		text = fmt.Sprintf("%s(%s)", field.Name, strings.Join(params, ", "))
+	}
 
-	switch n := se.Node.(type) {
-	case *ast.DesugaredObject:
-		for _, field := range n.Fields {
-			name := astext.TokenValue(field.Name)
+	visibility := 0
+	if field.Hidden {
+		visibility = 1
+	}
 
-			fieldSe := &token.ScopeEntry{
-				Detail: astext.TokenName(field.Body),
-			}
+	ci := createCompletionItem(field.Name, text, kind, r, nil)
+	ci.SortText = fmt.Sprintf("%d_%04d", visibility, i)
+	if field.Method && snippets {
+		ci.InsertTextFormat = lsp.ITFSnippet
+	}
 
-			ci := createCompletionItem(name, name, lsp.CIKVariable, editRange, fieldSe)
-			items = append(items, ci)
+	return ci
+}
+
+// fieldDetail describes the value of an object field. Methods are
+// described by their parameters.
+func fieldDetail(field token.ObjectField) string {
+	detail := astext.TokenName(field.Node)
+	switch field.Node.(type) {
+	case *ast.Apply, *ast.Binary, *ast.Conditional, *ast.Index, *ast.Local, *ast.Var, nil:
+		// values which aren't literals are described by their type.
+		if field.Type.IsKnown() {
+			detail = fmt.Sprintf("(%s)", field.Type)
 		}
-	case *ast.Object:
-		// TODO check to see if this case is used. Objects should be desguared.
-		for _, field := range n.Fields {
-			var name string
-			switch field.Kind {
-			case ast.ObjectFieldID:
-				if field.Id == nil {
-					return nil, errors.New("field id shouldn't be nil")
-				}
-				name = string(*field.Id)
-			case ast.ObjectFieldStr:
-				if field.Expr1 == nil {
-					return nil, errors.New("field id should be a string")
-				}
-				name = astext.TokenValue(field.Expr1)
-			}
-			if name != "" {
-				fieldSe := &token.ScopeEntry{
-					Detail: astext.TokenName(field.Expr2),
-				}
+	}
 
-				ci := createCompletionItem(name, name, lsp.CIKVariable, editRange, fieldSe)
-				items = append(items, ci)
-			}
+	if field.Method {
+		detail = fmt.Sprintf("%s(%s)", field.Name, strings.Join(field.Parameters, ", "))
+	}
+
+	if field.Hidden {
+		detail = "(hidden) " + detail
+	}
+
+	return detail
+}
+
+// stdCompletionItems creates completion items for the standard library
+// functions. The signature and documentation are filled in when the
+// item is resolved.
+func stdCompletionItems(editRange position.Range) ([]lsp.CompletionItem, error) {
+	stdlib, err := token.LoadStdlib()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []lsp.CompletionItem
+	for _, f := range stdlib.Functions() {
+		ci := createCompletionItem(f.Name, f.Name, lsp.CIKFunction, editRange, nil)
+		ci.Data = completionData{
+			Kind: completionDataStd,
+			Name: f.Name,
 		}
-	default:
-		span.LogFields(
-			log.String("text.unhandled", fmt.Sprintf("%T", n)),
-		)
+		items = append(items, ci)
 	}
 
 	return items, nil
@@ -171,9 +264,11 @@ func (mh *matchHandler) handleIndex(ctx context.Context, pos position.Position,
 
 func createCompletionItem(label, text string, kind int, r position.Range, se *token.ScopeEntry) lsp.CompletionItem {
 	var detail, documentation string
+	var deprecated bool
 	if se != nil {
 		detail = se.Detail
 		documentation = se.Documentation
+		deprecated = se.Doc.Deprecated
 	}
 
 	return lsp.CompletionItem{
@@ -181,6 +276,7 @@ func createCompletionItem(label, text string, kind int, r position.Range, se *to
 		Kind:          kind,
 		Detail:        detail,
 		Documentation: documentation,
+		Deprecated:    deprecated,
 		TextEdit: lsp.TextEdit{
 			Range:   r.ToLSP(),
 			NewText: text,
@@ -1,44 +1,93 @@
 package server
 
 import (
+	"context"
 	"testing"
 
 	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
 	"github.com/bryanl/jsonnet-language-server/pkg/langserver"
 	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
 	"github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func Test_matchHandler_handleImport(t *testing.T) {
-	nc := token.NewNodeCache()
-	cm := langserver.NewCompletionMatcher()
+	cases := []struct {
+		name       string
+		source     string
+		at         position.Position
+		candidates []langserver.ImportCandidate
+		expected   func(position.Position) []lsp.CompletionItem
+	}{
+		{
+			name:   "without a string",
+			source: "local foo = {\n    a: \"b\"\n};\n\nlocal y = import ",
+			at:     position.New(5, 18),
+			candidates: []langserver.ImportCandidate{
+				{Path: "1.jsonnet"},
+				{Path: "k8s/", IsDir: true},
+			},
+			expected: func(pos position.Position) []lsp.CompletionItem {
+				r := position.NewRange(pos, pos)
+				return []lsp.CompletionItem{
+					importItem("1.jsonnet", `"1.jsonnet"`, "1.jsonnet", lsp.CIKFile, r),
+					importItem("k8s/", `"k8s/`, "k8s/", lsp.CIKFolder, r),
+				}
+			},
+		},
+		{
+			name:   "in a string",
+			source: `local k = import "k8s/ap`,
+			at:     position.New(1, 25),
+			candidates: []langserver.ImportCandidate{
+				{Path: "k8s/apps/", IsDir: true},
+			},
+			expected: func(pos position.Position) []lsp.CompletionItem {
+				r := position.NewRange(position.New(1, 19), pos)
+				return []lsp.CompletionItem{
+					importItem("apps/", "k8s/apps/", "k8s/apps/", lsp.CIKFolder, r),
+				}
+			},
+		},
+	}
 
-	source := "local foo = {\n    a: \"b\"\n};\n\nlocal y = import "
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			nc := token.NewNodeCache()
+			cm := langserver.NewCompletionMatcher()
 
-	jpm := &fakeJsonnetPathManager{files: []string{"1.jsonnet", "2.libsonnet"}}
-	mh := newMatchHandler(jpm, nc)
-	mh.register(cm)
+			jpm := &fakeJsonnetPathManager{candidates: tc.candidates}
+			mh := newMatchHandler(jpm, nc, true)
+			require.NoError(t, mh.register(cm))
 
-	pos := position.New(5, 18)
-	got, err := cm.Match(pos, "file.jsonnet", source)
-	require.NoError(t, err)
+			got, err := cm.Match(testContext(), tc.at, "/app/file.jsonnet", tc.source)
+			require.NoError(t, err)
 
-	editRange := position.NewRange(pos, pos)
-	expected := []lsp.CompletionItem{
-		createCompletionItem("1.jsonnet", `"1.jsonnet"`, lsp.CIKFile, editRange, nil),
-		createCompletionItem("2.libsonnet", `"2.libsonnet"`, lsp.CIKFile, editRange, nil),
+			assert.Equal(t, tc.expected(tc.at), got)
+			assert.Equal(t, "/app", jpm.dir)
+		})
 	}
-	assert.Equal(t, expected, got)
+}
+
+func testContext() context.Context {
+	return opentracing.ContextWithSpan(context.Background(), opentracing.StartSpan("test"))
+}
+
+func importItem(label, text, filterText string, kind int, r position.Range) lsp.CompletionItem {
+	ci := createCompletionItem(label, text, kind, r, nil)
+	ci.FilterText = filterText
+	return ci
 }
 
 func Test_matchHandler_handleIndex(t *testing.T) {
 	cases := []struct {
-		name     string
-		text     string
-		at       position.Position
-		expected func(position.Range) []lsp.CompletionItem
+		name       string
+		text       string
+		at         position.Position
+		noSnippets bool
+		expected   func(position.Range) []lsp.CompletionItem
 	}{
 		{
 			name: "handle index",
@@ -46,8 +95,7 @@ func Test_matchHandler_handleIndex(t *testing.T) {
 			at:   position.New(5, 13),
 			expected: func(r position.Range) []lsp.CompletionItem {
 				return []lsp.CompletionItem{
-					createCompletionItem(`"a"`, `"a"`, lsp.CIKVariable, r,
-						&token.ScopeEntry{Detail: "(local)"}),
+					fieldItem("a", "a", lsp.CIKField, r, "0_0000", position.New(5, 13), position.New(2, 5), false),
 				}
 			},
 		},
@@ -57,8 +105,63 @@ func Test_matchHandler_handleIndex(t *testing.T) {
 			at:   position.New(1, 31),
 			expected: func(r position.Range) []lsp.CompletionItem {
 				return []lsp.CompletionItem{
-					createCompletionItem(`"a"`, `"a"`, lsp.CIKVariable, r,
-						&token.ScopeEntry{Detail: `(string) "a"`}),
+					fieldItem("a", "a", lsp.CIKField, r, "0_0000", position.New(1, 31), position.New(1, 16), false),
+				}
+			},
+		},
+		{
+			name: "self with hidden field and method",
+			text: `{a:: 1, f(x, y):: x, b: self.`,
+			at:   position.New(1, 30),
+			expected: func(r position.Range) []lsp.CompletionItem {
+				f := fieldItem("f", "f(${1:x}, ${2:y})", lsp.CIKMethod, r, "1_0001", position.New(1, 30), position.New(1, 9), true)
+				f.InsertTextFormat = lsp.ITFSnippet
+
+				return []lsp.CompletionItem{
+					fieldItem("a", "a", lsp.CIKField, r, "1_0000", position.New(1, 30), position.New(1, 2), true),
+					f,
+				}
+			},
+		},
+		{
+			name:       "method without snippet support",
+			text:       `{f(x, y):: x, b: self.`,
+			at:         position.New(1, 23),
+			noSnippets: true,
+			expected: func(r position.Range) []lsp.CompletionItem {
+				return []lsp.CompletionItem{
+					fieldItem("f", "f(", lsp.CIKMethod, r, "1_0000", position.New(1, 23), position.New(1, 2), true),
+				}
+			},
+		},
+		{
+			name: "inferred type",
+			text: `local xs=[{a: 1}]; local x=xs[0]; x.`,
+			at:   position.New(1, 36),
+			expected: func(r position.Range) []lsp.CompletionItem {
+				// fields found through the inferred type are described
+				// when they are completed.
+				a := createCompletionItem("a", "a", lsp.CIKField, r, nil)
+				a.SortText = "0_0000"
+				a.Detail = "(number)"
+				a.Data = completionData{
+					Kind:     completionDataField,
+					Path:     "file.jsonnet",
+					Position: lsp.Position{Line: 0, Character: 35},
+					Name:     "a",
+				}
+
+				return []lsp.CompletionItem{a}
+			},
+		},
+		{
+			name: "dollar",
+			text: `{a: 1, b: {c: $.`,
+			at:   position.New(1, 17),
+			expected: func(r position.Range) []lsp.CompletionItem {
+				return []lsp.CompletionItem{
+					fieldItem("a", "a", lsp.CIKField, r, "0_0000", position.New(1, 17), position.New(1, 2), false),
+					fieldItem("b", "b", lsp.CIKField, r, "0_0001", position.New(1, 17), position.New(1, 8), false),
 				}
 			},
 		},
@@ -69,11 +172,11 @@ func Test_matchHandler_handleIndex(t *testing.T) {
 			nc := token.NewNodeCache()
 			cm := langserver.NewCompletionMatcher()
 
-			jpm := &fakeJsonnetPathManager{files: []string{"1.jsonnet", "2.libsonnet"}}
-			mh := newMatchHandler(jpm, nc)
-			mh.register(cm)
+			jpm := &fakeJsonnetPathManager{}
+			mh := newMatchHandler(jpm, nc, !tc.noSnippets)
+			require.NoError(t, mh.register(cm))
 
-			got, err := cm.Match(tc.at, "file.jsonnet", tc.text)
+			got, err := cm.Match(testContext(), tc.at, "file.jsonnet", tc.text)
 			require.NoError(t, err)
 
 			editRange := position.NewRange(tc.at, tc.at)
@@ -116,13 +219,36 @@ func OffTest_resolveIndex(t *testing.T) {
 	}
 }
 
+func fieldItem(label, text string, kind int, r position.Range, sortText string, at, decl position.Position, hidden bool) lsp.CompletionItem {
+	ci := createCompletionItem(label, text, kind, r, nil)
+	ci.SortText = sortText
+	ci.Data = completionData{
+		Kind:        completionDataField,
+		Path:        "file.jsonnet",
+		Position:    at.ToLSP(),
+		Name:        label,
+		File:        "file.jsonnet",
+		Declaration: decl.ToLSP(),
+		Hidden:      hidden,
+	}
+	return ci
+}
+
 type fakeJsonnetPathManager struct {
-	files    []string
-	filesErr error
+	candidates    []langserver.ImportCandidate
+	candidatesErr error
+	libPaths      []string
+
+	dir string
 }
 
 var _ jsonnetPathManager = (*fakeJsonnetPathManager)(nil)
 
-func (jpm *fakeJsonnetPathManager) Files() ([]string, error) {
-	return jpm.files, jpm.filesErr
+func (jpm *fakeJsonnetPathManager) ImportCandidates(dir, prefix string, allFiles bool) ([]langserver.ImportCandidate, error) {
+	jpm.dir = dir
+	return jpm.candidates, jpm.candidatesErr
+}
+
+func (jpm *fakeJsonnetPathManager) LibPaths() []string {
+	return jpm.libPaths
 }
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/analysis/lexical/token"
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	jpos "github.com/bryanl/jsonnet-language-server/pkg/util/position"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+func textDocumentPrepareRename(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.TextDocumentPositionParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	doc, err := c.Text(ctx, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := uri.ToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, err := c.ForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := jpos.FromLSPPosition(params.Position)
+
+	rng, placeholder, err := token.PrepareRename(path, doc.String(), pos, pc.JsonnetLibPaths())
+	if err != nil {
+		return nil, err
+	}
+
+	return &lsp.PrepareRenameResult{
+		Range:       rng.ToLSP(),
+		Placeholder: placeholder,
+	}, nil
+}
+
+func textDocumentRename(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.RenameParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	doc, err := c.Text(ctx, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := uri.ToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, err := c.ForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := jpos.FromLSPPosition(params.Position)
+
+	current := token.Document{Path: path, Source: doc.String()}
+	documents := workspaceDocuments(ctx, pc, filepath.Dir(path))
+
+	locations, err := token.Rename(ctx, current, pos, params.NewName, pc.JsonnetLibPaths(), documents)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := &lsp.WorkspaceEdit{
+		Changes: make(map[string][]lsp.TextEdit),
+	}
+
+	for _, l := range locations {
+		lspLocation := l.ToLSP()
+		edit.Changes[lspLocation.URI] = append(edit.Changes[lspLocation.URI], lsp.TextEdit{
+			Range:   lspLocation.Range,
+			NewText: params.NewName,
+		})
+	}
+
+	return edit, nil
+}
+
+// workspaceDocuments returns the Jsonnet documents in dir and the lib
+// paths. Open documents are used instead of their contents on disk.
+func workspaceDocuments(ctx context.Context, c *config.Config, dir string) []token.Document {
+	span, _ := opentracing.StartSpanFromContext(ctx, "workspaceDocuments")
+	defer span.Finish()
+
+	sources := make(map[string]string)
+
+	dirs := append([]string{dir}, c.JsonnetLibPaths()...)
+	for _, d := range dirs {
+		err := filepath.Walk(d, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if fi.IsDir() {
+				// only the lib paths are searched recursively.
+				if path != d && d == dir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			switch filepath.Ext(path) {
+			case ".jsonnet", ".libsonnet":
+			default:
+				return nil
+			}
+
+			if _, ok := sources[path]; ok {
+				return nil
+			}
+
+			/* #nosec */
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			sources[path] = string(data)
+			return nil
+		})
+
+		if err != nil {
+			span.LogFields(log.Error(err))
+		}
+	}
+
+	for _, td := range c.TextDocuments() {
+		path, err := td.Filename()
+		if err != nil {
+			span.LogFields(log.Error(err))
+			continue
+		}
+
+		sources[path] = td.String()
+	}
+
+	var documents []token.Document
+	for path, source := range sources {
+		documents = append(documents, token.Document{Path: path, Source: source})
+	}
+
+	return documents
+}
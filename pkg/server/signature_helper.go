@@ -31,17 +31,26 @@ func textDocumentSignatureHelper(ctx context.Context, r *request, c *config.Conf
 		return nil, err
 	}
 
+	if sr == nil {
+		return nil, nil
+	}
+
 	si := lsp.SignatureInformation{
-		Label:      sr.Label,
-		Parameters: []lsp.ParameterInformation{},
+		Label:         sr.Label,
+		Documentation: sr.Documentation,
+		Parameters:    []lsp.ParameterInformation{},
 	}
 
 	for _, param := range sr.Parameters {
-		si.Parameters = append(si.Parameters, lsp.ParameterInformation{Label: param})
+		si.Parameters = append(si.Parameters, lsp.ParameterInformation{
+			Label:         param.Label(),
+			Documentation: param.Documentation,
+		})
 	}
 
 	response := &lsp.SignatureHelp{
-		Signatures: []lsp.SignatureInformation{si},
+		Signatures:      []lsp.SignatureInformation{si},
+		ActiveParameter: sr.ActiveParameter,
 	}
 
 	return response, nil
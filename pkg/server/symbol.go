@@ -28,7 +28,11 @@ func textDocumentSymbol(ctx context.Context, r *request, c *config.Config) (inte
 		return nil, err
 	}
 
-	var response []lsp.DocumentSymbol
+	return documentSymbols(symbols), nil
+}
+
+func documentSymbols(symbols []token.Symbol) []lsp.DocumentSymbol {
+	response := make([]lsp.DocumentSymbol, 0, len(symbols))
 
 	for _, symbol := range symbols {
 		enclosingRange := symbol.Range()
@@ -41,11 +45,11 @@ func textDocumentSymbol(ctx context.Context, r *request, c *config.Config) (inte
 			Deprecated:     symbol.IsDeprecated(),
 			Range:          enclosingRange.ToLSP(),
 			SelectionRange: selectionRange.ToLSP(),
-			Children:       make([]lsp.DocumentSymbol, 0),
+			Children:       documentSymbols(symbol.Children()),
 		}
 
 		response = append(response, ds)
 	}
 
-	return response, nil
+	return response
 }
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	"github.com/bryanl/jsonnet-language-server/pkg/util/uri"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+func workspaceDidChangeWorkspaceFolders(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+	ctx = opentracing.ContextWithSpan(ctx, span)
+
+	var params lsp.DidChangeWorkspaceFoldersParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	for _, folder := range params.Event.Removed {
+		path, err := uri.ToPath(folder.URI)
+		if err != nil {
+			span.LogFields(log.Error(err))
+			continue
+		}
+
+		c.RemoveWorkspaceFolder(ctx, path)
+	}
+
+	if err := addWorkspaceFolders(ctx, c, params.Event.Added); err != nil {
+		return nil, err
+	}
+
+	// the settings of the added folders are requested from the client.
+	if len(params.Event.Added) > 0 && c.ClientCapabilities().Workspace.Configuration {
+		go func() {
+			reportConfigurationError(ctx, r, pullConfiguration(ctx, r, c))
+		}()
+	}
+
+	return nil, nil
+}
+
+// addWorkspaceFolders adds workspace folders to the configuration.
+func addWorkspaceFolders(ctx context.Context, c *config.Config, folders []lsp.WorkspaceFolder) error {
+	for _, folder := range folders {
+		path, err := uri.ToPath(folder.URI)
+		if err != nil {
+			return err
+		}
+
+		c.AddWorkspaceFolder(ctx, path, folder.Name)
+	}
+
+	return nil
+}
+
+// watchWorkspaceFolders updates the symbol index when workspace folders
+// are added or removed, and refreshes the open documents so they are
+// analyzed with their folder's configuration.
+func watchWorkspaceFolders(c *config.Config) {
+	c.Watch(config.WorkspaceFolderUpdates, func(ctx context.Context, v interface{}) error {
+		path, ok := v.(string)
+		if !ok {
+			return errors.Errorf("workspace folder update is %T, not a path", v)
+		}
+
+		if c.InWorkspace(path) {
+			go indexDirs(context.Background(), c, path)
+		} else {
+			prefix := path + string(filepath.Separator)
+			for _, file := range c.SymbolIndex().Files() {
+				if strings.HasPrefix(file, prefix) && !c.InWorkspace(file) {
+					c.SymbolIndex().Remove(file)
+				}
+			}
+		}
+
+		return refreshTextDocuments(c)(ctx, v)
+	})
+}
+
+// requestConfig returns the configuration for the document a request is
+// about, so requests in a workspace folder use the folder's
+// configuration. Requests which aren't about a document use c.
+func requestConfig(ctx context.Context, r *request, c *config.Config) *config.Config {
+	if r.req.Params == nil {
+		return c
+	}
+
+	var params struct {
+		TextDocument *lsp.TextDocumentIdentifier `json:"textDocument"`
+	}
+	if err := r.Decode(&params); err != nil || params.TextDocument == nil {
+		return c
+	}
+
+	path, err := uri.ToPath(params.TextDocument.URI)
+	if err != nil {
+		return c
+	}
+
+	pc, err := c.ForPath(path)
+	if err != nil {
+		// handlers report the error when they load the configuration.
+		opentracing.SpanFromContext(ctx).LogFields(log.Error(err))
+		return c
+	}
+
+	return pc
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+
+	"github.com/bryanl/jsonnet-language-server/pkg/config"
+	"github.com/bryanl/jsonnet-language-server/pkg/lsp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+func workspaceSymbol(ctx context.Context, r *request, c *config.Config) (interface{}, error) {
+	span := opentracing.SpanFromContext(ctx)
+
+	var params lsp.WorkspaceSymbolParams
+	if err := r.Decode(&params); err != nil {
+		return nil, err
+	}
+
+	span.LogFields(
+		log.String("query", params.Query),
+	)
+
+	response := []lsp.SymbolInformation{}
+	for _, symbol := range c.SymbolIndex().Search(params.Query, params.Limit) {
+		response = append(response, lsp.SymbolInformation{
+			Name:          symbol.Name,
+			Kind:          symbol.Kind,
+			Location:      symbol.Location.ToLSP(),
+			ContainerName: symbol.ContainerName,
+		})
+	}
+
+	return response, nil
+}
+
+// watchSymbolIndex keeps the symbol index current as lib paths and
+// text documents change.
+func watchSymbolIndex(c *config.Config) {
+	c.Watch(config.JsonnetLibPaths, func(ctx context.Context, v interface{}) error {
+		paths, ok := v.([]string)
+		if !ok {
+			return errors.New("lib paths are not []string")
+		}
+
+		for _, path := range paths {
+			if err := c.SymbolIndex().IndexDir(ctx, path); err != nil {
+				return errors.Wrapf(err, "indexing lib path %q", path)
+			}
+		}
+
+		return nil
+	})
+
+	c.Watch(config.TextDocumentUpdates, func(ctx context.Context, v interface{}) error {
+		td, ok := v.(config.TextDocument)
+		if !ok {
+			return errors.Errorf("symbol index can't handle %T", v)
+		}
+
+		path, err := td.Filename()
+		if err != nil {
+			return err
+		}
+
+		// documents being edited often don't parse. The symbols from the
+		// last version that parsed are kept.
+		if err := c.SymbolIndex().IndexSource(path, td.String()); err != nil {
+			span := opentracing.SpanFromContext(ctx)
+			if span != nil {
+				span.LogFields(log.Error(err))
+			}
+		}
+
+		return nil
+	})
+}
+
+// indexWorkspace indexes the workspace root and workspace folders.
+func indexWorkspace(ctx context.Context, c *config.Config) {
+	var dirs []string
+	if root := c.RootPath(); root != "" {
+		dirs = append(dirs, root)
+	}
+
+	for _, folder := range c.WorkspaceFolders() {
+		dirs = append(dirs, folder.Path)
+	}
+
+	indexDirs(ctx, c, dirs...)
+}
+
+// indexDirs indexes directories.
+func indexDirs(ctx context.Context, c *config.Config, dirs ...string) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "indexDirs")
+	defer span.Finish()
+
+	for _, dir := range dirs {
+		if err := c.SymbolIndex().IndexDir(ctx, dir); err != nil {
+			span.LogFields(
+				log.Error(err),
+			)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package text
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FuzzyMatch reports whether the characters of pattern appear in s in
+// order. Case is ignored. The returned score is higher for better
+// matches: exact matches score highest, followed by matches of
+// consecutive characters and characters at the start of words.
+func FuzzyMatch(pattern, s string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+
+	pr := []rune(pattern)
+	pl := []rune(strings.ToLower(pattern))
+	sr := []rune(s)
+	sl := []rune(strings.ToLower(s))
+
+	if len(sl) != len(sr) || len(pl) != len(pr) {
+		// lower casing changed the length of the strings, so only
+		// exact matches are supported.
+		if strings.EqualFold(pattern, s) {
+			return 100, true
+		}
+		return 0, false
+	}
+
+	score := 0
+	pi := 0
+	prev := -2
+
+	for i := 0; i < len(sl) && pi < len(pl); i++ {
+		if sl[i] != pl[pi] {
+			continue
+		}
+
+		score++
+		if i == prev+1 {
+			score += 5
+		}
+		if isWordStart(sr, i) {
+			score += 10
+		}
+		if sr[i] == pr[pi] {
+			score++
+		}
+
+		prev = i
+		pi++
+	}
+
+	if pi < len(pl) {
+		return 0, false
+	}
+
+	if strings.EqualFold(pattern, s) {
+		score += 100
+	}
+
+	// shorter strings are better matches.
+	score -= len(sr) - len(pr)
+
+	return score, true
+}
+
+// isWordStart returns true if the rune at i starts a word in s.
+func isWordStart(s []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	prev, cur := s[i-1], s[i]
+	switch {
+	case prev == '_' || prev == '-' || prev == '.' || prev == '/':
+		return true
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		s       string
+		isMatch bool
+	}{
+		{name: "empty pattern", pattern: "", s: "deployment", isMatch: true},
+		{name: "exact", pattern: "deployment", s: "deployment", isMatch: true},
+		{name: "prefix", pattern: "dep", s: "deployment", isMatch: true},
+		{name: "subsequence", pattern: "dspec", s: "deploymentSpec", isMatch: true},
+		{name: "case insensitive", pattern: "DEPLOY", s: "deployment", isMatch: true},
+		{name: "out of order", pattern: "ped", s: "deployment", isMatch: false},
+		{name: "longer than string", pattern: "deployments", s: "deployment", isMatch: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := FuzzyMatch(tc.pattern, tc.s)
+			assert.Equal(t, tc.isMatch, ok)
+		})
+	}
+}
+
+func TestFuzzyMatch_score(t *testing.T) {
+	better := []struct {
+		pattern string
+		a       string
+		b       string
+	}{
+		{pattern: "spec", a: "spec", b: "deploymentSpec"},
+		{pattern: "ds", a: "deploymentSpec", b: "address"},
+		{pattern: "dep", a: "deployment", b: "dxexp"},
+		{pattern: "port", a: "port", b: "ports"},
+	}
+
+	for _, tc := range better {
+		t.Run(tc.pattern, func(t *testing.T) {
+			a, ok := FuzzyMatch(tc.pattern, tc.a)
+			assert.True(t, ok)
+			b, ok := FuzzyMatch(tc.pattern, tc.b)
+			assert.True(t, ok)
+
+			assert.True(t, a > b, "expected %q (%d) to score higher than %q (%d)", tc.a, a, tc.b, b)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package text
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxPieces is the number of pieces a table can have before it is
+	// compacted into a single piece.
+	maxPieces = 256
+)
+
+type piece struct {
+	text     string
+	newlines int
+}
+
+func newPiece(s string) piece {
+	return piece{text: s, newlines: strings.Count(s, "\n")}
+}
+
+// PieceTable is an immutable text buffer made of pieces of text. Edits
+// return a new table which shares the unchanged pieces with the
+// original, so a table can be read while newer versions are created.
+type PieceTable struct {
+	pieces   []piece
+	length   int
+	newlines int
+
+	once sync.Once
+	text string
+}
+
+// NewPieceTable creates an instance of PieceTable containing s.
+func NewPieceTable(s string) *PieceTable {
+	var pieces []piece
+	if s != "" {
+		pieces = append(pieces, newPiece(s))
+	}
+
+	return newPieceTable(pieces)
+}
+
+func newPieceTable(pieces []piece) *PieceTable {
+	pt := &PieceTable{pieces: pieces}
+	for _, p := range pieces {
+		pt.length += len(p.text)
+		pt.newlines += p.newlines
+	}
+
+	return pt
+}
+
+// Len returns the length of the text in bytes.
+func (pt *PieceTable) Len() int {
+	return pt.length
+}
+
+// LineCount returns the number of lines in the text.
+func (pt *PieceTable) LineCount() int {
+	return pt.newlines + 1
+}
+
+// String returns the text.
+func (pt *PieceTable) String() string {
+	pt.once.Do(func() {
+		switch len(pt.pieces) {
+		case 0:
+		case 1:
+			pt.text = pt.pieces[0].text
+		default:
+			var sb strings.Builder
+			sb.Grow(pt.length)
+			for _, p := range pt.pieces {
+				sb.WriteString(p.text)
+			}
+			pt.text = sb.String()
+		}
+	})
+
+	return pt.text
+}
+
+// Offset converts a zero based line and UTF-16 character to a byte
+// offset. Like LSP positions, characters past the end of a line
+// resolve to the end of the line, and lines past the end of the text
+// resolve to the end of the text.
+func (pt *PieceTable) Offset(line, character int) int {
+	if line < 0 {
+		return 0
+	}
+
+	if line > pt.newlines {
+		return pt.length
+	}
+
+	start := pt.lineStart(line)
+	offset := start
+
+	units := 0
+	s := pt.slice(start, pt.lineEnd(start))
+	for _, r := range s {
+		if units >= character {
+			break
+		}
+
+		units += utf16Units(r)
+		offset += utf8.RuneLen(r)
+	}
+
+	return offset
+}
+
+// Replace replaces the bytes between start and end with s.
+func (pt *PieceTable) Replace(start, end int, s string) (*PieceTable, error) {
+	if start < 0 || end > pt.length || start > end {
+		return nil, errors.Errorf("invalid range %d-%d for text with length %d", start, end, pt.length)
+	}
+
+	pieces := make([]piece, 0, len(pt.pieces)+2)
+
+	inserted := s == ""
+	insert := func() {
+		if !inserted {
+			pieces = append(pieces, newPiece(s))
+			inserted = true
+		}
+	}
+
+	pos := 0
+	for _, p := range pt.pieces {
+		pStart, pEnd := pos, pos+len(p.text)
+		pos = pEnd
+
+		if pEnd <= start {
+			pieces = append(pieces, p)
+			continue
+		}
+
+		if pStart >= end {
+			insert()
+			pieces = append(pieces, p)
+			continue
+		}
+
+		if pStart < start {
+			pieces = append(pieces, newPiece(p.text[:start-pStart]))
+		}
+
+		insert()
+
+		if pEnd > end {
+			pieces = append(pieces, newPiece(p.text[end-pStart:]))
+		}
+	}
+
+	insert()
+
+	if len(pieces) > maxPieces {
+		next := newPieceTable(pieces)
+		return NewPieceTable(next.String()), nil
+	}
+
+	return newPieceTable(pieces), nil
+}
+
+// lineStart returns the offset of the first byte of line.
+func (pt *PieceTable) lineStart(line int) int {
+	if line == 0 {
+		return 0
+	}
+
+	offset := 0
+	for _, p := range pt.pieces {
+		if line > p.newlines {
+			line -= p.newlines
+			offset += len(p.text)
+			continue
+		}
+
+		s := p.text
+		for ; line > 0; line-- {
+			i := strings.IndexByte(s, '\n')
+			offset += i + 1
+			s = s[i+1:]
+		}
+
+		return offset
+	}
+
+	return offset
+}
+
+// lineEnd returns the offset of the newline ending the line starting
+// at start, or the length of the text if the line is the last line.
+func (pt *PieceTable) lineEnd(start int) int {
+	pos := 0
+	for _, p := range pt.pieces {
+		pStart, pEnd := pos, pos+len(p.text)
+		pos = pEnd
+
+		if pEnd <= start {
+			continue
+		}
+
+		from := 0
+		if start > pStart {
+			from = start - pStart
+		}
+
+		if i := strings.IndexByte(p.text[from:], '\n'); i != -1 {
+			return pStart + from + i
+		}
+	}
+
+	return pt.length
+}
+
+// slice returns the text between start and end.
+func (pt *PieceTable) slice(start, end int) string {
+	var sb strings.Builder
+
+	pos := 0
+	for _, p := range pt.pieces {
+		pStart, pEnd := pos, pos+len(p.text)
+		pos = pEnd
+
+		if pEnd <= start {
+			continue
+		}
+
+		if pStart >= end {
+			break
+		}
+
+		from, to := 0, len(p.text)
+		if start > pStart {
+			from = start - pStart
+		}
+		if end < pEnd {
+			to = end - pStart
+		}
+
+		sb.WriteString(p.text[from:to])
+	}
+
+	return sb.String()
+}
+
+// utf16Units returns the number of UTF-16 code units needed to encode r.
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
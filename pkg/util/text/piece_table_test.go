@@ -0,0 +1,138 @@
+package text
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPieceTable_Replace(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		edits    [][3]interface{}
+		expected string
+		isErr    bool
+	}{
+		{
+			name:     "insert into empty text",
+			edits:    [][3]interface{}{{0, 0, "abc"}},
+			expected: "abc",
+		},
+		{
+			name:     "insert at start",
+			source:   "world",
+			edits:    [][3]interface{}{{0, 0, "hello "}},
+			expected: "hello world",
+		},
+		{
+			name:     "insert at end",
+			source:   "hello",
+			edits:    [][3]interface{}{{5, 5, " world"}},
+			expected: "hello world",
+		},
+		{
+			name:     "delete",
+			source:   "hello big world",
+			edits:    [][3]interface{}{{5, 9, ""}},
+			expected: "hello world",
+		},
+		{
+			name:   "edits across pieces",
+			source: "local a = 1;\na",
+			edits: [][3]interface{}{
+				{6, 7, "foo"},
+				{15, 16, "foo"},
+				{4, 12, ""},
+			},
+			expected: "loca1;\nfoo",
+		},
+		{
+			name:   "invalid range",
+			source: "abc",
+			edits:  [][3]interface{}{{2, 4, ""}},
+			isErr:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pt := NewPieceTable(tc.source)
+
+			var err error
+			for _, edit := range tc.edits {
+				pt, err = pt.Replace(edit[0].(int), edit[1].(int), edit[2].(string))
+				if err != nil {
+					break
+				}
+			}
+
+			if tc.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, pt.String())
+			assert.Equal(t, len(tc.expected), pt.Len())
+			assert.Equal(t, strings.Count(tc.expected, "\n")+1, pt.LineCount())
+		})
+	}
+}
+
+func TestPieceTable_Replace_immutable(t *testing.T) {
+	pt := NewPieceTable("abc")
+
+	next, err := pt.Replace(1, 2, "x")
+	require.NoError(t, err)
+
+	assert.Equal(t, "abc", pt.String())
+	assert.Equal(t, "axc", next.String())
+}
+
+func TestPieceTable_Replace_compact(t *testing.T) {
+	pt := NewPieceTable("")
+
+	var err error
+	for i := 0; i < maxPieces*2; i++ {
+		pt, err = pt.Replace(pt.Len(), pt.Len(), "a\n")
+		require.NoError(t, err)
+	}
+
+	assert.True(t, len(pt.pieces) <= maxPieces)
+	assert.Equal(t, strings.Repeat("a\n", maxPieces*2), pt.String())
+}
+
+func TestPieceTable_Offset(t *testing.T) {
+	cases := []struct {
+		name      string
+		line      int
+		character int
+		expected  int
+	}{
+		{name: "start", line: 0, character: 0, expected: 0},
+		{name: "first line", line: 0, character: 3, expected: 3},
+		{name: "past end of line", line: 0, character: 20, expected: 5},
+		{name: "second line", line: 1, character: 0, expected: 6},
+		{name: "after two byte rune", line: 1, character: 2, expected: 9},
+		{name: "after surrogate pair", line: 2, character: 3, expected: 16},
+		{name: "inside surrogate pair", line: 2, character: 2, expected: 16},
+		{name: "past last line", line: 10, character: 0, expected: 18},
+		{name: "negative line", line: -1, character: 0, expected: 0},
+	}
+
+	// line 2 is split across pieces.
+	pt := NewPieceTable("hello\nxé!\na")
+	pt, err := pt.Replace(12, 12, "😀b\n")
+	require.NoError(t, err)
+	require.Equal(t, "hello\nxé!\na😀b\n", pt.String())
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := pt.Offset(tc.line, tc.character)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
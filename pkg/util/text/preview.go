@@ -0,0 +1,31 @@
+package text
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Preview shortens text so it can be displayed in a small space. Only
+// the first maxLines lines are kept, and lines longer than maxWidth
+// characters are cut. Removed text is marked with "...".
+func Preview(s string, maxLines, maxWidth int) string {
+	lines := strings.Split(s, "\n")
+
+	truncated := false
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		truncated = true
+	}
+
+	for i, line := range lines {
+		if utf8.RuneCountInString(line) > maxWidth {
+			lines[i] = string([]rune(line)[:maxWidth]) + "..."
+		}
+	}
+
+	if truncated {
+		lines = append(lines, "...")
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,27 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreview(t *testing.T) {
+	cases := []struct {
+		name     string
+		s        string
+		expected string
+	}{
+		{name: "short", s: "{\n  \"a\": 1\n}", expected: "{\n  \"a\": 1\n}"},
+		{name: "too many lines", s: "[\n  1,\n  2,\n  3\n]", expected: "[\n  1,\n  2,\n..."},
+		{name: "long line", s: "\"abcdefghijkl\"", expected: "\"abcdefghi..."},
+		{name: "multibyte", s: "\"äöüäöüäöüäöü\"", expected: "\"äöüäöüäöü..."},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Preview(tc.s, 3, 10)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
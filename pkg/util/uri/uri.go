@@ -19,3 +19,13 @@ func ToPath(uriStr string) (string, error) {
 
 	return u.Path, nil
 }
+
+// FromPath converts a filesystem path to a URI.
+func FromPath(path string) string {
+	u := url.URL{
+		Scheme: "file",
+		Path:   path,
+	}
+
+	return u.String()
+}